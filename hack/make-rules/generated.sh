@@ -6,6 +6,6 @@ REPO_ROOT=$(dirname $(dirname $(dirname "$0")))
 cd "${REPO_ROOT}"
 
 go run k8s.io/code-generator/cmd/deepcopy-gen \
-   -i "./pkg/api" \
+   -i "./pkg/api,./pkg/api/k3dv1alpha3" \
    -O zz_generated.deepcopy \
    --go-header-file hack/boilerplate.go.txt
@@ -3,12 +3,15 @@ package exec
 import (
 	"context"
 	"os/exec"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
 // A dummy package to help with mocking out exec.NewCommand
 
 type CmdRunner interface {
 	Run(ctx context.Context, cmd string, args ...string) error
+	RunIO(ctx context.Context, iostreams genericclioptions.IOStreams, cmd string, args ...string) error
 }
 
 type RealCmdRunner struct{}
@@ -17,9 +20,22 @@ func (RealCmdRunner) Run(ctx context.Context, cmd string, args ...string) error
 	return exec.CommandContext(ctx, cmd, args...).Run()
 }
 
+func (RealCmdRunner) RunIO(ctx context.Context, iostreams genericclioptions.IOStreams, cmd string, args ...string) error {
+	c := exec.CommandContext(ctx, cmd, args...)
+	c.Stdin = iostreams.In
+	c.Stdout = iostreams.Out
+	c.Stderr = iostreams.ErrOut
+	return c.Run()
+}
+
 type FakeCmdRunner func(argv []string)
 
 func (f FakeCmdRunner) Run(ctx context.Context, cmd string, args ...string) error {
 	f(append([]string{cmd}, args...))
 	return nil
 }
+
+func (f FakeCmdRunner) RunIO(ctx context.Context, iostreams genericclioptions.IOStreams, cmd string, args ...string) error {
+	f(append([]string{cmd}, args...))
+	return nil
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 )
 
 const serviceName = "ctlptl-portforward-service"
@@ -23,20 +25,25 @@ type ContainerClient interface {
 
 type Controller struct {
 	client ContainerClient
+	engine docker.Engine
 }
 
-func NewController(client ContainerClient) *Controller {
-	return &Controller{client: client}
+func NewController(client ContainerClient, engine docker.Engine) *Controller {
+	return &Controller{client: client, engine: engine}
 }
 
 func DefaultController(ctx context.Context) (*Controller, error) {
-	client, err := client.NewClientWithOpts(client.FromEnv)
+	engine, err := docker.ResolveEngine("")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := docker.NewClient(ctx, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	client.NegotiateAPIVersion(ctx)
-	return NewController(client), nil
+	return NewController(client, engine), nil
 }
 
 // Connect a port on the local machine to a port on a remote docker machine.
@@ -66,7 +73,7 @@ func (c *Controller) StartRemotePortforwarder(ctx context.Context) error {
 		return fmt.Errorf("inspecting remote portforwarder: %v", err)
 	}
 
-	cmd := exec.Command("docker", "run", "-d", "-it",
+	cmd := exec.Command(c.engine.CLI(), "run", "-d", "-it",
 		"--name", serviceName, "--net=host", "--restart=always",
 		"--entrypoint", "/bin/sh", "alpine/socat", "-c", "while true; do sleep 1000; done")
 	return cmd.Run()
@@ -95,7 +102,7 @@ func (c *Controller) socatProcessOnPort(port int) (*process.Process, string, err
 func (c *Controller) StartLocalPortforwarder(ctx context.Context, port int) error {
 	args := []string{
 		fmt.Sprintf("TCP-LISTEN:%d,reuseaddr,fork", port),
-		fmt.Sprintf("EXEC:'docker exec -i %s socat STDIO TCP:localhost:%d'", serviceName, port),
+		fmt.Sprintf("EXEC:'%s exec -i %s socat STDIO TCP:localhost:%d'", c.engine.CLI(), serviceName, port),
 	}
 
 	existing, cmdline, err := c.socatProcessOnPort(port)
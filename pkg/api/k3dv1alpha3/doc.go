@@ -0,0 +1,8 @@
+// Package k3dv1alpha3 copies the k3d.io/v1alpha3 Simple config types,
+// so that ctlptl can embed a k3d config without depending on all of k3d.
+//
+// Adapted from:
+// https://github.com/rancher/k3d/blob/v5.0.0/pkg/config/v1alpha3/types.go
+//
+// +k8s:deepcopy-gen=package
+package k3dv1alpha3
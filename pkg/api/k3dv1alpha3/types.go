@@ -0,0 +1,107 @@
+package k3dv1alpha3
+
+// TypeMeta is the same as the k3d config TypeMeta.
+type TypeMeta struct {
+	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+}
+
+// SimpleConfig describes the toplevel k3d configuration file.
+type SimpleConfig struct {
+	TypeMeta     `yaml:",inline"`
+	Name         string                  `json:"name,omitempty" yaml:"name,omitempty"`
+	Servers      int                     `json:"servers,omitempty" yaml:"servers,omitempty"` // default 1
+	Agents       int                     `json:"agents,omitempty" yaml:"agents,omitempty"`   // default 0
+	ExposeAPI    SimpleExposureOpts      `json:"kubeAPI,omitempty" yaml:"kubeAPI,omitempty"`
+	Image        string                  `json:"image,omitempty" yaml:"image,omitempty"`
+	Network      string                  `json:"network,omitempty" yaml:"network,omitempty"`
+	Subnet       string                  `json:"subnet,omitempty" yaml:"subnet,omitempty"`
+	ClusterToken string                  `json:"clusterToken,omitempty" yaml:"clusterToken,omitempty"` // default: auto-generated
+	Volumes      []VolumeWithNodeFilters `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Ports        []PortWithNodeFilters   `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Options      SimpleConfigOptions     `json:"options,omitempty" yaml:"options,omitempty"`
+	Env          []EnvVarWithNodeFilters `json:"env,omitempty" yaml:"env,omitempty"`
+	Registries   SimpleConfigRegistries  `json:"registries,omitempty" yaml:"registries,omitempty"`
+}
+
+// SimpleExposureOpts provides a simplified syntax compared to the original k3d.ExposureOpts
+type SimpleExposureOpts struct {
+	Host     string `json:"host,omitempty" yaml:"host,omitempty"`
+	HostIP   string `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	HostPort string `json:"hostPort,omitempty" yaml:"hostPort,omitempty"`
+}
+
+type VolumeWithNodeFilters struct {
+	Volume      string   `json:"volume,omitempty" yaml:"volume,omitempty"`
+	NodeFilters []string `json:"nodeFilters,omitempty" yaml:"nodeFilters,omitempty"`
+}
+
+type PortWithNodeFilters struct {
+	Port        string   `json:"port,omitempty" yaml:"port,omitempty"`
+	NodeFilters []string `json:"nodeFilters,omitempty" yaml:"nodeFilters,omitempty"`
+}
+
+type LabelWithNodeFilters struct {
+	Label       string   `json:"label,omitempty" yaml:"label,omitempty"`
+	NodeFilters []string `json:"nodeFilters,omitempty" yaml:"nodeFilters,omitempty"`
+}
+
+type EnvVarWithNodeFilters struct {
+	EnvVar      string   `json:"envVar,omitempty" yaml:"envVar,omitempty"`
+	NodeFilters []string `json:"nodeFilters,omitempty" yaml:"nodeFilters,omitempty"`
+}
+
+type K3sArgWithNodeFilters struct {
+	Arg         string   `json:"arg,omitempty" yaml:"arg,omitempty"`
+	NodeFilters []string `json:"nodeFilters,omitempty" yaml:"nodeFilters,omitempty"`
+}
+
+// SimpleConfigOptions describes the set of options referenced in the Simple Config
+type SimpleConfigOptions struct {
+	K3dOptions        SimpleConfigOptionsK3d        `json:"k3d,omitempty" yaml:"k3d,omitempty"`
+	K3sOptions        SimpleConfigOptionsK3s        `json:"k3s,omitempty" yaml:"k3s,omitempty"`
+	KubeconfigOptions SimpleConfigOptionsKubeconfig `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+	Runtime           SimpleConfigOptionsRuntime    `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+}
+
+type SimpleConfigOptionsRuntime struct {
+	GPURequest    string                 `json:"gpuRequest,omitempty" yaml:"gpuRequest,omitempty"`
+	ServersMemory string                 `json:"serversMemory,omitempty" yaml:"serversMemory,omitempty"`
+	AgentsMemory  string                 `json:"agentsMemory,omitempty" yaml:"agentsMemory,omitempty"`
+	Labels        []LabelWithNodeFilters `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type SimpleConfigOptionsK3d struct {
+	Wait                bool                               `json:"wait,omitempty" yaml:"wait,omitempty"`
+	Timeout             string                             `json:"timeout,omitempty" yaml:"timeout,omitempty"` // a duration, e.g., 60s
+	DisableLoadbalancer bool                               `json:"disableLoadbalancer,omitempty" yaml:"disableLoadbalancer,omitempty"`
+	DisableImageVolume  bool                               `json:"disableImageVolume,omitempty" yaml:"disableImageVolume,omitempty"`
+	NoRollback          bool                               `json:"disableRollback,omitempty" yaml:"disableRollback,omitempty"`
+	Loadbalancer        SimpleConfigOptionsK3dLoadbalancer `json:"loadbalancer,omitempty" yaml:"loadbalancer,omitempty"`
+}
+
+type SimpleConfigOptionsK3dLoadbalancer struct {
+	ConfigOverrides []string `json:"configOverrides,omitempty" yaml:"configOverrides,omitempty"`
+}
+
+type SimpleConfigOptionsK3s struct {
+	ExtraArgs  []K3sArgWithNodeFilters `json:"extraArgs,omitempty" yaml:"extraArgs,omitempty"`
+	NodeLabels []LabelWithNodeFilters  `json:"nodeLabels,omitempty" yaml:"nodeLabels,omitempty"`
+}
+
+type SimpleConfigOptionsKubeconfig struct {
+	UpdateDefaultKubeconfig bool `json:"updateDefaultKubeconfig,omitempty" yaml:"updateDefaultKubeconfig,omitempty"` // default: true
+	SwitchCurrentContext    bool `json:"switchCurrentContext,omitempty" yaml:"switchCurrentContext,omitempty"`       // default: true
+}
+
+type SimpleConfigRegistries struct {
+	Use    []string                          `json:"use,omitempty" yaml:"use,omitempty"`
+	Create *SimpleConfigRegistryCreateConfig `json:"create,omitempty" yaml:"create,omitempty"`
+	Config string                            `json:"config,omitempty" yaml:"config,omitempty"` // registries.yaml (k3s config for containerd registry override)
+}
+
+type SimpleConfigRegistryCreateConfig struct {
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Host     string `json:"host,omitempty" yaml:"host,omitempty"`
+	HostPort string `json:"hostPort,omitempty" yaml:"hostPort,omitempty"`
+}
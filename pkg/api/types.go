@@ -4,6 +4,8 @@ import (
 	"github.com/tilt-dev/localregistry-go"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
 )
 
 // TypeMeta partially copies apimachinery/pkg/apis/meta/v1.TypeMeta
@@ -21,6 +23,16 @@ type Cluster struct {
 	// The cluster name. Pulled from .kube/config.
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 
+	// Labels for organizing clusters, e.g., team: payments
+	//
+	// Select clusters by label with `ctlptl get -l`.
+	// Stored in the cluster, along with the rest of the cluster spec.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Notes about the cluster for people and tools.
+	// Stored in the cluster, along with the rest of the cluster spec.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+
 	// The name of the tool used to create this cluster.
 	Product string `json:"product,omitempty" yaml:"product,omitempty"`
 
@@ -30,6 +42,72 @@ type Cluster struct {
 	// CPU, it will return an error.
 	MinCPUs int `json:"minCPUs,omitempty" yaml:"minCPUs,omitempty"`
 
+	// Make sure that the cluster has access to at least this much memory,
+	// as a Kubernetes quantity. If ctlptl can't guarantee this much
+	// memory, it will return an error.
+	//
+	// Examples:
+	// 8Gi
+	// 4096Mi
+	MinMemory string `json:"minMemory,omitempty" yaml:"minMemory,omitempty"`
+
+	// Make sure that the cluster has access to at least this much disk,
+	// as a Kubernetes quantity. If ctlptl can't guarantee this much
+	// disk, it will return an error.
+	//
+	// Examples:
+	// 64Gi
+	MinDisk string `json:"minDisk,omitempty" yaml:"minDisk,omitempty"`
+
+	// How long the cluster should live after it's created, as a Go duration.
+	//
+	// `ctlptl gc` deletes clusters that are older than their TTL.
+	// If not set, the cluster never expires.
+	//
+	// Examples:
+	// 8h
+	// 30m
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// The nodes in the cluster.
+	//
+	// If not set, uses the product's default (usually a single node).
+	//
+	// Only supported on kind, minikube, and k3d.
+	Nodes *ClusterNodes `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+
+	// Ports on the host to forward into the cluster, e.g., to reach an
+	// ingress controller.
+	//
+	// Only supported on kind, minikube, and k3d.
+	PortMappings []PortMapping `json:"portMappings,omitempty" yaml:"portMappings,omitempty"`
+
+	// An ingress controller to install after the cluster is created.
+	//
+	// Only supported on kind, minikube, and k3d.
+	Ingress *ClusterIngress `json:"ingress,omitempty" yaml:"ingress,omitempty"`
+
+	// Manifests to apply after the cluster is created, in order.
+	//
+	// Each addon is a path to a YAML file, or an http(s) URL. ctlptl waits
+	// for the Deployments, DaemonSets, and CRDs in each addon to become ready
+	// before it applies the next one.
+	//
+	// ctlptl records the addons it applied on the cluster, so re-applying
+	// an existing cluster only applies the new addons.
+	Addons []string `json:"addons,omitempty" yaml:"addons,omitempty"`
+
+	// Images to load into the cluster after it's created, so that the first
+	// deploy doesn't wait on cold pulls.
+	//
+	// ctlptl pulls each image into the host's container engine, then pushes
+	// it to the cluster's registry. If the cluster doesn't have a registry,
+	// ctlptl loads the image onto the cluster's nodes instead, which is
+	// supported on kind, minikube, k3d, and docker-desktop.
+	//
+	// Images must be referenced by tag, not by digest.
+	PreloadImages []string `json:"preloadImages,omitempty" yaml:"preloadImages,omitempty"`
+
 	// The name of a registry.
 	//
 	// If the registry doesn't exist, ctlptl will create one with this name.
@@ -39,6 +117,15 @@ type Cluster struct {
 	// Not supported on all cluster products.
 	Registry string `json:"registry,omitempty" yaml:"registry,omitempty"`
 
+	// Pull-through caches for remote registries, keyed by the remote registry
+	// host, e.g., docker.io: ctlptl-dockerhub-cache
+	//
+	// ctlptl creates a caching registry with each name, and configures the
+	// container runtime on each node to pull images from the remote through it.
+	//
+	// Supported on kind, minikube, and k3d.
+	RegistryMirrors map[string]string `json:"registryMirrors,omitempty" yaml:"registryMirrors,omitempty"`
+
 	// The desired version of Kubernetes to run.
 	//
 	// Examples:
@@ -49,6 +136,42 @@ type Cluster struct {
 	// Not all cluster products allow you to customize this.
 	KubernetesVersion string `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
 
+	// The node image to use for the cluster.
+	//
+	// Overrides the image that ctlptl would otherwise pick for the KubernetesVersion.
+	//
+	// Example:
+	// kindest/node:v1.21.1
+	//
+	// Only supported on KIND.
+	NodeImage string `json:"nodeImage,omitempty" yaml:"nodeImage,omitempty"`
+
+	// Kubernetes feature gates to turn on or off in every component.
+	//
+	// Example:
+	// EphemeralContainers: true
+	//
+	// Only supported on kind and minikube.
+	FeatureGates map[string]bool `json:"featureGates,omitempty" yaml:"featureGates,omitempty"`
+
+	// Extra flags for the Kubernetes API server.
+	//
+	// Only supported on kind and minikube.
+	APIServer *ClusterComponent `json:"apiServer,omitempty" yaml:"apiServer,omitempty"`
+
+	// Extra flags for the kubelet on each node.
+	//
+	// Only supported on kind and minikube.
+	Kubelet *ClusterComponent `json:"kubelet,omitempty" yaml:"kubelet,omitempty"`
+
+	// The container engine that runs the cluster.
+	//
+	// Supported values: docker, podman
+	//
+	// If empty, ctlptl uses docker if it's installed, and falls back to podman.
+	// Only kind and minikube clusters can run on podman.
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+
 	// The Kind cluster config. Only applicable for clusters with product: kind.
 	//
 	// Full documentation at:
@@ -59,12 +182,127 @@ type Cluster struct {
 	// wins over one specified in the Kind config.
 	KindV1Alpha4Cluster *v1alpha4.Cluster `json:"kindV1Alpha4Cluster,omitempty" yaml:"kindV1Alpha4Cluster,omitempty"`
 
+	// The K3D cluster config. Only applicable for clusters with product: k3d.
+	//
+	// Full documentation at:
+	// https://k3d.io/v5.0.0/usage/configfile/
+	//
+	// Properties of this config may be overridden by properties of the ctlptl
+	// Cluster config. For example, the name field of the top-level Cluster object
+	// wins over one specified in the K3D config.
+	K3DV1Alpha3Simple *k3dv1alpha3.SimpleConfig `json:"k3dV1Alpha3Simple,omitempty" yaml:"k3dV1Alpha3Simple,omitempty"`
+
+	// The Minikube cluster config. Only applicable for clusters with product: minikube.
+	Minikube *MinikubeCluster `json:"minikube,omitempty" yaml:"minikube,omitempty"`
+
 	// Most recently observed status of the cluster.
 	// Populated by the system.
 	// Read-only.
 	Status ClusterStatus `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
+// ClusterNodes describes the node topology of a cluster,
+// independent of the product.
+type ClusterNodes struct {
+	// The number of control plane nodes.
+	//
+	// Defaults to 1.
+	ControlPlanes int `json:"controlPlanes,omitempty" yaml:"controlPlanes,omitempty"`
+
+	// The number of worker nodes.
+	//
+	// Defaults to 0, which means workloads run on the control plane.
+	Workers int `json:"workers,omitempty" yaml:"workers,omitempty"`
+}
+
+// ClusterComponent configures a Kubernetes component, like the API server.
+type ClusterComponent struct {
+	// Extra flags for the component, without the leading dashes.
+	//
+	// Example:
+	// audit-log-path: /var/log/kubernetes/audit.log
+	ExtraArgs map[string]string `json:"extraArgs,omitempty" yaml:"extraArgs,omitempty"`
+}
+
+// ClusterIngress describes the ingress controller to install in the cluster.
+type ClusterIngress struct {
+	// The ingress controller. One of nginx, contour, or traefik.
+	//
+	// ctlptl installs manifests bundled with the ctlptl binary, and waits
+	// for the controller to become ready.
+	Controller string `json:"controller,omitempty" yaml:"controller,omitempty"`
+}
+
+// PortMapping forwards a port on the host to a port on the cluster's nodes.
+type PortMapping struct {
+	// The port on the host.
+	HostPort int `json:"hostPort,omitempty" yaml:"hostPort,omitempty"`
+
+	// The port on the cluster's nodes.
+	ContainerPort int `json:"containerPort,omitempty" yaml:"containerPort,omitempty"`
+
+	// The protocol to forward. One of TCP, UDP, or SCTP.
+	//
+	// Defaults to TCP.
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+
+	// The host address to listen on.
+	//
+	// Defaults to all addresses (0.0.0.0).
+	ListenAddress string `json:"listenAddress,omitempty" yaml:"listenAddress,omitempty"`
+}
+
+// MinikubeCluster describes minikube-specific options for starting a cluster.
+//
+// Options in this struct, when possible, should match the flags
+// to `minikube start`.
+//
+// https://minikube.sigs.k8s.io/docs/commands/start/
+type MinikubeCluster struct {
+	// The driver that minikube uses to run the cluster.
+	//
+	// Defaults to docker. A registry or registry mirrors require
+	// a container driver (docker or podman).
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+
+	// The container runtime inside the cluster.
+	//
+	// Defaults to containerd. A registry or registry mirrors require containerd.
+	ContainerRuntime string `json:"containerRuntime,omitempty" yaml:"containerRuntime,omitempty"`
+
+	// The amount of RAM to allocate to the cluster, in minikube's format.
+	//
+	// Examples:
+	// 4g
+	// 8192mb
+	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"`
+
+	// The disk size to allocate to the cluster, in minikube's format.
+	//
+	// Examples:
+	// 20g
+	DiskSize string `json:"diskSize,omitempty" yaml:"diskSize,omitempty"`
+
+	// Extra configuration for Kubernetes components, passed as --extra-config.
+	//
+	// Examples:
+	// kubelet.max-pods=100
+	// apiserver.enable-admission-plugins=NamespaceLifecycle
+	ExtraConfigs []string `json:"extraConfigs,omitempty" yaml:"extraConfigs,omitempty"`
+
+	// Addons to enable when the cluster starts.
+	//
+	// Examples:
+	// ingress
+	// metrics-server
+	Addons []string `json:"addons,omitempty" yaml:"addons,omitempty"`
+
+	// Extra flags to pass to `minikube start`, verbatim.
+	//
+	// Useful for minikube options that don't have a field here yet.
+	StartFlags []string `json:"startFlags,omitempty" yaml:"startFlags,omitempty"`
+}
+
 type ClusterStatus struct {
 	// When the cluster was first created.
 	CreationTimestamp metav1.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
@@ -75,6 +313,23 @@ type ClusterStatus struct {
 	// The number of CPU. Only applicable to local clusters.
 	CPUs int `json:"cpus,omitempty" yaml:"cpus,omitempty"`
 
+	// The amount of memory, in bytes. Only applicable to local clusters.
+	Memory int64 `json:"memory,omitempty" yaml:"memory,omitempty"`
+
+	// The amount of disk, in bytes. Only applicable to local clusters.
+	Disk int64 `json:"disk,omitempty" yaml:"disk,omitempty"`
+
+	// The number of nodes in the cluster, as reported by the Kubernetes API.
+	Nodes int `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+
+	// The ingress controller running in the cluster, as reported by
+	// the cluster's IngressClasses.
+	//
+	// This may be a controller that ctlptl didn't install, like the
+	// traefik that comes with k3d. The controller that ctlptl installed
+	// is in the spec.
+	IngressController string `json:"ingressController,omitempty" yaml:"ingressController,omitempty"`
+
 	// Whether this is the current cluster in `kubectl`
 	Current bool `json:"current,omitempty" yaml:"current,omitempty"`
 
@@ -87,6 +342,10 @@ type ClusterStatus struct {
 	// v1.18.10-gke.601
 	// v1.19.3-34+fa32ff1c160058
 	KubernetesVersion string `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
+
+	// What ctlptl observed when it read the cluster, e.g., whether it
+	// could reach the API server. See the ClusterCondition* types.
+	Conditions []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 }
 
 // ClusterList is a list of Clusters.
@@ -111,9 +370,34 @@ type Registry struct {
 	// The registry name. Get/set from the Docker container name.
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 
+	// Labels for organizing registries, e.g., team: payments
+	//
+	// Select registries by label with `ctlptl get -l`.
+	// Stored as labels on the registry container. Docker can't change the labels
+	// on an existing container, so ctlptl refuses to change them on an existing
+	// registry; delete the registry to relabel it.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Notes about the registry for people and tools.
+	// Stored as labels on the registry container, like Labels.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+
 	// The desired host port. Set to 0 to choose a random port.
 	Port int `json:"port,omitempty" yaml:"port,omitempty"`
 
+	// The container engine that runs the registry.
+	//
+	// Supported values: docker, podman
+	//
+	// If empty, ctlptl uses docker if it's installed, and falls back to podman.
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+
+	// If set, the registry runs as a pull-through cache of the remote registry
+	// at this URL, e.g., https://registry-1.docker.io
+	//
+	// A pull-through cache is read-only, so you can't push images to it.
+	ProxyRemoteURL string `json:"proxyRemoteURL,omitempty" yaml:"proxyRemoteURL,omitempty"`
+
 	// Most recently observed status of the registry.
 	// Populated by the system.
 	// Read-only.
@@ -146,6 +430,55 @@ type RegistryStatus struct {
 	// Reflects underlying ContainerState.Status
 	// https://github.com/moby/moby/blob/v20.10.3/api/types/types.go#L314
 	State string
+
+	// What ctlptl observed when it read the registry, e.g., whether the
+	// container is running. See the RegistryCondition* types.
+	Conditions []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// Condition types for clusters.
+const (
+	// Whether ctlptl can reach the machine that runs the cluster,
+	// e.g., the container engine or the minikube VM.
+	ClusterConditionMachineReachable = "MachineReachable"
+
+	// Whether the cluster's API server answers a health check.
+	ClusterConditionAPIServerReachable = "APIServerReachable"
+
+	// Whether the registry that the cluster advertises is running.
+	ClusterConditionRegistryConnected = "RegistryConnected"
+
+	// Whether the cluster has the spec that ctlptl recorded when it created it.
+	ClusterConditionSpecRecorded = "SpecRecorded"
+
+	// Whether ctlptl could list the cluster's nodes, which it reads
+	// the creation time and node count from.
+	ClusterConditionNodesReadable = "NodesReadable"
+
+	// Whether ctlptl could read the cluster's IngressClasses, which it
+	// reads the ingress controller from.
+	ClusterConditionIngressReadable = "IngressReadable"
+)
+
+// Condition types for registries.
+const (
+	// Whether the registry container is running.
+	RegistryConditionRunning = "Running"
+)
+
+// An observation about one aspect of a cluster or registry.
+type Condition struct {
+	// The aspect that this condition describes, e.g., APIServerReachable.
+	Type string `json:"type" yaml:"type"`
+
+	// True, False, or Unknown.
+	Status metav1.ConditionStatus `json:"status" yaml:"status"`
+
+	// A CamelCase reason for the status, e.g., APIServerUnreachable.
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// A human-readable explanation, e.g., the error ctlptl got.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
 }
 
 // RegistryList is a list of Registrys.
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateMetadata checks that labels and annotations follow the same syntax
+// as Kubernetes labels and annotations, so that label selectors work on them.
+func ValidateMetadata(labels, annotations map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		errs := validation.IsQualifiedName(key)
+		if len(errs) > 0 {
+			return fmt.Errorf("labels: invalid key %q: %s", key, strings.Join(errs, "; "))
+		}
+		errs = validation.IsValidLabelValue(labels[key])
+		if len(errs) > 0 {
+			return fmt.Errorf("labels: invalid value %q for %s: %s", labels[key], key, strings.Join(errs, "; "))
+		}
+	}
+	for _, key := range sortedKeys(annotations) {
+		errs := validation.IsQualifiedName(key)
+		if len(errs) > 0 {
+			return fmt.Errorf("annotations: invalid key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
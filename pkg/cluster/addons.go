@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func validateAddons(desired *api.Cluster) error {
+	seen := make(map[string]bool, len(desired.Addons))
+	for _, addon := range desired.Addons {
+		if strings.TrimSpace(addon) == "" {
+			return fmt.Errorf("addons: must not contain empty entries")
+		}
+		if seen[addon] {
+			return fmt.Errorf("addons: %s is listed more than once", addon)
+		}
+		seen[addon] = true
+	}
+	return nil
+}
+
+func isAddonURL(addon string) bool {
+	return strings.HasPrefix(addon, "http://") || strings.HasPrefix(addon, "https://")
+}
+
+// Reads the addon manifest from a file or a URL.
+func readAddon(ctx context.Context, addon string) ([]byte, error) {
+	if !isAddonURL(addon) {
+		return ioutil.ReadFile(addon)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addon, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", addon, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// The desired addons that haven't been applied yet, in order.
+func newAddons(desired, applied []string) []string {
+	isApplied := make(map[string]bool, len(applied))
+	for _, addon := range applied {
+		isApplied[addon] = true
+	}
+
+	var result []string
+	for _, addon := range desired {
+		if !isApplied[addon] {
+			result = append(result, addon)
+		}
+	}
+	return result
+}
+
+// Applies the addons in order, waiting for each one to become ready.
+//
+// After each addon, we record it in the cluster spec, so that
+// re-applying the cluster skips the addons that succeeded.
+func (c *Controller) installAddons(ctx context.Context, spec *api.Cluster, addons []string) error {
+	client, err := c.client(spec.Name)
+	if err != nil {
+		return err
+	}
+	dclient, err := c.dynamicClient(spec.Name)
+	if err != nil {
+		return err
+	}
+	mapper := newManifestRESTMapper(client.Discovery())
+
+	for _, addon := range addons {
+		data, err := readAddon(ctx, addon)
+		if err != nil {
+			return fmt.Errorf("reading addon %s: %v", addon, err)
+		}
+		objs, err := decodeManifest(data)
+		if err != nil {
+			return fmt.Errorf("reading addon %s: %v", addon, err)
+		}
+
+		_, _ = fmt.Fprintf(c.iostreams.ErrOut, " 🧩 Applying addon %s to cluster %s\n", addon, spec.Name)
+
+		var crds []*unstructured.Unstructured
+		for _, obj := range objs {
+			err := applyUnstructured(ctx, dclient, mapper, obj)
+			if meta.IsNoMatchError(err) && len(crds) > 0 {
+				// The object may be an instance of a CRD earlier in the addon,
+				// so wait for the CRDs and look again.
+				err = c.waitForObjectsReady(ctx, dclient, mapper, crds, fmt.Sprintf("addon %s CRDs", addon))
+				if err == nil {
+					mapper.Reset()
+					err = applyUnstructured(ctx, dclient, mapper, obj)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("applying addon %s: %s %s: %v", addon, obj.GetKind(), obj.GetName(), err)
+			}
+
+			if obj.GetKind() == "CustomResourceDefinition" {
+				crds = append(crds, obj)
+			}
+		}
+
+		err = c.waitForObjectsReady(ctx, dclient, mapper, objs, fmt.Sprintf("addon %s", addon))
+		if err != nil {
+			return err
+		}
+
+		// Later addons may use the CRDs in this one.
+		if len(crds) > 0 {
+			mapper.Reset()
+		}
+
+		spec.Addons = append(spec.Addons, addon)
+		err = c.writeClusterSpec(ctx, spec)
+		if err != nil {
+			return fmt.Errorf("recording addon %s: %v", addon, err)
+		}
+	}
+	return nil
+}
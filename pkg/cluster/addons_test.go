@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func TestValidateAddons(t *testing.T) {
+	assert.NoError(t, validateAddons(&api.Cluster{Addons: []string{"a.yaml", "https://example.com/b.yaml"}}))
+
+	err := validateAddons(&api.Cluster{Addons: []string{"a.yaml", " "}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "addons: must not contain empty entries")
+	}
+
+	err = validateAddons(&api.Cluster{Addons: []string{"a.yaml", "a.yaml"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "addons: a.yaml is listed more than once")
+	}
+}
+
+func TestNewAddons(t *testing.T) {
+	assert.Equal(t, []string{"a.yaml", "b.yaml"}, newAddons([]string{"a.yaml", "b.yaml"}, nil))
+	assert.Equal(t, []string{"c.yaml"}, newAddons([]string{"a.yaml", "c.yaml", "b.yaml"}, []string{"b.yaml", "a.yaml"}))
+	assert.Nil(t, newAddons([]string{"a.yaml"}, []string{"a.yaml"}))
+}
+
+func TestReadAddonURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/components.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, "kind: ConfigMap\n")
+	}))
+	defer server.Close()
+
+	data, err := readAddon(context.Background(), server.URL+"/components.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "kind: ConfigMap\n", string(data))
+
+	_, err = readAddon(context.Background(), server.URL+"/missing.yaml")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "404 Not Found")
+	}
+}
+
+func TestDecodeManifest(t *testing.T) {
+	objs, err := decodeManifest([]byte(`# A comment
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: cert-manager
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: cert-manager
+    namespace: cert-manager
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: cert-manager
+    namespace: cert-manager
+`))
+	require.NoError(t, err)
+
+	var kinds []string
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.Equal(t, []string{"Namespace", "ServiceAccount", "Deployment"}, kinds)
+}
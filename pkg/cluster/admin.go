@@ -18,3 +18,15 @@ type Admin interface {
 
 	Delete(ctx context.Context, config *api.Cluster) error
 }
+
+// An admin that can copy an image from the host's container engine
+// onto the cluster's nodes.
+type imageLoader interface {
+	LoadImage(ctx context.Context, cluster *api.Cluster, image string) error
+}
+
+// An admin that can move an existing cluster to a newer Kubernetes version
+// without recreating it.
+type upgrader interface {
+	Upgrade(ctx context.Context, desired, existing *api.Cluster) error
+}
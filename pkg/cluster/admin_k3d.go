@@ -0,0 +1,299 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/tilt-dev/localregistry-go"
+	"gopkg.in/yaml.v3"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog/v2"
+
+	cexec "github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
+)
+
+// k3dAdmin uses the k3d CLI to manipulate a k3d cluster,
+// once the underlying machine has been setup.
+type k3dAdmin struct {
+	iostreams genericclioptions.IOStreams
+	runner    cexec.CmdRunner
+}
+
+func newK3dAdmin(iostreams genericclioptions.IOStreams, runner cexec.CmdRunner) *k3dAdmin {
+	return &k3dAdmin{
+		iostreams: iostreams,
+		runner:    runner,
+	}
+}
+
+func (a *k3dAdmin) EnsureInstalled(ctx context.Context) error {
+	_, err := exec.LookPath("k3d")
+	if err != nil {
+		return fmt.Errorf("k3d not installed. Please install k3d with these instructions: https://k3d.io/#installation")
+	}
+	return nil
+}
+
+// The k3s registry config, as documented here:
+// https://rancher.com/docs/k3s/latest/en/installation/private-registry/
+type k3sRegistriesConfig struct {
+	Mirrors map[string]k3sRegistryMirror `yaml:"mirrors,omitempty"`
+
+	// Other registry settings (like configs and auths) that we pass through as-is.
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+type k3sRegistryMirror struct {
+	Endpoints []string `yaml:"endpoint"`
+
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// Adds mirrors for the registry and the pull-through caches
+// to the registries.yaml in the k3d config.
+func (a *k3dAdmin) registriesConfig(existing string, registry *api.Registry, mirrors []registryMirror) (string, error) {
+	config := k3sRegistriesConfig{}
+	err := yaml.Unmarshal([]byte(existing), &config)
+	if err != nil {
+		return "", errors.Wrap(err, "reading k3d registries config")
+	}
+	if config.Mirrors == nil {
+		config.Mirrors = map[string]k3sRegistryMirror{}
+	}
+
+	if registry != nil {
+		endpoint := fmt.Sprintf("http://%s:%d", registry.Name, registry.Status.ContainerPort)
+		config.Mirrors[fmt.Sprintf("localhost:%d", registry.Status.HostPort)] = k3sRegistryMirror{
+			Endpoints: []string{endpoint},
+		}
+		config.Mirrors[fmt.Sprintf("%s:%d", registry.Name, registry.Status.ContainerPort)] = k3sRegistryMirror{
+			Endpoints: []string{endpoint},
+		}
+	}
+	for _, m := range mirrors {
+		config.Mirrors[m.host] = k3sRegistryMirror{
+			Endpoints: []string{m.endpoint()},
+		}
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "writing k3d registries config")
+	}
+	return string(data), nil
+}
+
+// Catches k3d config errors before k3d does.
+func validateK3dConfig(desired *api.Cluster) error {
+	if desired.K3DV1Alpha3Simple == nil {
+		return nil
+	}
+	timeout := desired.K3DV1Alpha3Simple.Options.K3dOptions.Timeout
+	if timeout == "" {
+		return nil
+	}
+	_, err := time.ParseDuration(timeout)
+	if err != nil {
+		return fmt.Errorf("k3d options.k3d.timeout: %v", err)
+	}
+	return nil
+}
+
+func (a *k3dAdmin) k3dClusterConfig(desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) (*k3dv1alpha3.SimpleConfig, error) {
+	k3dConfig := desired.K3DV1Alpha3Simple
+	if k3dConfig == nil {
+		k3dConfig = &k3dv1alpha3.SimpleConfig{}
+	} else {
+		k3dConfig = k3dConfig.DeepCopy()
+	}
+	k3dConfig.Kind = "Simple"
+	k3dConfig.APIVersion = "k3d.io/v1alpha3"
+	k3dConfig.Name = strings.TrimPrefix(desired.Name, "k3d-")
+
+	if desired.Nodes != nil {
+		k3dConfig.Servers, k3dConfig.Agents = nodeCounts(desired.Nodes)
+	}
+
+	k3dConfig.Ports = append(k3dConfig.Ports, k3dPortMappings(desired.PortMappings)...)
+
+	// k3s ships with Traefik. Turn it off so that it doesn't
+	// fight with the ingress controller we install.
+	if desired.Ingress != nil {
+		k3dConfig.Options.K3sOptions.ExtraArgs = append(k3dConfig.Options.K3sOptions.ExtraArgs,
+			k3dv1alpha3.K3sArgWithNodeFilters{
+				Arg:         "--disable=traefik",
+				NodeFilters: []string{"server:*"},
+			})
+	}
+
+	if desired.KubernetesVersion != "" {
+		image, err := k3sImage(desired.KubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		k3dConfig.Image = image
+	}
+
+	if registry != nil || len(mirrors) > 0 {
+		registriesConfig, err := a.registriesConfig(k3dConfig.Registries.Config, registry, mirrors)
+		if err != nil {
+			return nil, err
+		}
+		k3dConfig.Registries.Config = registriesConfig
+	}
+	return k3dConfig, nil
+}
+
+func (a *k3dAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	return a.CreateWithMirrors(ctx, desired, registry, nil)
+}
+
+func (a *k3dAdmin) CreateWithMirrors(ctx context.Context, desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
+	klog.V(3).Infof("Creating cluster with config:\n%+v\n---\n", desired)
+	if registry != nil {
+		klog.V(3).Infof("Initializing cluster with registry config:\n%+v\n---\n", registry)
+	}
+
+	clusterName := desired.Name
+	if !strings.HasPrefix(clusterName, "k3d-") {
+		return fmt.Errorf("all k3d clusters must have a name with the prefix k3d-*")
+	}
+
+	k3dName := strings.TrimPrefix(clusterName, "k3d-")
+
+	k3dConfig, err := a.k3dClusterConfig(desired, registry, mirrors)
+	if err != nil {
+		return errors.Wrap(err, "creating k3d cluster")
+	}
+
+	data, err := yaml.Marshal(k3dConfig)
+	if err != nil {
+		return errors.Wrap(err, "creating k3d cluster")
+	}
+
+	f, err := ioutil.TempFile("", "ctlptl-k3d-config-*.yaml")
+	if err != nil {
+		return errors.Wrap(err, "creating k3d cluster")
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = f.Write(data)
+	_ = f.Close()
+	if err != nil {
+		return errors.Wrap(err, "creating k3d cluster")
+	}
+
+	args := []string{"cluster", "create", k3dName, "--wait", "--config", f.Name()}
+	err = a.runner.RunIO(ctx,
+		genericclioptions.IOStreams{Out: a.iostreams.Out, ErrOut: a.iostreams.ErrOut},
+		"k3d", args...)
+	if err != nil {
+		return errors.Wrap(err, "creating k3d cluster")
+	}
+
+	networkName := k3dNetworkName(k3dConfig)
+	if registry != nil {
+		err := a.connectRegistry(ctx, registry, networkName)
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range mirrors {
+		err := a.connectRegistry(ctx, m.registry, networkName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *k3dAdmin) connectRegistry(ctx context.Context, registry *api.Registry, networkName string) error {
+	if a.inK3dNetwork(registry, networkName) {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(a.iostreams.ErrOut, "   Connecting k3d to registry %s\n", registry.Name)
+	err := a.runner.Run(ctx, "docker", "network", "connect", networkName, registry.Name)
+	if err != nil {
+		return errors.Wrap(err, "connecting registry")
+	}
+	return nil
+}
+
+// k3d creates a separate network for each cluster,
+// unless the config asks to join an existing network.
+func k3dNetworkName(k3dConfig *k3dv1alpha3.SimpleConfig) string {
+	if k3dConfig.Network != "" {
+		return k3dConfig.Network
+	}
+	return fmt.Sprintf("k3d-%s", k3dConfig.Name)
+}
+
+func (a *k3dAdmin) inK3dNetwork(registry *api.Registry, networkName string) bool {
+	for _, n := range registry.Status.Networks {
+		if n == networkName {
+			return true
+		}
+	}
+	return false
+}
+
+// k3s publishes one image per Kubernetes patch release, tagged
+// with the Kubernetes version and a k3s build number.
+//
+// https://hub.docker.com/r/rancher/k3s/tags
+func k3sImage(k8sVersion string) (string, error) {
+	v, err := semver.ParseTolerant(k8sVersion)
+	if err != nil {
+		return "", fmt.Errorf("parsing kubernetesVersion: %v", err)
+	}
+
+	build := "k3s1"
+	if len(v.Build) > 0 && strings.HasPrefix(v.Build[0], "k3s") {
+		build = v.Build[0]
+	}
+	return fmt.Sprintf("rancher/k3s:v%d.%d.%d-%s", v.Major, v.Minor, v.Patch, build), nil
+}
+
+func (a *k3dAdmin) LocalRegistryHosting(ctx context.Context, desired *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
+	return &localregistry.LocalRegistryHostingV1{
+		Host:                   fmt.Sprintf("localhost:%d", registry.Status.HostPort),
+		HostFromClusterNetwork: fmt.Sprintf("%s:%d", registry.Name, registry.Status.ContainerPort),
+		Help:                   "https://github.com/tilt-dev/ctlptl",
+	}, nil
+}
+
+func (a *k3dAdmin) LoadImage(ctx context.Context, cluster *api.Cluster, image string) error {
+	k3dName := strings.TrimPrefix(cluster.Name, "k3d-")
+	err := a.runner.Run(ctx, "k3d", "image", "import", image, "--cluster", k3dName)
+	if err != nil {
+		return errors.Wrap(err, "k3d image import")
+	}
+	return nil
+}
+
+func (a *k3dAdmin) Delete(ctx context.Context, config *api.Cluster) error {
+	clusterName := config.Name
+	if !strings.HasPrefix(clusterName, "k3d-") {
+		return fmt.Errorf("all k3d clusters must have a name with the prefix k3d-*")
+	}
+
+	k3dName := strings.TrimPrefix(clusterName, "k3d-")
+	err := a.runner.RunIO(ctx, a.iostreams, "k3d", "cluster", "delete", k3dName)
+	if err != nil {
+		return errors.Wrap(err, "deleting k3d cluster")
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
+)
+
+func TestK3sImage(t *testing.T) {
+	img, err := k3sImage("v1.21.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "rancher/k3s:v1.21.2-k3s1", img)
+
+	img, err = k3sImage("1.20.4+k3s2")
+	assert.NoError(t, err)
+	assert.Equal(t, "rancher/k3s:v1.20.4-k3s2", img)
+
+	_, err = k3sImage("latest")
+	assert.Error(t, err)
+}
+
+func TestK3dCreateWithRegistry(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+
+	calls := [][]string{}
+	k3dConfig := k3dv1alpha3.SimpleConfig{}
+	runner := exec.FakeCmdRunner(func(argv []string) {
+		calls = append(calls, argv)
+		for i, arg := range argv {
+			if arg == "--config" {
+				contents, err := ioutil.ReadFile(argv[i+1])
+				require.NoError(t, err)
+				decoder := yaml.NewDecoder(bytes.NewReader(contents))
+				decoder.KnownFields(true)
+				require.NoError(t, decoder.Decode(&k3dConfig))
+			}
+		}
+	})
+	a := newK3dAdmin(iostreams, runner)
+
+	err := a.Create(context.Background(), &api.Cluster{
+		Name:              "k3d-my-cluster",
+		Product:           "k3d",
+		KubernetesVersion: "v1.21.2",
+	}, &api.Registry{
+		Name: "ctlptl-registry",
+		Status: api.RegistryStatus{
+			HostPort:      5002,
+			ContainerPort: 5000,
+			Networks:      []string{"bridge"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(calls))
+	assert.Equal(t, []string{"k3d", "cluster", "create", "my-cluster", "--wait", "--config"}, calls[0][:6])
+	assert.Equal(t, []string{"docker", "network", "connect", "k3d-my-cluster", "ctlptl-registry"}, calls[1])
+	assert.Equal(t, "k3d.io/v1alpha3", k3dConfig.APIVersion)
+	assert.Equal(t, "my-cluster", k3dConfig.Name)
+	assert.Equal(t, "rancher/k3s:v1.21.2-k3s1", k3dConfig.Image)
+	assert.Equal(t, `mirrors:
+    ctlptl-registry:5000:
+        endpoint:
+            - http://ctlptl-registry:5000
+    localhost:5002:
+        endpoint:
+            - http://ctlptl-registry:5000
+`, k3dConfig.Registries.Config)
+}
+
+func TestK3dClusterConfig(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	a := newK3dAdmin(iostreams, exec.FakeCmdRunner(func(argv []string) {}))
+
+	desired := &api.Cluster{
+		Name:    "k3d-my-cluster",
+		Product: "k3d",
+		K3DV1Alpha3Simple: &k3dv1alpha3.SimpleConfig{
+			Name:    "your-cluster",
+			Agents:  2,
+			Network: "my-network",
+			Registries: k3dv1alpha3.SimpleConfigRegistries{
+				Config: `configs:
+  docker.io:
+    auth:
+      username: nick
+`,
+			},
+		},
+	}
+	k3dConfig, err := a.k3dClusterConfig(desired, &api.Registry{
+		Name: "ctlptl-registry",
+		Status: api.RegistryStatus{
+			HostPort:      5002,
+			ContainerPort: 5000,
+		},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "my-cluster", k3dConfig.Name)
+	assert.Equal(t, 2, k3dConfig.Agents)
+	assert.Equal(t, "my-network", k3dNetworkName(k3dConfig))
+	assert.Equal(t, `mirrors:
+    ctlptl-registry:5000:
+        endpoint:
+            - http://ctlptl-registry:5000
+    localhost:5002:
+        endpoint:
+            - http://ctlptl-registry:5000
+configs:
+    docker.io:
+        auth:
+            username: nick
+`, k3dConfig.Registries.Config)
+
+	// Make sure we didn't modify the original config.
+	assert.Equal(t, "your-cluster", desired.K3DV1Alpha3Simple.Name)
+}
+
+func TestK3dClusterConfigNodes(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	a := newK3dAdmin(iostreams, exec.FakeCmdRunner(func(argv []string) {}))
+
+	k3dConfig, err := a.k3dClusterConfig(&api.Cluster{
+		Name:    "k3d-my-cluster",
+		Product: "k3d",
+		Nodes:   &api.ClusterNodes{ControlPlanes: 3, Workers: 2},
+	}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, k3dConfig.Servers)
+	assert.Equal(t, 2, k3dConfig.Agents)
+}
+
+func TestK3dClusterConfigIngress(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	a := newK3dAdmin(iostreams, exec.FakeCmdRunner(func(argv []string) {}))
+
+	k3dConfig, err := a.k3dClusterConfig(&api.Cluster{
+		Name:    "k3d-my-cluster",
+		Product: "k3d",
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []k3dv1alpha3.K3sArgWithNodeFilters{
+		{Arg: "--disable=traefik", NodeFilters: []string{"server:*"}},
+	}, k3dConfig.Options.K3sOptions.ExtraArgs)
+}
+
+func TestK3dCreateWithMirrors(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+
+	calls := [][]string{}
+	k3dConfig := k3dv1alpha3.SimpleConfig{}
+	runner := exec.FakeCmdRunner(func(argv []string) {
+		calls = append(calls, argv)
+		for i, arg := range argv {
+			if arg == "--config" {
+				contents, err := ioutil.ReadFile(argv[i+1])
+				require.NoError(t, err)
+				require.NoError(t, yaml.Unmarshal(contents, &k3dConfig))
+			}
+		}
+	})
+	a := newK3dAdmin(iostreams, runner)
+
+	err := a.CreateWithMirrors(context.Background(), &api.Cluster{
+		Name:    "k3d-my-cluster",
+		Product: "k3d",
+	}, nil, []registryMirror{{
+		host: "docker.io",
+		registry: &api.Registry{
+			Name:   "ctlptl-dockerhub-cache",
+			Status: api.RegistryStatus{ContainerPort: 5000, Networks: []string{"bridge"}},
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(calls))
+	assert.Equal(t, []string{"docker", "network", "connect", "k3d-my-cluster", "ctlptl-dockerhub-cache"}, calls[1])
+	assert.Equal(t, `mirrors:
+    docker.io:
+        endpoint:
+            - http://ctlptl-dockerhub-cache:5000
+`, k3dConfig.Registries.Config)
+}
+
+func TestK3dCreateBadName(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	a := newK3dAdmin(iostreams, exec.FakeCmdRunner(func(argv []string) {
+		t.Fatalf("unexpected command: %v", argv)
+	}))
+
+	err := a.Create(context.Background(), &api.Cluster{Name: "my-cluster", Product: "k3d"}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "prefix k3d-*")
+	}
+}
+
+func TestK3dLoadImage(t *testing.T) {
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	calls := [][]string{}
+	a := newK3dAdmin(iostreams, exec.FakeCmdRunner(func(argv []string) {
+		calls = append(calls, argv)
+	}))
+
+	err := a.LoadImage(context.Background(), &api.Cluster{Name: "k3d-my-cluster", Product: "k3d"}, "nginx:1.21")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"k3d", "image", "import", "nginx:1.21", "--cluster", "my-cluster"}}, calls)
+}
+
+func TestValidateK3dConfigTimeout(t *testing.T) {
+	c := &api.Cluster{
+		Product:           "k3d",
+		K3DV1Alpha3Simple: &k3dv1alpha3.SimpleConfig{},
+	}
+	c.K3DV1Alpha3Simple.Options.K3dOptions.Timeout = "60s"
+	assert.NoError(t, validateK3dConfig(c))
+
+	c.K3DV1Alpha3Simple.Options.K3dOptions.Timeout = "60"
+	err := validateK3dConfig(c)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "k3d options.k3d.timeout")
+	}
+}
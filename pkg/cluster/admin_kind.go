@@ -1,7 +1,6 @@
 package cluster
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os/exec"
@@ -10,37 +9,54 @@ import (
 	"github.com/blang/semver/v4"
 	"github.com/pkg/errors"
 	"github.com/tilt-dev/localregistry-go"
-	"gopkg.in/yaml.v3"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 
 	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 )
 
 const kindNetworkName = "kind"
 
-// kindAdmin uses the kind CLI to manipulate a kind cluster,
+// Where the kind node image keeps its containerd config.
+const kindContainerdConfigPath = "/etc/containerd/config.toml"
+
+// The containerd config table that holds registry mirrors on kind nodes.
+const kindMirrorsTable = `plugins."io.containerd.grpc.v1.cri".registry.mirrors`
+
+// kindAdmin uses kind to manipulate a kind cluster,
 // once the underlying machine has been setup.
 type kindAdmin struct {
 	iostreams genericclioptions.IOStreams
+	engine    docker.Engine
+
+	// Lazily initialized, so that we don't look for a kind binary
+	// or read the image table until we need them.
+	client     kindClient
+	nodeImages KindNodeImageTable
 }
 
-func newKindAdmin(iostreams genericclioptions.IOStreams) *kindAdmin {
+func newKindAdmin(iostreams genericclioptions.IOStreams, engine docker.Engine) *kindAdmin {
 	return &kindAdmin{
 		iostreams: iostreams,
+		engine:    engine,
 	}
 }
 
-func (a *kindAdmin) EnsureInstalled(ctx context.Context) error {
-	_, err := exec.LookPath("kind")
-	if err != nil {
-		return fmt.Errorf("kind not installed. Please install kind with these instructions: https://kind.sigs.k8s.io/")
+func (a *kindAdmin) kindClient(ctx context.Context) kindClient {
+	if a.client == nil {
+		a.client = newKindClient(ctx, a.iostreams, a.engine)
 	}
+	return a.client
+}
+
+// Kind is linked into ctlptl, so there's nothing to install.
+func (a *kindAdmin) EnsureInstalled(ctx context.Context) error {
 	return nil
 }
 
-func (a *kindAdmin) kindClusterConfig(desired *api.Cluster, registry *api.Registry) *v1alpha4.Cluster {
+func (a *kindAdmin) kindClusterConfig(desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) *v1alpha4.Cluster {
 	kindConfig := desired.KindV1Alpha4Cluster
 	if kindConfig == nil {
 		kindConfig = &v1alpha4.Cluster{}
@@ -50,19 +66,118 @@ func (a *kindAdmin) kindClusterConfig(desired *api.Cluster, registry *api.Regist
 	kindConfig.Kind = "Cluster"
 	kindConfig.APIVersion = "kind.x-k8s.io/v1alpha4"
 
+	if desired.Nodes != nil {
+		controlPlanes, workers := nodeCounts(desired.Nodes)
+		kindConfig.Nodes = nil
+		for i := 0; i < controlPlanes; i++ {
+			kindConfig.Nodes = append(kindConfig.Nodes, v1alpha4.Node{Role: v1alpha4.ControlPlaneRole})
+		}
+		for i := 0; i < workers; i++ {
+			kindConfig.Nodes = append(kindConfig.Nodes, v1alpha4.Node{Role: v1alpha4.WorkerRole})
+		}
+	}
+
+	if len(desired.PortMappings) > 0 {
+		// Kind forwards ports to a single node, so put them on the first control plane.
+		if len(kindConfig.Nodes) == 0 {
+			kindConfig.Nodes = []v1alpha4.Node{{Role: v1alpha4.ControlPlaneRole}}
+		}
+		for i, node := range kindConfig.Nodes {
+			if node.Role == v1alpha4.ControlPlaneRole {
+				kindConfig.Nodes[i].ExtraPortMappings = append(node.ExtraPortMappings,
+					kindPortMappings(desired.PortMappings)...)
+				break
+			}
+		}
+	}
+
+	// Feature gates in the kind config take precedence.
+	if len(desired.FeatureGates) > 0 && kindConfig.FeatureGates == nil {
+		kindConfig.FeatureGates = make(map[string]bool, len(desired.FeatureGates))
+	}
+	for gate, enabled := range desired.FeatureGates {
+		_, ok := kindConfig.FeatureGates[gate]
+		if !ok {
+			kindConfig.FeatureGates[gate] = enabled
+		}
+	}
+	kindConfig.KubeadmConfigPatches = append(kindConfig.KubeadmConfigPatches, kubeadmExtraArgsPatches(desired)...)
+
 	if registry != nil {
-		patch := fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."localhost:%d"]
-  endpoint = ["http://%s:%d"]
-[plugins."io.containerd.grpc.v1.cri".registry.mirrors."%s:%d"]
-  endpoint = ["http://%s:%d"]
-`, registry.Status.HostPort, registry.Name, registry.Status.ContainerPort,
-			registry.Name, registry.Status.ContainerPort, registry.Name, registry.Status.ContainerPort)
-		kindConfig.ContainerdConfigPatches = append(kindConfig.ContainerdConfigPatches, patch)
+		kindConfig.ContainerdConfigPatches = append(kindConfig.ContainerdConfigPatches,
+			kindMirrorsPatch(kindRegistryMirrors(registry)))
+	}
+
+	if len(mirrors) > 0 {
+		containerdMirrors := make([]containerdMirror, 0, len(mirrors))
+		for _, m := range mirrors {
+			containerdMirrors = append(containerdMirrors, containerdMirror{host: m.host, endpoint: m.endpoint()})
+		}
+		kindConfig.ContainerdConfigPatches = append(kindConfig.ContainerdConfigPatches,
+			kindMirrorsPatch(containerdMirrors))
 	}
 	return kindConfig
 }
 
+// The containerd mirrors that send pulls from the registry's host
+// and in-cluster names to the registry container.
+func kindRegistryMirrors(registry *api.Registry) []containerdMirror {
+	endpoint := fmt.Sprintf("http://%s:%d", registry.Name, registry.Status.ContainerPort)
+	return []containerdMirror{
+		{host: fmt.Sprintf("localhost:%d", registry.Status.HostPort), endpoint: endpoint},
+		{host: fmt.Sprintf("%s:%d", registry.Name, registry.Status.ContainerPort), endpoint: endpoint},
+	}
+}
+
+// Builds a containerd config patch that adds the mirrors.
+func kindMirrorsPatch(mirrors []containerdMirror) string {
+	patch := ""
+	for _, m := range mirrors {
+		patch += fmt.Sprintf(`[%s."%s"]
+  endpoint = ["%s"]
+`, kindMirrorsTable, m.host, m.endpoint)
+	}
+	return patch
+}
+
+// Removes the mirror tables for the mirrors' hosts from a containerd config.
+//
+// Each table runs from its header to the next header.
+func removeKindMirrors(config string, mirrors []containerdMirror) string {
+	headers := make(map[string]bool, len(mirrors))
+	for _, m := range mirrors {
+		headers[fmt.Sprintf(`[%s."%s"]`, kindMirrorsTable, m.host)] = true
+	}
+
+	result := ""
+	inMirror := false
+	for _, line := range strings.SplitAfter(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inMirror = headers[trimmed]
+		}
+		if !inMirror {
+			result += line
+		}
+	}
+	return result
+}
+
+// Adds the mirrors to a containerd config, replacing any mirrors
+// for the same hosts.
+func addKindMirrors(config string, mirrors []containerdMirror) string {
+	config = removeKindMirrors(config, mirrors)
+	if config != "" && !strings.HasSuffix(config, "\n") {
+		config += "\n"
+	}
+	return config + kindMirrorsPatch(mirrors)
+}
+
 func (a *kindAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	return a.CreateWithMirrors(ctx, desired, registry, nil)
+}
+
+func (a *kindAdmin) CreateWithMirrors(ctx context.Context, desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
 	klog.V(3).Infof("Creating cluster with config:\n%+v\n---\n", desired)
 	if registry != nil {
 		klog.V(3).Infof("Initializing cluster with registry config:\n%+v\n---\n", registry)
@@ -74,49 +189,120 @@ func (a *kindAdmin) Create(ctx context.Context, desired *api.Cluster, registry *
 	}
 
 	kindName := strings.TrimPrefix(clusterName, "kind-")
+	client := a.kindClient(ctx)
+
+	// If the kind nodes are still around (e.g., because someone deleted
+	// the kubeconfig context), we can't tell how they were configured.
+	nodes, err := client.listNodes(ctx, kindName)
+	if err != nil {
+		return errors.Wrap(err, "creating kind cluster")
+	}
+	if len(nodes) > 0 {
+		return fmt.Errorf("kind cluster %s already exists, but has no kubeconfig context. "+
+			"Run 'kind delete cluster --name %s' and try again", kindName, kindName)
+	}
 
-	args := []string{"create", "cluster", "--name", kindName}
-	if desired.KubernetesVersion != "" {
-		kindVersion, err := a.getKindVersion(ctx)
+	nodeImage := desired.NodeImage
+	if nodeImage == "" && desired.KubernetesVersion != "" {
+		kindVersion, err := client.version(ctx)
 		if err != nil {
 			return errors.Wrap(err, "creating cluster")
 		}
 
-		node, err := a.getNodeImage(ctx, kindVersion, desired.KubernetesVersion)
+		nodeImage, err = a.getNodeImage(ctx, kindVersion, desired.KubernetesVersion)
 		if err != nil {
 			return errors.Wrap(err, "creating cluster")
 		}
-		args = append(args, "--image", node)
 	}
 
-	kindConfig := a.kindClusterConfig(desired, registry)
-	buf := bytes.NewBuffer(nil)
-	encoder := yaml.NewEncoder(buf)
-	err := encoder.Encode(kindConfig)
+	kindConfig := a.kindClusterConfig(desired, registry, mirrors)
+	err = client.create(ctx, kindName, kindConfig, nodeImage)
 	if err != nil {
 		return errors.Wrap(err, "creating kind cluster")
 	}
 
-	args = append(args, "--config", "-")
+	if registry != nil {
+		err := a.connectRegistry(ctx, registry)
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range mirrors {
+		err := a.connectRegistry(ctx, m.registry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *kindAdmin) connectRegistry(ctx context.Context, registry *api.Registry) error {
+	if a.inKindNetwork(registry) {
+		return nil
+	}
 
-	cmd := exec.CommandContext(ctx, "kind", args...)
-	cmd.Stdout = a.iostreams.Out
-	cmd.Stderr = a.iostreams.ErrOut
-	cmd.Stdin = buf
-	err = cmd.Run()
+	_, _ = fmt.Fprintf(a.iostreams.ErrOut, "   Connecting kind to registry %s\n", registry.Name)
+	cmd := exec.CommandContext(ctx, a.engine.CLI(), "network", "connect", kindNetworkName, registry.Name)
+	err := cmd.Run()
 	if err != nil {
-		return errors.Wrap(err, "creating kind cluster")
+		return errors.Wrap(err, "connecting registry")
+	}
+	return nil
+}
+
+func (a *kindAdmin) AttachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	err := a.connectRegistry(ctx, registry)
+	if err != nil {
+		return err
+	}
+
+	mirrors := kindRegistryMirrors(registry)
+	return a.updateContainerdConfig(ctx, cluster, func(config string) string {
+		return addKindMirrors(config, mirrors)
+	})
+}
+
+// The kind network is shared by every kind cluster, so we leave the registry
+// connected to it, in case other clusters use the registry.
+func (a *kindAdmin) DetachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	mirrors := kindRegistryMirrors(registry)
+	return a.updateContainerdConfig(ctx, cluster, func(config string) string {
+		return removeKindMirrors(config, mirrors)
+	})
+}
+
+// Rewrites the containerd config on every node, then restarts containerd
+// so that it picks up the change.
+func (a *kindAdmin) updateContainerdConfig(ctx context.Context, cluster *api.Cluster, update func(config string) string) error {
+	kindName := strings.TrimPrefix(cluster.Name, "kind-")
+	nodes, err := a.kindClient(ctx).listNodes(ctx, kindName)
+	if err != nil {
+		return errors.Wrap(err, "configuring kind registry")
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found for kind cluster %s", kindName)
 	}
 
-	if registry != nil && !a.inKindNetwork(registry) {
-		_, _ = fmt.Fprintf(a.iostreams.ErrOut, "   Connecting kind to registry %s\n", registry.Name)
-		cmd := exec.CommandContext(ctx, "docker", "network", "connect", kindNetworkName, registry.Name)
-		err := cmd.Run()
+	cli := a.engine.CLI()
+	for _, node := range nodes {
+		config, err := exec.CommandContext(ctx, cli, "exec", node, "cat", kindContainerdConfigPath).Output()
 		if err != nil {
-			return errors.Wrap(err, "connecting registry")
+			return errors.Wrapf(err, "reading containerd config on node %s", node)
+		}
+
+		cmd := exec.CommandContext(ctx, cli, "exec", "-i", node, "cp", "/dev/stdin", kindContainerdConfigPath)
+		cmd.Stdin = strings.NewReader(update(string(config)))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "writing containerd config on node %s: %s", node, strings.TrimSpace(string(out)))
 		}
-	}
 
+		out, err = exec.CommandContext(ctx, cli, "exec", node, "systemctl", "restart", "containerd").CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "restarting containerd on node %s: %s", node, strings.TrimSpace(string(out)))
+		}
+	}
 	return nil
 }
 
@@ -144,24 +330,35 @@ func (a *kindAdmin) Delete(ctx context.Context, config *api.Cluster) error {
 	}
 
 	kindName := strings.TrimPrefix(clusterName, "kind-")
-	cmd := exec.CommandContext(ctx, "kind", "delete", "cluster", "--name", kindName)
-	cmd.Stdout = a.iostreams.Out
-	cmd.Stderr = a.iostreams.ErrOut
-	cmd.Stdin = a.iostreams.In
-	err := cmd.Run()
+	err := a.kindClient(ctx).delete(ctx, kindName)
 	if err != nil {
 		return errors.Wrap(err, "deleting kind cluster")
 	}
 	return nil
 }
 
+func (a *kindAdmin) LoadImage(ctx context.Context, cluster *api.Cluster, image string) error {
+	kindName := strings.TrimPrefix(cluster.Name, "kind-")
+	return a.kindClient(ctx).loadImage(ctx, kindName, image)
+}
+
 func (a *kindAdmin) getNodeImage(ctx context.Context, kindVersion, k8sVersion string) (string, error) {
-	nodeTable, ok := kindK8sNodeTable[kindVersion]
+	if a.nodeImages == nil {
+		nodeImages, err := LoadKindNodeImageTable(ctx)
+		if err != nil {
+			return "", err
+		}
+		a.nodeImages = nodeImages
+	}
+
+	nodeTable, ok := a.nodeImages[kindVersion]
 	if !ok {
 		return "", fmt.Errorf("unsupported Kind version %s.\n"+
 			"To set up a specific Kubernetes version in Kind, ctlptl needs an official Kubernetes image.\n"+
-			"If you're running an unofficial version of Kind, remove 'kubernetesVersion' from your cluster config to use the default image.\n"+
-			"If you're running a newly released version of Kind, please file an issue: https://github.com/tilt-dev/ctlptl/issues/new", kindVersion)
+			"If you're running an unofficial version of Kind, remove 'kubernetesVersion' from your cluster config to use the default image, "+
+			"or set 'nodeImage' to the image you want.\n"+
+			"If you're running a newly released version of Kind, add its images to ~/.ctlptl/kind-images.yaml "+
+			"(see 'ctlptl get kind-images' for the format), or file an issue: https://github.com/tilt-dev/ctlptl/issues/new", kindVersion)
 	}
 
 	// Kind doesn't maintain Kubernetes nodes for every patch version, so just get the closest
@@ -178,89 +375,3 @@ func (a *kindAdmin) getNodeImage(ctx context.Context, kindVersion, k8sVersion st
 	}
 	return node, nil
 }
-
-func (a *kindAdmin) getKindVersion(ctx context.Context) (string, error) {
-	cmd := exec.CommandContext(ctx, "kind", "version")
-	out, err := cmd.Output()
-	if err != nil {
-		return "", errors.Wrap(err, "kind version")
-	}
-
-	parts := strings.Split(string(out), " ")
-	if len(parts) < 2 {
-		return "", fmt.Errorf("parsing kind version output: %s", string(out))
-	}
-
-	return parts[1], nil
-}
-
-// This table must be built up manually from the Kind release notes each
-// time a new Kind version is released :\
-var kindK8sNodeTable = map[string]map[string]string{
-	"v0.11.1": map[string]string{
-		"1.21": "kindest/node:v1.21.1@sha256:69860bda5563ac81e3c0057d654b5253219618a22ec3a346306239bba8cfa1a6",
-		"1.20": "kindest/node:v1.20.7@sha256:cbeaf907fc78ac97ce7b625e4bf0de16e3ea725daf6b04f930bd14c67c671ff9",
-		"1.19": "kindest/node:v1.19.11@sha256:07db187ae84b4b7de440a73886f008cf903fcf5764ba8106a9fd5243d6f32729",
-		"1.18": "kindest/node:v1.18.19@sha256:7af1492e19b3192a79f606e43c35fb741e520d195f96399284515f077b3b622c",
-		"1.17": "kindest/node:v1.17.17@sha256:66f1d0d91a88b8a001811e2f1054af60eef3b669a9a74f9b6db871f2f1eeed00",
-		"1.16": "kindest/node:v1.16.15@sha256:83067ed51bf2a3395b24687094e283a7c7c865ccc12a8b1d7aa673ba0c5e8861",
-		"1.15": "kindest/node:v1.15.12@sha256:b920920e1eda689d9936dfcf7332701e80be12566999152626b2c9d730397a95",
-		"1.14": "kindest/node:v1.14.10@sha256:f8a66ef82822ab4f7569e91a5bccaf27bceee135c1457c512e54de8c6f7219f8",
-	},
-	"v0.11.0": map[string]string{
-		"1.21": "kindest/node:v1.21.1@sha256:fae9a58f17f18f06aeac9772ca8b5ac680ebbed985e266f711d936e91d113bad",
-		"1.20": "kindest/node:v1.20.7@sha256:e645428988191fc824529fd0bb5c94244c12401cf5f5ea3bd875eb0a787f0fe9",
-		"1.19": "kindest/node:v1.19.11@sha256:7664f21f9cb6ba2264437de0eb3fe99f201db7a3ac72329547ec4373ba5f5911",
-		"1.18": "kindest/node:v1.18.19@sha256:530378628c7c518503ade70b1df698b5de5585dcdba4f349328d986b8849b1ee",
-		"1.17": "kindest/node:v1.17.17@sha256:c581fbf67f720f70aaabc74b44c2332cc753df262b6c0bca5d26338492470c17",
-		"1.16": "kindest/node:v1.16.15@sha256:430c03034cd856c1f1415d3e37faf35a3ea9c5aaa2812117b79e6903d1fc9651",
-		"1.15": "kindest/node:v1.15.12@sha256:8d575f056493c7778935dd855ded0e95c48cb2fab90825792e8fc9af61536bf9",
-		"1.14": "kindest/node:v1.14.10@sha256:6033e04bcfca7c5f2a9c4ce77551e1abf385bcd2709932ec2f6a9c8c0aff6d4f",
-	},
-	"v0.10.0": map[string]string{
-		"1.20": "kindest/node:v1.20.2@sha256:8f7ea6e7642c0da54f04a7ee10431549c0257315b3a634f6ef2fecaaedb19bab",
-		"1.19": "kindest/node:v1.19.7@sha256:a70639454e97a4b733f9d9b67e12c01f6b0297449d5b9cbbef87473458e26dca",
-		"1.18": "kindest/node:v1.18.15@sha256:5c1b980c4d0e0e8e7eb9f36f7df525d079a96169c8a8f20d8bd108c0d0889cc4",
-		"1.17": "kindest/node:v1.17.17@sha256:7b6369d27eee99c7a85c48ffd60e11412dc3f373658bc59b7f4d530b7056823e",
-		"1.16": "kindest/node:v1.16.15@sha256:c10a63a5bda231c0a379bf91aebf8ad3c79146daca59db816fb963f731852a99",
-		"1.15": "kindest/node:v1.15.12@sha256:67181f94f0b3072fb56509107b380e38c55e23bf60e6f052fbd8052d26052fb5",
-		"1.14": "kindest/node:v1.14.10@sha256:3fbed72bcac108055e46e7b4091eb6858ad628ec51bf693c21f5ec34578f6180",
-	},
-	"v0.9.0": map[string]string{
-		"1.19": "kindest/node:v1.19.1@sha256:98cf5288864662e37115e362b23e4369c8c4a408f99cbc06e58ac30ddc721600",
-		"1.18": "kindest/node:v1.18.8@sha256:f4bcc97a0ad6e7abaf3f643d890add7efe6ee4ab90baeb374b4f41a4c95567eb",
-		"1.17": "kindest/node:v1.17.11@sha256:5240a7a2c34bf241afb54ac05669f8a46661912eab05705d660971eeb12f6555",
-		"1.16": "kindest/node:v1.16.15@sha256:a89c771f7de234e6547d43695c7ab047809ffc71a0c3b65aa54eda051c45ed20",
-		"1.15": "kindest/node:v1.15.12@sha256:d9b939055c1e852fe3d86955ee24976cab46cba518abcb8b13ba70917e6547a6",
-		"1.14": "kindest/node:v1.14.10@sha256:ce4355398a704fca68006f8a29f37aafb49f8fc2f64ede3ccd0d9198da910146",
-		"1.13": "kindest/node:v1.13.12@sha256:1c1a48c2bfcbae4d5f4fa4310b5ed10756facad0b7a2ca93c7a4b5bae5db29f5",
-	},
-	"v0.8.1": map[string]string{
-		"1.18": "kindest/node:v1.18.2@sha256:7b27a6d0f2517ff88ba444025beae41491b016bc6af573ba467b70c5e8e0d85f",
-		"1.17": "kindest/node:v1.17.5@sha256:ab3f9e6ec5ad8840eeb1f76c89bb7948c77bbf76bcebe1a8b59790b8ae9a283a",
-		"1.16": "kindest/node:v1.16.9@sha256:7175872357bc85847ec4b1aba46ed1d12fa054c83ac7a8a11f5c268957fd5765",
-		"1.15": "kindest/node:v1.15.11@sha256:6cc31f3533deb138792db2c7d1ffc36f7456a06f1db5556ad3b6927641016f50",
-		"1.14": "kindest/node:v1.14.10@sha256:6cd43ff41ae9f02bb46c8f455d5323819aec858b99534a290517ebc181b443c6",
-		"1.13": "kindest/node:v1.13.12@sha256:214476f1514e47fe3f6f54d0f9e24cfb1e4cda449529791286c7161b7f9c08e7",
-		"1.12": "kindest/node:v1.12.10@sha256:faeb82453af2f9373447bb63f50bae02b8020968e0889c7fa308e19b348916cb",
-	},
-	"v0.8.0": map[string]string{
-		"1.18": "kindest/node:v1.18.2@sha256:7b27a6d0f2517ff88ba444025beae41491b016bc6af573ba467b70c5e8e0d85f",
-		"1.17": "kindest/node:v1.17.5@sha256:ab3f9e6ec5ad8840eeb1f76c89bb7948c77bbf76bcebe1a8b59790b8ae9a283a",
-		"1.16": "kindest/node:v1.16.9@sha256:7175872357bc85847ec4b1aba46ed1d12fa054c83ac7a8a11f5c268957fd5765",
-		"1.15": "kindest/node:v1.15.11@sha256:6cc31f3533deb138792db2c7d1ffc36f7456a06f1db5556ad3b6927641016f50",
-		"1.14": "kindest/node:v1.14.10@sha256:6cd43ff41ae9f02bb46c8f455d5323819aec858b99534a290517ebc181b443c6",
-		"1.13": "kindest/node:v1.13.12@sha256:214476f1514e47fe3f6f54d0f9e24cfb1e4cda449529791286c7161b7f9c08e7",
-		"1.12": "kindest/node:v1.12.10@sha256:faeb82453af2f9373447bb63f50bae02b8020968e0889c7fa308e19b348916cb",
-	},
-	"v0.7.0": map[string]string{
-		"1.18": "kindest/node:v1.18.0@sha256:0e20578828edd939d25eb98496a685c76c98d54084932f76069f886ec315d694",
-		"1.17": "kindest/node:v1.17.0@sha256:9512edae126da271b66b990b6fff768fbb7cd786c7d39e86bdf55906352fdf62",
-		"1.16": "kindest/node:v1.16.4@sha256:b91a2c2317a000f3a783489dfb755064177dbc3a0b2f4147d50f04825d016f55",
-		"1.15": "kindest/node:v1.15.7@sha256:e2df133f80ef633c53c0200114fce2ed5e1f6947477dbc83261a6a921169488d",
-		"1.14": "kindest/node:v1.14.10@sha256:81ae5a3237c779efc4dda43cc81c696f88a194abcc4f8fa34f86cf674aa14977",
-		"1.13": "kindest/node:v1.13.12@sha256:5e8ae1a4e39f3d151d420ef912e18368745a2ede6d20ea87506920cd947a7e3a",
-		"1.12": "kindest/node:v1.12.10@sha256:68a6581f64b54994b824708286fafc37f1227b7b54cbb8865182ce1e036ed1cc",
-		"1.11": "kindest/node:v1.11.10@sha256:e6f3dade95b7cb74081c5b9f3291aaaa6026a90a977e0b990778b6adc9ea6248",
-	},
-}
@@ -7,6 +7,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 )
 
 func TestNodeImage(t *testing.T) {
@@ -15,7 +19,7 @@ func TestNodeImage(t *testing.T) {
 		Out:    os.Stdout,
 		ErrOut: os.Stderr,
 	}
-	a := newKindAdmin(iostreams)
+	a := newKindAdmin(iostreams, docker.EngineDocker)
 	ctx := context.Background()
 
 	img, err := a.getNodeImage(ctx, "v0.9.0", "v1.19")
@@ -34,3 +38,223 @@ func TestNodeImage(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "kindest/node:v1.16.9@sha256:7175872357bc85847ec4b1aba46ed1d12fa054c83ac7a8a11f5c268957fd5765", img)
 }
+
+func TestParseKindVersion(t *testing.T) {
+	v, err := parseKindVersion("kind v0.11.1 go1.16.4 darwin/amd64\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.11.1", v)
+
+	v, err = parseKindVersion("kind v0.12.0-alpha+6d3e4d5a1f2b3c go1.17 linux/amd64\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.12.0-alpha+6d3e4d5a1f2b3c", v)
+
+	_, err = parseKindVersion("command not found")
+	assert.Error(t, err)
+}
+
+func TestKindCreate(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.Create(context.Background(), &api.Cluster{
+		Name:              "kind-kind",
+		KubernetesVersion: "v1.19.3",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "kind", client.created)
+	assert.Equal(t, "kindest/node:v1.19.1@sha256:98cf5288864662e37115e362b23e4369c8c4a408f99cbc06e58ac30ddc721600", client.nodeImage)
+	assert.Equal(t, "kind.x-k8s.io/v1alpha4", client.config.APIVersion)
+}
+
+func TestKindCreateNodes(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.Create(context.Background(), &api.Cluster{
+		Name:  "kind-kind",
+		Nodes: &api.ClusterNodes{Workers: 2},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha4.Node{
+		{Role: v1alpha4.ControlPlaneRole},
+		{Role: v1alpha4.WorkerRole},
+		{Role: v1alpha4.WorkerRole},
+	}, client.config.Nodes)
+}
+
+func TestKindCreateExistingNodes(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0", nodes: []string{"kind-control-plane"}}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.Create(context.Background(), &api.Cluster{Name: "kind-kind"}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Run 'kind delete cluster --name kind'")
+	}
+	assert.Equal(t, "", client.created)
+}
+
+func TestKindCreateFeatureGates(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.Create(context.Background(), &api.Cluster{
+		Name:         "kind-kind",
+		FeatureGates: map[string]bool{"EphemeralContainers": true, "CSIStorageCapacity": true},
+		APIServer:    &api.ClusterComponent{ExtraArgs: map[string]string{"v": "4"}},
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			FeatureGates: map[string]bool{"CSIStorageCapacity": false},
+		},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"EphemeralContainers": true, "CSIStorageCapacity": false},
+		client.config.FeatureGates)
+	assert.Equal(t, []string{`kind: ClusterConfiguration
+apiServer:
+  extraArgs:
+    "v": "4"
+`}, client.config.KubeadmConfigPatches)
+}
+
+func TestKindCreateWithMirrors(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.CreateWithMirrors(context.Background(), &api.Cluster{Name: "kind-kind"}, nil, []registryMirror{
+		{
+			host: "docker.io",
+			registry: &api.Registry{
+				Name:   "ctlptl-dockerhub-cache",
+				Status: api.RegistryStatus{ContainerPort: 5000, Networks: []string{"bridge", "kind"}},
+			},
+		},
+		{
+			host: "gcr.io",
+			registry: &api.Registry{
+				Name:   "ctlptl-gcr-cache",
+				Status: api.RegistryStatus{ContainerPort: 5000, Networks: []string{"kind"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["http://ctlptl-dockerhub-cache:5000"]
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."gcr.io"]
+  endpoint = ["http://ctlptl-gcr-cache:5000"]
+`}, client.config.ContainerdConfigPatches)
+}
+
+func TestKindAddAndRemoveMirrors(t *testing.T) {
+	config := `version = 2
+
+[plugins]
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "k8s.gcr.io/pause:3.5"
+    [plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+      endpoint = ["http://ctlptl-dockerhub-cache:5000"]
+`
+	mirrors := kindRegistryMirrors(&api.Registry{
+		Name:   "ctlptl-registry",
+		Status: api.RegistryStatus{ContainerPort: 5000, HostPort: 5002},
+	})
+
+	attached := addKindMirrors(config, mirrors)
+	assert.Equal(t, config+`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."localhost:5002"]
+  endpoint = ["http://ctlptl-registry:5000"]
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."ctlptl-registry:5000"]
+  endpoint = ["http://ctlptl-registry:5000"]
+`, attached)
+
+	// Attaching again replaces the old mirrors rather than duplicating them.
+	assert.Equal(t, attached, addKindMirrors(attached, mirrors))
+
+	// Detaching leaves the other mirrors alone.
+	assert.Equal(t, config, removeKindMirrors(attached, mirrors))
+}
+
+func TestKindDelete(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.9.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	err := a.Delete(context.Background(), &api.Cluster{Name: "kind-kind"})
+	assert.NoError(t, err)
+	assert.Equal(t, "kind", client.deleted)
+
+	err = a.Delete(context.Background(), &api.Cluster{Name: "kind"})
+	assert.Error(t, err)
+}
+
+type fakeKindClient struct {
+	kindVersion string
+	nodes       []string
+	created     string
+	config      *v1alpha4.Cluster
+	nodeImage   string
+	deleted     string
+	loaded      []string
+}
+
+func (c *fakeKindClient) version(ctx context.Context) (string, error) {
+	return c.kindVersion, nil
+}
+
+func (c *fakeKindClient) create(ctx context.Context, name string, config *v1alpha4.Cluster, nodeImage string) error {
+	c.created = name
+	c.config = config
+	c.nodeImage = nodeImage
+	return nil
+}
+
+func (c *fakeKindClient) delete(ctx context.Context, name string) error {
+	c.deleted = name
+	return nil
+}
+
+func (c *fakeKindClient) listNodes(ctx context.Context, name string) ([]string, error) {
+	return c.nodes, nil
+}
+
+func (c *fakeKindClient) loadImage(ctx context.Context, name string, image string) error {
+	c.loaded = append(c.loaded, name+"/"+image)
+	return nil
+}
+
+func TestKindNodeImageTableMerge(t *testing.T) {
+	table, err := parseKindNodeImageTable(embeddedKindImages)
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.11.1", table.KindVersions()[0])
+
+	user, err := parseKindNodeImageTable([]byte(`
+v0.11.1:
+  "1.21": example.com/node:v1.21.1
+v0.12.0:
+  "1.23": kindest/node:v1.23.4
+`))
+	assert.NoError(t, err)
+
+	table.merge(user)
+	assert.Equal(t, "v0.12.0", table.KindVersions()[0])
+	assert.Equal(t, "kindest/node:v1.23.4", table["v0.12.0"]["1.23"])
+	assert.Equal(t, "example.com/node:v1.21.1", table["v0.11.1"]["1.21"])
+	assert.Equal(t, "kindest/node:v1.20.7@sha256:cbeaf907fc78ac97ce7b625e4bf0de16e3ea725daf6b04f930bd14c67c671ff9", table["v0.11.1"]["1.20"])
+}
+
+func TestKindCreateNodeImage(t *testing.T) {
+	client := &fakeKindClient{kindVersion: "v0.99.0"}
+	a := newKindAdmin(genericclioptions.NewTestIOStreamsDiscard(), docker.EngineDocker)
+	a.client = client
+
+	// An explicit node image skips the lookup, so an unknown kind version is OK.
+	err := a.Create(context.Background(), &api.Cluster{
+		Name:              "kind-kind",
+		KubernetesVersion: "v1.23.0",
+		NodeImage:         "kindest/node:v1.23.0",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "kindest/node:v1.23.0", client.nodeImage)
+}
@@ -0,0 +1,229 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"github.com/tilt-dev/localregistry-go"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	cexec "github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+// The directory where the MicroK8s containerd looks for per-registry host
+// configuration.
+//
+// https://microk8s.io/docs/registry-private
+const microk8sCertsDir = "/var/snap/microk8s/current/args/certs.d"
+
+// The names that `microk8s config` uses for its kubeconfig entries.
+const microk8sContextName = "microk8s"
+const microk8sClusterName = "microk8s-cluster"
+const microk8sUserName = "microk8s-admin"
+
+// microk8sAdmin uses the microk8s CLI to manipulate a microk8s cluster,
+// once the underlying machine has been setup.
+//
+// MicroK8s only supports one cluster per machine, so the admin manages
+// the whole MicroK8s install.
+//
+// If the cluster is created with a registry, we wire the registry into
+// the MicroK8s containerd config. Otherwise, we enable the built-in MicroK8s
+// registry addon, which advertises itself to tools on its own.
+type microk8sAdmin struct {
+	iostreams   genericclioptions.IOStreams
+	runner      cexec.CmdRunner
+	os          string
+	certsDir    string
+	readConfig  func(ctx context.Context) ([]byte, error)
+	pathOptions *clientcmd.PathOptions
+}
+
+func newMicrok8sAdmin(iostreams genericclioptions.IOStreams, runner cexec.CmdRunner) *microk8sAdmin {
+	return &microk8sAdmin{
+		iostreams:   iostreams,
+		runner:      runner,
+		os:          runtime.GOOS,
+		certsDir:    microk8sCertsDir,
+		readConfig:  readMicrok8sConfig,
+		pathOptions: clientcmd.NewDefaultPathOptions(),
+	}
+}
+
+func (a *microk8sAdmin) EnsureInstalled(ctx context.Context) error {
+	_, err := exec.LookPath("microk8s")
+	if err != nil {
+		return fmt.Errorf("microk8s not installed. Please install microk8s with these instructions: https://microk8s.io/docs/getting-started")
+	}
+	return nil
+}
+
+func (a *microk8sAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	klog.V(3).Infof("Creating cluster with config:\n%+v\n---\n", desired)
+	if registry != nil {
+		klog.V(3).Infof("Initializing cluster with registry config:\n%+v\n---\n", registry)
+	}
+
+	if desired.Name != microk8sContextName {
+		return fmt.Errorf("microk8s clusters must be named %s", microk8sContextName)
+	}
+
+	if registry != nil {
+		err := a.writeRegistryHosts(registry)
+		if err != nil {
+			return errors.Wrap(err, "configuring microk8s registry")
+		}
+	}
+
+	err := a.runner.RunIO(ctx, a.outStreams(), "microk8s", "start")
+	if err != nil {
+		return errors.Wrap(err, "starting microk8s")
+	}
+
+	err = a.runner.RunIO(ctx, a.outStreams(), "microk8s", "status", "--wait-ready")
+	if err != nil {
+		return errors.Wrap(err, "starting microk8s")
+	}
+
+	if registry == nil {
+		err = a.runner.RunIO(ctx, a.outStreams(), "microk8s", "enable", "registry")
+		if err != nil {
+			return errors.Wrap(err, "enabling microk8s registry")
+		}
+	}
+
+	return a.mergeKubeconfig(ctx)
+}
+
+func (a *microk8sAdmin) outStreams() genericclioptions.IOStreams {
+	return genericclioptions.IOStreams{Out: a.iostreams.Out, ErrOut: a.iostreams.ErrOut}
+}
+
+// MicroK8s runs containerd directly on the host, so the cluster can reach
+// the registry at the same localhost port that we use to push.
+func (a *microk8sAdmin) writeRegistryHosts(registry *api.Registry) error {
+	if a.os != "linux" {
+		return fmt.Errorf("connecting a registry to microk8s is only supported on Linux")
+	}
+
+	host := fmt.Sprintf("localhost:%d", registry.Status.HostPort)
+	dir := filepath.Join(a.certsDir, host)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return errors.Wrap(err, "writing containerd hosts (ctlptl may need permission to modify the microk8s snap)")
+	}
+
+	contents := fmt.Sprintf(`server = "http://%s"
+
+[host."http://%s"]
+  capabilities = ["pull", "resolve"]
+`, host, host)
+	err = ioutil.WriteFile(filepath.Join(dir, "hosts.toml"), []byte(contents), 0644)
+	if err != nil {
+		return errors.Wrap(err, "writing containerd hosts (ctlptl may need permission to modify the microk8s snap)")
+	}
+	return nil
+}
+
+func readMicrok8sConfig(ctx context.Context) ([]byte, error) {
+	return exec.CommandContext(ctx, "microk8s", "config").Output()
+}
+
+// MicroK8s keeps its own kubeconfig. Copy its cluster into the user's kubeconfig
+// so that kubectl (and ctlptl) can find it.
+func (a *microk8sAdmin) mergeKubeconfig(ctx context.Context) error {
+	data, err := a.readConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reading microk8s kubeconfig")
+	}
+
+	mConfig, err := clientcmd.Load(data)
+	if err != nil {
+		return errors.Wrap(err, "reading microk8s kubeconfig")
+	}
+
+	config, err := a.pathOptions.GetStartingConfig()
+	if err != nil {
+		return errors.Wrap(err, "reading kubeconfig")
+	}
+
+	err = mergeMicrok8sConfig(config, mConfig)
+	if err != nil {
+		return err
+	}
+
+	return clientcmd.ModifyConfig(a.pathOptions, *config, false)
+}
+
+// Copies the microk8s context into the given config.
+//
+// `microk8s config` names its user "admin", which is too generic
+// to copy as-is, so we rename it.
+func mergeMicrok8sConfig(config, mConfig *clientcmdapi.Config) error {
+	mContext, ok := mConfig.Contexts[microk8sContextName]
+	if !ok {
+		return fmt.Errorf("microk8s kubeconfig: no context %q", microk8sContextName)
+	}
+	mCluster, ok := mConfig.Clusters[mContext.Cluster]
+	if !ok {
+		return fmt.Errorf("microk8s kubeconfig: no cluster %q", mContext.Cluster)
+	}
+	mUser, ok := mConfig.AuthInfos[mContext.AuthInfo]
+	if !ok {
+		return fmt.Errorf("microk8s kubeconfig: no user %q", mContext.AuthInfo)
+	}
+
+	newContext := mContext.DeepCopy()
+	newContext.Cluster = microk8sClusterName
+	newContext.AuthInfo = microk8sUserName
+	newContext.LocationOfOrigin = ""
+	newCluster := mCluster.DeepCopy()
+	newCluster.LocationOfOrigin = ""
+	newUser := mUser.DeepCopy()
+	newUser.LocationOfOrigin = ""
+
+	if config.Contexts == nil {
+		config.Contexts = map[string]*clientcmdapi.Context{}
+	}
+	if config.Clusters == nil {
+		config.Clusters = map[string]*clientcmdapi.Cluster{}
+	}
+	if config.AuthInfos == nil {
+		config.AuthInfos = map[string]*clientcmdapi.AuthInfo{}
+	}
+	config.Contexts[microk8sContextName] = newContext
+	config.Clusters[microk8sClusterName] = newCluster
+	config.AuthInfos[microk8sUserName] = newUser
+	return nil
+}
+
+func (a *microk8sAdmin) LocalRegistryHosting(ctx context.Context, desired *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
+	return &localregistry.LocalRegistryHostingV1{
+		Host:                   fmt.Sprintf("localhost:%d", registry.Status.HostPort),
+		HostFromClusterNetwork: fmt.Sprintf("localhost:%d", registry.Status.HostPort),
+		Help:                   "https://github.com/tilt-dev/ctlptl",
+	}, nil
+}
+
+func (a *microk8sAdmin) Delete(ctx context.Context, config *api.Cluster) error {
+	err := a.runner.RunIO(ctx, a.iostreams, "microk8s", "reset")
+	if err != nil {
+		return errors.Wrap(err, "deleting microk8s cluster")
+	}
+
+	err = a.runner.RunIO(ctx, a.iostreams, "microk8s", "stop")
+	if err != nil {
+		return errors.Wrap(err, "deleting microk8s cluster")
+	}
+	return nil
+}
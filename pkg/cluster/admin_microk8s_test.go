@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+const fakeMicrok8sConfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2VydA==
+    server: https://127.0.0.1:16443
+  name: microk8s-cluster
+contexts:
+- context:
+    cluster: microk8s-cluster
+    user: admin
+  name: microk8s
+current-context: microk8s
+kind: Config
+preferences: {}
+users:
+- name: admin
+  user:
+    token: fake-token
+`
+
+type microk8sFixture struct {
+	t          *testing.T
+	dir        string
+	calls      [][]string
+	a          *microk8sAdmin
+	kubeconfig string
+}
+
+func newMicrok8sFixture(t *testing.T) *microk8sFixture {
+	dir, err := ioutil.TempDir("", "ctlptl-microk8s")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	f := &microk8sFixture{t: t, dir: dir, kubeconfig: filepath.Join(dir, "kubeconfig")}
+	err = ioutil.WriteFile(f.kubeconfig, []byte{}, 0600)
+	require.NoError(t, err)
+
+	iostreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	runner := exec.FakeCmdRunner(func(argv []string) {
+		f.calls = append(f.calls, argv)
+	})
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = f.kubeconfig
+	pathOptions.EnvVar = ""
+	pathOptions.LoadingRules.ExplicitPath = f.kubeconfig
+
+	a := newMicrok8sAdmin(iostreams, runner)
+	a.os = "linux"
+	a.certsDir = filepath.Join(dir, "certs.d")
+	a.readConfig = func(ctx context.Context) ([]byte, error) {
+		return []byte(fakeMicrok8sConfig), nil
+	}
+	a.pathOptions = pathOptions
+	f.a = a
+	return f
+}
+
+func TestMicrok8sCreate(t *testing.T) {
+	f := newMicrok8sFixture(t)
+	err := f.a.Create(context.Background(), &api.Cluster{Name: "microk8s", Product: "microk8s"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"microk8s", "start"},
+		{"microk8s", "status", "--wait-ready"},
+		{"microk8s", "enable", "registry"},
+	}, f.calls)
+
+	config, err := clientcmd.LoadFromFile(f.kubeconfig)
+	require.NoError(t, err)
+	assert.Equal(t, "microk8s-cluster", config.Contexts["microk8s"].Cluster)
+	assert.Equal(t, "microk8s-admin", config.Contexts["microk8s"].AuthInfo)
+	assert.Equal(t, "https://127.0.0.1:16443", config.Clusters["microk8s-cluster"].Server)
+	assert.Equal(t, "fake-token", config.AuthInfos["microk8s-admin"].Token)
+}
+
+func TestMicrok8sCreateWithRegistry(t *testing.T) {
+	f := newMicrok8sFixture(t)
+	err := f.a.Create(context.Background(), &api.Cluster{Name: "microk8s", Product: "microk8s"}, &api.Registry{
+		Name: "ctlptl-registry",
+		Status: api.RegistryStatus{
+			HostPort:      5002,
+			ContainerPort: 5000,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"microk8s", "start"},
+		{"microk8s", "status", "--wait-ready"},
+	}, f.calls)
+
+	contents, err := ioutil.ReadFile(filepath.Join(f.dir, "certs.d", "localhost:5002", "hosts.toml"))
+	require.NoError(t, err)
+	assert.Equal(t, `server = "http://localhost:5002"
+
+[host."http://localhost:5002"]
+  capabilities = ["pull", "resolve"]
+`, string(contents))
+}
+
+func TestMicrok8sCreateBadName(t *testing.T) {
+	f := newMicrok8sFixture(t)
+	err := f.a.Create(context.Background(), &api.Cluster{Name: "my-cluster", Product: "microk8s"}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "must be named microk8s")
+	}
+	assert.Equal(t, 0, len(f.calls))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/pkg/errors"
 	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 	"github.com/tilt-dev/localregistry-go"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog/v2"
@@ -38,21 +39,16 @@ func (a *minikubeAdmin) EnsureInstalled(ctx context.Context) error {
 }
 
 func (a *minikubeAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	return a.CreateWithMirrors(ctx, desired, registry, nil)
+}
+
+func (a *minikubeAdmin) CreateWithMirrors(ctx context.Context, desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
 	klog.V(3).Infof("Creating cluster with config:\n%+v\n---\n", desired)
 	if registry != nil {
 		klog.V(3).Infof("Initializing cluster with registry config:\n%+v\n---\n", registry)
 	}
 
-	clusterName := desired.Name
-
-	// TODO(nick): Let the user pass in their own Minikube configuration.
-	args := []string{"start", "--driver=docker", "--container-runtime=containerd", "-p", clusterName}
-	if desired.MinCPUs != 0 {
-		args = append(args, fmt.Sprintf("--cpus=%d", desired.MinCPUs))
-	}
-	if desired.KubernetesVersion != "" {
-		args = append(args, "--kubernetes-version", desired.KubernetesVersion)
-	}
+	args := minikubeStartArgs(desired, a.dockerClient.Engine())
 
 	in := strings.NewReader("")
 
@@ -65,14 +61,13 @@ func (a *minikubeAdmin) Create(ctx context.Context, desired *api.Cluster, regist
 		return errors.Wrap(err, "creating minikube cluster")
 	}
 
-	if registry != nil {
-		container, err := a.dockerClient.ContainerInspect(ctx, clusterName)
+	if registry != nil || len(mirrors) > 0 {
+		networkMode, err := a.networkMode(ctx, desired)
 		if err != nil {
-			return errors.Wrap(err, "inspecting minikube cluster")
+			return err
 		}
-		networkMode := container.HostConfig.NetworkMode
 
-		err = a.applyContainerdPatch(ctx, desired, registry, networkMode)
+		err = a.applyContainerdPatch(ctx, desired, registry, mirrors, networkMode)
 		if err != nil {
 			return err
 		}
@@ -81,16 +76,148 @@ func (a *minikubeAdmin) Create(ctx context.Context, desired *api.Cluster, regist
 	return nil
 }
 
-func (a *minikubeAdmin) applyContainerdPatch(ctx context.Context, desired *api.Cluster, registry *api.Registry, networkMode container.NetworkMode) error {
-	configPath := "/etc/containerd/config.toml"
+// Builds the `minikube start` arguments from the cluster config.
+//
+// Fields in the top-level cluster config (like minCPUs) come before the
+// raw start flags, so that users can override them if they need to.
+//
+// If the config doesn't specify a driver, we use the driver for the container engine.
+func minikubeStartArgs(desired *api.Cluster, engine docker.Engine) []string {
+	config := desired.Minikube
+	if config == nil {
+		config = &api.MinikubeCluster{}
+	}
+
+	driver := config.Driver
+	if driver == "" {
+		driver = engine.CLI()
+	}
+	containerRuntime := config.ContainerRuntime
+	if containerRuntime == "" {
+		containerRuntime = "containerd"
+	}
+
+	args := []string{"start",
+		fmt.Sprintf("--driver=%s", driver),
+		fmt.Sprintf("--container-runtime=%s", containerRuntime),
+		"-p", desired.Name,
+	}
+	if desired.MinCPUs != 0 {
+		args = append(args, fmt.Sprintf("--cpus=%d", desired.MinCPUs))
+	}
+	if desired.KubernetesVersion != "" {
+		args = append(args, "--kubernetes-version", desired.KubernetesVersion)
+	}
+	if desired.Nodes != nil {
+		controlPlanes, workers := nodeCounts(desired.Nodes)
+		args = append(args, fmt.Sprintf("--nodes=%d", controlPlanes+workers))
+	}
+	for _, m := range desired.PortMappings {
+		args = append(args, fmt.Sprintf("--ports=%s", dockerPortSpec(m)))
+	}
+	// Explicit minikube sizes take precedence over minMemory and minDisk.
+	if config.Memory != "" {
+		args = append(args, fmt.Sprintf("--memory=%s", config.Memory))
+	} else if minMemory, err := minMemoryBytes(desired); err == nil && minMemory != 0 {
+		args = append(args, fmt.Sprintf("--memory=%dmb", ceilDiv(minMemory, mebibyte)))
+	}
+	if config.DiskSize != "" {
+		args = append(args, fmt.Sprintf("--disk-size=%s", config.DiskSize))
+	} else if minDisk, err := minDiskBytes(desired); err == nil && minDisk != 0 {
+		args = append(args, fmt.Sprintf("--disk-size=%dmb", ceilDiv(minDisk, mebibyte)))
+	}
+	if len(desired.FeatureGates) > 0 {
+		args = append(args, fmt.Sprintf("--feature-gates=%s", featureGatesFlag(desired.FeatureGates)))
+	}
+	for _, extraConfig := range minikubeExtraConfigs(desired) {
+		args = append(args, fmt.Sprintf("--extra-config=%s", extraConfig))
+	}
+	for _, extraConfig := range config.ExtraConfigs {
+		args = append(args, fmt.Sprintf("--extra-config=%s", extraConfig))
+	}
+	for _, addon := range config.Addons {
+		args = append(args, fmt.Sprintf("--addons=%s", addon))
+	}
+	args = append(args, config.StartFlags...)
+	return args
+}
 
+// A containerd mirror entry: pulls from host go to the endpoint.
+type containerdMirror struct {
+	host     string
+	endpoint string
+}
+
+// Builds a sed expression that adds the mirrors to the containerd config.
+//
+// minikube's config already has a mirror for docker.io, and TOML doesn't allow
+// the same table twice, so we delete the existing tables for these hosts first.
+// The deletes have to come before the insert, or they'd match the new tables.
+func minikubeMirrorsSedExpr(mirrors []containerdMirror) string {
+	// this is the most annoying sed expression i've ever had to write
+	// minikube does not give us great primitives for writing files on the host machine :\
+	// so we have to hack around the shell escaping on its interactive shell
+	expr := `s,\\\[plugins.cri.registry.mirrors\\\],[plugins.cri.registry.mirrors]`
+	if len(mirrors) > 0 {
+		expr = minikubeRemoveMirrorsSedExpr(mirrors) + `\;` + expr
+	}
+	for _, m := range mirrors {
+		expr += fmt.Sprintf(
+			`\\\n`+
+				`\ \ \ \ \ \ \ \ [plugins.cri.registry.mirrors.\\\"%s\\\"]\\\n`+
+				`\ \ \ \ \ \ \ \ \ \ endpoint\ =\ [\\\"%s\\\"]`,
+			m.host, m.endpoint)
+	}
+	return expr + ","
+}
+
+// Builds a sed expression that deletes the mirrors from the containerd config,
+// with the same escaping as minikubeMirrorsSedExpr.
+func minikubeRemoveMirrorsSedExpr(mirrors []containerdMirror) string {
+	exprs := make([]string, 0, len(mirrors))
+	for _, m := range mirrors {
+		// Each mirror is a header line followed by an endpoint line.
+		exprs = append(exprs, fmt.Sprintf(`/\\\[plugins.cri.registry.mirrors.\\\"%s\\\"\\\]/,+1d`, m.host))
+	}
+	return strings.Join(exprs, `\;`)
+}
+
+// The docker network mode of the minikube cluster's container.
+func (a *minikubeAdmin) networkMode(ctx context.Context, cluster *api.Cluster) (container.NetworkMode, error) {
+	container, err := a.dockerClient.ContainerInspect(ctx, cluster.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "inspecting minikube cluster")
+	}
+	return container.HostConfig.NetworkMode, nil
+}
+
+// The host that the nodes use to reach the registry.
+func minikubeNetworkHost(registry *api.Registry, networkMode container.NetworkMode) string {
+	if networkMode.IsUserDefined() {
+		return registry.Name
+	}
+	return registry.Status.IPAddress
+}
+
+// The containerd mirrors that send pulls from the registry's host
+// and in-cluster names to the registry container.
+func minikubeRegistryMirrors(registry *api.Registry, networkMode container.NetworkMode) []containerdMirror {
+	networkHost := minikubeNetworkHost(registry, networkMode)
+	endpoint := fmt.Sprintf("http://%s:%d", networkHost, registry.Status.ContainerPort)
+	return []containerdMirror{
+		{host: fmt.Sprintf("localhost:%d", registry.Status.HostPort), endpoint: endpoint},
+		{host: fmt.Sprintf("%s:%d", networkHost, registry.Status.ContainerPort), endpoint: endpoint},
+	}
+}
+
+func (a *minikubeAdmin) nodes(ctx context.Context, cluster *api.Cluster) ([]string, error) {
 	nodeOutput := bytes.NewBuffer(nil)
-	cmd := exec.CommandContext(ctx, "minikube", "-p", desired.Name, "node", "list")
+	cmd := exec.CommandContext(ctx, "minikube", "-p", cluster.Name, "node", "list")
 	cmd.Stdout = nodeOutput
 	cmd.Stderr = a.iostreams.ErrOut
 	err := cmd.Run()
 	if err != nil {
-		return errors.Wrap(err, "configuring minikube registry")
+		return nil, err
 	}
 
 	nodes := []string{}
@@ -106,36 +233,23 @@ func (a *minikubeAdmin) applyContainerdPatch(ctx context.Context, desired *api.C
 		}
 		nodes = append(nodes, node)
 	}
+	return nodes, nil
+}
 
-	// Minikube v0.15.0+ creates a unique network for each minikube cluster.
-	if networkMode.IsUserDefined() && !a.inRegistryNetwork(registry, networkMode) {
-		cmd := exec.CommandContext(ctx, "docker", "network", "connect", networkMode.UserDefined(), registry.Name)
-		err := cmd.Run()
-		if err != nil {
-			return errors.Wrap(err, "connecting registry")
-		}
+// Runs the sed expression on the containerd config on every node,
+// then restarts containerd so that it picks up the change.
+func (a *minikubeAdmin) editContainerdConfig(ctx context.Context, cluster *api.Cluster, sedExpr string) error {
+	configPath := "/etc/containerd/config.toml"
+
+	nodes, err := a.nodes(ctx, cluster)
+	if err != nil {
+		return errors.Wrap(err, "configuring minikube registry")
 	}
 
 	for _, node := range nodes {
-		networkHost := registry.Status.IPAddress
-		if networkMode.IsUserDefined() {
-			networkHost = registry.Name
-		}
-
-		// this is the most annoying sed expression i've ever had to write
-		// minikube does not give us great primitives for writing files on the host machine :\
-		// so we have to hack around the shell escaping on its interactive shell
-		cmd := exec.CommandContext(ctx, "minikube", "-p", desired.Name, "--node", node,
+		cmd := exec.CommandContext(ctx, "minikube", "-p", cluster.Name, "--node", node,
 			"ssh", "sudo", "sed", `\-i`,
-			fmt.Sprintf(
-				`s,\\\[plugins.cri.registry.mirrors\\\],[plugins.cri.registry.mirrors]\\\n`+
-					`\ \ \ \ \ \ \ \ [plugins.cri.registry.mirrors.\\\"localhost:%d\\\"]\\\n`+
-					`\ \ \ \ \ \ \ \ \ \ endpoint\ =\ [\\\"http://%s:%d\\\"]\\\n`+
-					`\ \ \ \ \ \ \ \ [plugins.cri.registry.mirrors.\\\"%s:%d\\\"]\\\n`+
-					`\ \ \ \ \ \ \ \ \ \ endpoint\ =\ [\\\"http://%s:%d\\\"],`,
-				registry.Status.HostPort, networkHost, registry.Status.ContainerPort,
-				networkHost, registry.Status.ContainerPort,
-				networkHost, registry.Status.ContainerPort),
+			sedExpr,
 			configPath)
 		cmd.Stderr = a.iostreams.ErrOut
 		cmd.Stdout = a.iostreams.Out
@@ -144,7 +258,7 @@ func (a *minikubeAdmin) applyContainerdPatch(ctx context.Context, desired *api.C
 			return errors.Wrap(err, "configuring minikube registry")
 		}
 
-		cmd = exec.CommandContext(ctx, "minikube", "-p", desired.Name, "--node", node,
+		cmd = exec.CommandContext(ctx, "minikube", "-p", cluster.Name, "--node", node,
 			"ssh", "sudo", "systemctl", "restart", "containerd")
 		cmd.Stderr = a.iostreams.ErrOut
 		cmd.Stdout = a.iostreams.Out
@@ -156,6 +270,79 @@ func (a *minikubeAdmin) applyContainerdPatch(ctx context.Context, desired *api.C
 	return nil
 }
 
+func (a *minikubeAdmin) applyContainerdPatch(ctx context.Context, desired *api.Cluster, registry *api.Registry, mirrors []registryMirror, networkMode container.NetworkMode) error {
+	// Minikube v0.15.0+ creates a unique network for each minikube cluster.
+	registries := []*api.Registry{}
+	if registry != nil {
+		registries = append(registries, registry)
+	}
+	for _, m := range mirrors {
+		registries = append(registries, m.registry)
+	}
+	for _, reg := range registries {
+		if networkMode.IsUserDefined() && !a.inRegistryNetwork(reg, networkMode) {
+			cmd := exec.CommandContext(ctx, a.dockerClient.Engine().CLI(), "network", "connect", networkMode.UserDefined(), reg.Name)
+			err := cmd.Run()
+			if err != nil {
+				return errors.Wrap(err, "connecting registry")
+			}
+		}
+	}
+
+	containerdMirrors := []containerdMirror{}
+	if registry != nil {
+		containerdMirrors = append(containerdMirrors, minikubeRegistryMirrors(registry, networkMode)...)
+	}
+	for _, m := range mirrors {
+		containerdMirrors = append(containerdMirrors, containerdMirror{
+			host:     m.host,
+			endpoint: fmt.Sprintf("http://%s:%d", minikubeNetworkHost(m.registry, networkMode), m.registry.Status.ContainerPort),
+		})
+	}
+
+	return a.editContainerdConfig(ctx, desired, minikubeMirrorsSedExpr(containerdMirrors))
+}
+
+func (a *minikubeAdmin) AttachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	networkMode, err := a.networkMode(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	return a.applyContainerdPatch(ctx, cluster, registry, nil, networkMode)
+}
+
+// Writes the registry and mirrors back into the containerd config
+// after `minikube start` resets it.
+func (a *minikubeAdmin) RestoreRegistryConfig(ctx context.Context, cluster *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
+	networkMode, err := a.networkMode(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	return a.applyContainerdPatch(ctx, cluster, registry, mirrors, networkMode)
+}
+
+func (a *minikubeAdmin) DetachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	networkMode, err := a.networkMode(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	err = a.editContainerdConfig(ctx, cluster, minikubeRemoveMirrorsSedExpr(minikubeRegistryMirrors(registry, networkMode)))
+	if err != nil {
+		return err
+	}
+
+	// The network belongs to this cluster, so no other cluster needs the registry on it.
+	if networkMode.IsUserDefined() && a.inRegistryNetwork(registry, networkMode) {
+		cmd := exec.CommandContext(ctx, a.dockerClient.Engine().CLI(), "network", "disconnect", networkMode.UserDefined(), registry.Name)
+		err := cmd.Run()
+		if err != nil {
+			return errors.Wrap(err, "disconnecting registry")
+		}
+	}
+	return nil
+}
+
 func (a *minikubeAdmin) inRegistryNetwork(registry *api.Registry, networkMode container.NetworkMode) bool {
 	for _, n := range registry.Status.Networks {
 		if n == networkMode.UserDefined() {
@@ -166,23 +353,43 @@ func (a *minikubeAdmin) inRegistryNetwork(registry *api.Registry, networkMode co
 }
 
 func (a *minikubeAdmin) LocalRegistryHosting(ctx context.Context, desired *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
-	container, err := a.dockerClient.ContainerInspect(ctx, desired.Name)
+	networkMode, err := a.networkMode(ctx, desired)
 	if err != nil {
-		return nil, errors.Wrap(err, "inspecting minikube cluster")
-	}
-	networkMode := container.HostConfig.NetworkMode
-	networkHost := registry.Status.IPAddress
-	if networkMode.IsUserDefined() {
-		networkHost = registry.Name
+		return nil, err
 	}
 
 	return &localregistry.LocalRegistryHostingV1{
 		Host:                   fmt.Sprintf("localhost:%d", registry.Status.HostPort),
-		HostFromClusterNetwork: fmt.Sprintf("%s:%d", networkHost, registry.Status.ContainerPort),
+		HostFromClusterNetwork: fmt.Sprintf("%s:%d", minikubeNetworkHost(registry, networkMode), registry.Status.ContainerPort),
 		Help:                   "https://github.com/tilt-dev/ctlptl",
 	}, nil
 }
 
+// Minikube upgrades the cluster when we start it at a newer version.
+//
+// Start it with the same flags we created it with. Otherwise, minikube
+// would fall back to its defaults, e.g., for the driver and runtime.
+func (a *minikubeAdmin) Upgrade(ctx context.Context, desired, existing *api.Cluster) error {
+	args := minikubeStartArgs(desired, a.dockerClient.Engine())
+	cmd := exec.CommandContext(ctx, "minikube", args...)
+	cmd.Stdout = a.iostreams.Out
+	cmd.Stderr = a.iostreams.ErrOut
+	cmd.Stdin = strings.NewReader("")
+	err := cmd.Run()
+	if err != nil {
+		return errors.Wrap(err, "upgrading minikube cluster")
+	}
+	return nil
+}
+
+func (a *minikubeAdmin) LoadImage(ctx context.Context, cluster *api.Cluster, image string) error {
+	out, err := exec.CommandContext(ctx, "minikube", "-p", cluster.Name, "image", "load", image).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "minikube image load: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 func (a *minikubeAdmin) Delete(ctx context.Context, config *api.Cluster) error {
 	cmd := exec.CommandContext(ctx, "minikube", "delete", "-p", config.Name)
 	cmd.Stdout = a.iostreams.Out
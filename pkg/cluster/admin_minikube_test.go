@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+)
+
+func TestMinikubeStartArgsDefault(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name:              "minikube",
+		MinCPUs:           3,
+		KubernetesVersion: "v1.19.1",
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=docker", "--container-runtime=containerd", "-p", "minikube",
+		"--cpus=3", "--kubernetes-version", "v1.19.1",
+	}, args)
+}
+
+func TestMinikubeStartArgsConfig(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name: "minikube",
+		Minikube: &api.MinikubeCluster{
+			Driver:           "hyperkit",
+			ContainerRuntime: "cri-o",
+			Memory:           "4g",
+			DiskSize:         "20g",
+			ExtraConfigs:     []string{"kubelet.max-pods=100"},
+			Addons:           []string{"ingress", "metrics-server"},
+			StartFlags:       []string{"--embed-certs"},
+		},
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=hyperkit", "--container-runtime=cri-o", "-p", "minikube",
+		"--memory=4g", "--disk-size=20g",
+		"--extra-config=kubelet.max-pods=100",
+		"--addons=ingress", "--addons=metrics-server",
+		"--embed-certs",
+	}, args)
+}
+
+func TestMinikubeStartArgsMinResources(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name:      "minikube",
+		MinMemory: "8Gi",
+		MinDisk:   "40G",
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=docker", "--container-runtime=containerd", "-p", "minikube",
+		"--memory=8192mb", "--disk-size=38147mb",
+	}, args)
+}
+
+func TestMinikubeStartArgsNodes(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name:  "minikube",
+		Nodes: &api.ClusterNodes{Workers: 2},
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=docker", "--container-runtime=containerd", "-p", "minikube",
+		"--nodes=3",
+	}, args)
+}
+
+func TestMinikubeStartArgsPortMappings(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name: "minikube",
+		PortMappings: []api.PortMapping{
+			{HostPort: 8080, ContainerPort: 80},
+			{HostPort: 8443, ContainerPort: 443, ListenAddress: "127.0.0.1"},
+		},
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=docker", "--container-runtime=containerd", "-p", "minikube",
+		"--ports=8080:80/tcp", "--ports=127.0.0.1:8443:443/tcp",
+	}, args)
+}
+
+func TestMinikubeStartArgsExtraArgs(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{
+		Name:         "minikube",
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+		APIServer:    &api.ClusterComponent{ExtraArgs: map[string]string{"v": "4"}},
+		Kubelet:      &api.ClusterComponent{ExtraArgs: map[string]string{"max-pods": "200"}},
+		Minikube: &api.MinikubeCluster{
+			ExtraConfigs: []string{"kubelet.max-pods=300"},
+		},
+	}, docker.EngineDocker)
+	assert.Equal(t, []string{
+		"start", "--driver=docker", "--container-runtime=containerd", "-p", "minikube",
+		"--feature-gates=EphemeralContainers=true",
+		"--extra-config=apiserver.v=4",
+		"--extra-config=kubelet.max-pods=200",
+		"--extra-config=kubelet.max-pods=300",
+	}, args)
+}
+
+func TestMinikubeStartArgsPodman(t *testing.T) {
+	args := minikubeStartArgs(&api.Cluster{Name: "minikube"}, docker.EnginePodman)
+	assert.Equal(t, []string{
+		"start", "--driver=podman", "--container-runtime=containerd", "-p", "minikube",
+	}, args)
+}
+
+func TestMinikubeMirrorsSedExpr(t *testing.T) {
+	expr := minikubeMirrorsSedExpr([]containerdMirror{
+		{host: "localhost:5002", endpoint: "http://ctlptl-registry:5000"},
+		{host: "docker.io", endpoint: "http://ctlptl-dockerhub-cache:5000"},
+	})
+	assert.Equal(t,
+		`/\\\[plugins.cri.registry.mirrors.\\\"localhost:5002\\\"\\\]/,+1d\;`+
+			`/\\\[plugins.cri.registry.mirrors.\\\"docker.io\\\"\\\]/,+1d\;`+
+			`s,\\\[plugins.cri.registry.mirrors\\\],[plugins.cri.registry.mirrors]\\\n`+
+			`\ \ \ \ \ \ \ \ [plugins.cri.registry.mirrors.\\\"localhost:5002\\\"]\\\n`+
+			`\ \ \ \ \ \ \ \ \ \ endpoint\ =\ [\\\"http://ctlptl-registry:5000\\\"]\\\n`+
+			`\ \ \ \ \ \ \ \ [plugins.cri.registry.mirrors.\\\"docker.io\\\"]\\\n`+
+			`\ \ \ \ \ \ \ \ \ \ endpoint\ =\ [\\\"http://ctlptl-dockerhub-cache:5000\\\"],`,
+		expr)
+}
+
+func TestMinikubeRemoveMirrorsSedExpr(t *testing.T) {
+	expr := minikubeRemoveMirrorsSedExpr([]containerdMirror{
+		{host: "localhost:5002", endpoint: "http://ctlptl-registry:5000"},
+		{host: "ctlptl-registry:5000", endpoint: "http://ctlptl-registry:5000"},
+	})
+	assert.Equal(t,
+		`/\\\[plugins.cri.registry.mirrors.\\\"localhost:5002\\\"\\\]/,+1d\;`+
+			`/\\\[plugins.cri.registry.mirrors.\\\"ctlptl-registry:5000\\\"\\\]/,+1d`,
+		expr)
+}
@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tilt-dev/localregistry-go"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+// The RancherDesktop admin manages the Kubernetes cluster for Rancher Desktop.
+//
+// Like Docker Desktop, most of the work happens on the machine, which
+// toggles Kubernetes and sets the Kubernetes version with rdctl.
+type rancherDesktopAdmin struct {
+	rd rdClient
+}
+
+func newRancherDesktopAdmin(rd rdClient) *rancherDesktopAdmin {
+	return &rancherDesktopAdmin{rd: rd}
+}
+
+func (a *rancherDesktopAdmin) EnsureInstalled(ctx context.Context) error { return nil }
+
+func (a *rancherDesktopAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	if registry != nil {
+		return fmt.Errorf("ctlptl currently does not support connecting a registry to rancher-desktop")
+	}
+	if desired.Name != string(ProductRancherDesktop) {
+		return fmt.Errorf("rancher-desktop clusters must be named %s", ProductRancherDesktop)
+	}
+	return nil
+}
+
+func (a *rancherDesktopAdmin) LocalRegistryHosting(ctx context.Context, desired *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
+	return nil, nil
+}
+
+func (a *rancherDesktopAdmin) Delete(ctx context.Context, config *api.Cluster) error {
+	settings, err := a.rd.settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	disabled := false
+	args, err := rancherDesktopSetArgs(settings, rancherDesktopTarget{k8sEnabled: &disabled})
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return a.rd.set(ctx, args)
+}
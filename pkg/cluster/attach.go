@@ -0,0 +1,144 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
+)
+
+// Setting a cluster's registry to "none" disconnects it from its current registry.
+//
+// An empty registry means the cluster config doesn't care.
+const noRegistry = "none"
+
+// An admin that can connect an existing cluster to a registry,
+// and disconnect it again, without recreating the cluster.
+type registryAttacher interface {
+	// Configures the container runtime on every node to pull from the registry,
+	// and connects the registry to the cluster network.
+	AttachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error
+
+	// Removes the registry from the container runtime config on every node.
+	DetachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error
+}
+
+func supportsRegistryAttach(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube
+}
+
+// The registry that the desired cluster should be connected to,
+// or "" if it shouldn't be connected to one.
+func desiredRegistryName(desired *api.Cluster) string {
+	if desired.Registry == noRegistry {
+		return ""
+	}
+	return desired.Registry
+}
+
+// Whether the desired cluster asks for a different registry than
+// the existing cluster is connected to.
+func registryChanged(desired, existing *api.Cluster) bool {
+	if desired.Registry == noRegistry {
+		return existing.Registry != ""
+	}
+	return desired.Registry != "" && desired.Registry != existing.Registry
+}
+
+// Describes how Apply would change the registry of an existing cluster.
+func planRegistryAttach(desired, existing *api.Cluster) []plan.Action {
+	if !registryChanged(desired, existing) {
+		return nil
+	}
+
+	var actions []plan.Action
+	if existing.Registry != "" {
+		actions = append(actions, plan.Action{Description: fmt.Sprintf("detach registry %s", existing.Registry)})
+	}
+	if name := desiredRegistryName(desired); name != "" {
+		actions = append(actions, plan.Action{Description: fmt.Sprintf("attach registry %s", name)})
+	}
+	return actions
+}
+
+// Looks up a registry that a cluster is connected to.
+func (c *Controller) getRegistry(ctx context.Context, name string) (*api.Registry, error) {
+	regCtl, err := c.registryController(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := regCtl.List(ctx, registry.ListOptions{FieldSelector: fmt.Sprintf("name=%s", name)})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		if item.Name == name {
+			return item.DeepCopy(), nil
+		}
+	}
+	return nil, fmt.Errorf("registry %s not found", name)
+}
+
+// Connects an existing cluster to the desired registry in place,
+// disconnecting it from its current registry first.
+//
+// The registry may be nil if the cluster should not have one.
+func (c *Controller) reattachRegistry(ctx context.Context, admin Admin, desired, existing *api.Cluster, reg *api.Registry) error {
+	attacher, ok := admin.(registryAttacher)
+	if !ok {
+		return fmt.Errorf("product %s can't change the registry of an existing cluster", desired.Product)
+	}
+
+	if existing.Registry != "" {
+		current, err := c.getRegistry(ctx, existing.Registry)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(c.iostreams.ErrOut, " 🔌 Disconnecting cluster %s from registry %s\n", existing.Name, current.Name)
+		err = attacher.DetachRegistry(ctx, existing, current)
+		if err != nil {
+			return err
+		}
+
+		err = c.deleteRegistryHosting(ctx, existing)
+		if err != nil {
+			return err
+		}
+	}
+
+	if reg != nil {
+		err := attacher.AttachRegistry(ctx, desired, reg)
+		if err != nil {
+			return err
+		}
+
+		err = c.createRegistryHosting(ctx, admin, desired, reg)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing.Registry = desiredRegistryName(desired)
+	return c.writeClusterSpec(ctx, existing)
+}
+
+// Removes the configmap that tells other tools about the cluster's registry.
+func (c *Controller) deleteRegistryHosting(ctx context.Context, cluster *api.Cluster) error {
+	client, err := c.client(cluster.Name)
+	if err != nil {
+		return err
+	}
+
+	err = client.CoreV1().ConfigMaps("kube-public").Delete(ctx, localRegistryHostingConfigMap, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
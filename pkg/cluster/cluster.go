@@ -14,7 +14,9 @@ import (
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
+	"github.com/tilt-dev/ctlptl/internal/exec"
 	"github.com/tilt-dev/ctlptl/internal/socat"
 	"github.com/tilt-dev/ctlptl/pkg/api"
 	"github.com/tilt-dev/ctlptl/pkg/docker"
@@ -26,11 +28,13 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -42,11 +46,14 @@ import (
 )
 
 const clusterSpecConfigMap = "ctlptl-cluster-spec"
+const localRegistryHostingConfigMap = "local-registry-hosting"
 
 var typeMeta = api.TypeMeta{APIVersion: "ctlptl.dev/v1alpha1", Kind: "Cluster"}
 var listTypeMeta = api.TypeMeta{APIVersion: "ctlptl.dev/v1alpha1", Kind: "ClusterList"}
 var groupResource = schema.GroupResource{Group: "ctlptl.dev", Resource: "clusters"}
 
+var errClusterSpecNotFound = errors.New("cluster spec not found")
+
 // Due to the way the Kubernetes apiserver works, there's no easy way to
 // distinguish between "server is taking a long time to respond because it's
 // gone" and "server is taking a long time to respond because it has a slow auth
@@ -75,6 +82,10 @@ type registryController interface {
 
 type clientLoader func(*rest.Config) (kubernetes.Interface, error)
 
+type dynamicClientLoader func(*rest.Config) (dynamic.Interface, error)
+
+type dockerClientLoader func(context.Context, docker.Engine) (dockerClient, error)
+
 type socatController interface {
 	ConnectRemoteDockerPort(ctx context.Context, port int) error
 }
@@ -83,16 +94,22 @@ type Controller struct {
 	iostreams                   genericclioptions.IOStreams
 	config                      clientcmdapi.Config
 	clients                     map[string]kubernetes.Interface
+	dynamicClients              map[string]dynamic.Interface
 	admins                      map[Product]Admin
 	dockerClient                dockerClient
+	engine                      docker.Engine
 	dmachine                    *dockerMachine
 	configLoader                configLoader
 	configWriter                configWriter
 	registryCtl                 registryController
 	clientLoader                clientLoader
+	dynamicClientLoader         dynamicClientLoader
+	dockerClientLoader          dockerClientLoader
 	socat                       socatController
 	waitForKubeConfigTimeout    time.Duration
 	waitForClusterCreateTimeout time.Duration
+	waitForReadyTimeout         time.Duration
+	confirmRecreate             ConfirmRecreateFunc
 
 	// TODO(nick): I deeply regret making this struct use goroutines. It makes
 	// everything so much more complex.
@@ -119,6 +136,14 @@ func DefaultController(iostreams genericclioptions.IOStreams) (*Controller, erro
 		return kubernetes.NewForConfig(restConfig)
 	})
 
+	dynamicClientLoader := dynamicClientLoader(func(restConfig *rest.Config) (dynamic.Interface, error) {
+		return dynamic.NewForConfig(restConfig)
+	})
+
+	dockerClientLoader := dockerClientLoader(func(ctx context.Context, engine docker.Engine) (dockerClient, error) {
+		return newDockerWrapper(ctx, engine)
+	})
+
 	config, err := configLoader()
 	if err != nil {
 		return nil, err
@@ -129,11 +154,15 @@ func DefaultController(iostreams genericclioptions.IOStreams) (*Controller, erro
 		config:                      config,
 		configWriter:                configWriter,
 		clients:                     make(map[string]kubernetes.Interface),
+		dynamicClients:              make(map[string]dynamic.Interface),
 		admins:                      make(map[Product]Admin),
 		configLoader:                configLoader,
 		clientLoader:                clientLoader,
+		dynamicClientLoader:         dynamicClientLoader,
+		dockerClientLoader:          dockerClientLoader,
 		waitForKubeConfigTimeout:    waitForKubeConfigTimeout,
 		waitForClusterCreateTimeout: waitForClusterCreateTimeout,
+		waitForReadyTimeout:         waitForReadyTimeout,
 	}, nil
 }
 
@@ -147,7 +176,7 @@ func (c *Controller) getSocatController(ctx context.Context) (socatController, e
 	defer c.mu.Unlock()
 
 	if c.socat == nil {
-		c.socat = socat.NewController(dcli)
+		c.socat = socat.NewController(dcli, dcli.Engine())
 	}
 
 	return c.socat, nil
@@ -161,16 +190,51 @@ func (c *Controller) getDockerClient(ctx context.Context) (dockerClient, error)
 		return c.dockerClient, nil
 	}
 
-	client, err := newDockerWrapperFromEnv(ctx)
+	engine := c.engine
+	if engine == "" {
+		var err error
+		engine, err = docker.ResolveEngine("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := c.dockerClientLoader(ctx, engine)
 	if err != nil {
 		return nil, err
 	}
 
+	c.engine = engine
 	c.dockerClient = client
 	return client, nil
 }
 
-func (c *Controller) machine(ctx context.Context, name string, product Product) (Machine, error) {
+// Pins the container engine for this controller.
+//
+// An empty name means "auto-detect". The engine can't change once
+// we've connected to it.
+func (c *Controller) useEngine(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	engine, err := docker.ResolveEngine(name)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dockerClient != nil && c.dockerClient.Engine() != engine {
+		return fmt.Errorf("cannot use container engine %s: already connected to %s",
+			engine, c.dockerClient.Engine())
+	}
+	c.engine = engine
+	return nil
+}
+
+func (c *Controller) machine(ctx context.Context, cluster *api.Cluster) (Machine, error) {
 	dockerClient, err := c.getDockerClient(ctx)
 	if err != nil {
 		return nil, err
@@ -179,7 +243,7 @@ func (c *Controller) machine(ctx context.Context, name string, product Product)
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	switch product {
+	switch Product(cluster.Product) {
 	case ProductDockerDesktop, ProductKIND, ProductK3D:
 		if c.dmachine == nil {
 			machine, err := NewDockerMachine(ctx, dockerClient, c.iostreams.ErrOut)
@@ -198,20 +262,37 @@ func (c *Controller) machine(ctx context.Context, name string, product Product)
 			}
 			c.dmachine = machine
 		}
-		return newMinikubeMachine(name, c.dmachine), nil
+		driver := minikubeDesiredDriver(cluster, dockerClient.Engine())
+		return newMinikubeMachine(cluster.Name, driver, c.dmachine, exec.RealCmdRunner{}, c.iostreams.ErrOut)
+
+	case ProductMicroK8s:
+		return newMicrok8sMachine(exec.RealCmdRunner{}, c.iostreams.ErrOut), nil
+
+	case ProductRancherDesktop:
+		return newRancherDesktopMachine(newRancherDesktopClient(exec.RealCmdRunner{}), c.iostreams.ErrOut), nil
+	}
+
+	path, ok := findPlugin(Product(cluster.Product))
+	if ok {
+		return newPluginMachine(newPluginClient(path, exec.RealCmdRunner{}, c.iostreams.ErrOut), cluster), nil
 	}
 
-	return unknownMachine{product: product}, nil
+	return unknownMachine{product: Product(cluster.Product)}, nil
 }
 
 func (c *Controller) registryController(ctx context.Context) (registryController, error) {
+	_, err := c.getDockerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	result := c.registryCtl
 	if result == nil {
 		var err error
-		result, err = registry.DefaultController(ctx, c.iostreams)
+		result, err = registry.ControllerForEngine(ctx, c.iostreams, c.engine)
 		if err != nil {
 			return nil, err
 		}
@@ -236,27 +317,78 @@ func (c *Controller) admin(ctx context.Context, product Product) (Admin, error)
 		return admin, nil
 	}
 
+	admin, err = c.newAdmin(product, dockerClient)
+	if err != nil {
+		return nil, err
+	}
+	c.admins[product] = admin
+	return admin, nil
+}
+
+// The admin for an existing cluster.
+//
+// Uses the container engine that created the cluster, even if
+// this controller is connected to a different engine.
+func (c *Controller) adminForCluster(ctx context.Context, cluster *api.Cluster) (Admin, error) {
+	dockerClient, err := c.getDockerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.Engine == "" || docker.Engine(cluster.Engine) == dockerClient.Engine() {
+		return c.admin(ctx, Product(cluster.Product))
+	}
+
+	engine, err := docker.ResolveEngine(cluster.Engine)
+	if err != nil {
+		return nil, err
+	}
+	engineClient, err := c.dockerClientLoader(ctx, engine)
+	if err != nil {
+		return nil, err
+	}
+	return c.newAdmin(Product(cluster.Product), engineClient)
+}
+
+func (c *Controller) newAdmin(product Product, dockerClient dockerClient) (Admin, error) {
+	var admin Admin
 	switch product {
 	case ProductDockerDesktop:
 		if !dockerClient.IsLocalHost() {
 			return nil, fmt.Errorf("Detected remote DOCKER_HOST. Remote Docker engines do not support Docker Desktop clusters: %s",
 				docker.GetHostEnv())
 		}
+		if dockerClient.Engine() != docker.EngineDocker {
+			return nil, fmt.Errorf("Docker Desktop clusters require the docker engine. Current engine: %s",
+				dockerClient.Engine())
+		}
 
 		admin = newDockerDesktopAdmin()
 	case ProductKIND:
-		admin = newKindAdmin(c.iostreams)
+		admin = newKindAdmin(c.iostreams, dockerClient.Engine())
 	case ProductMinikube:
 		admin = newMinikubeAdmin(c.iostreams, dockerClient)
+	case ProductK3D:
+		admin = newK3dAdmin(c.iostreams, exec.RealCmdRunner{})
+	case ProductMicroK8s:
+		admin = newMicrok8sAdmin(c.iostreams, exec.RealCmdRunner{})
+	case ProductRancherDesktop:
+		admin = newRancherDesktopAdmin(newRancherDesktopClient(exec.RealCmdRunner{}))
 	}
 
 	if product == "" {
 		return nil, fmt.Errorf("you must specify a 'product' field in your cluster config")
 	}
+	if admin == nil {
+		// Before we give up, check if there's a plugin for this product.
+		path, ok := findPlugin(product)
+		if ok {
+			admin = newPluginAdmin(newPluginClient(path, exec.RealCmdRunner{}, c.iostreams.ErrOut))
+		}
+	}
 	if admin == nil {
 		return nil, fmt.Errorf("ctlptl doesn't know how to set up clusters for product: %s", product)
 	}
-	c.admins[product] = admin
 	return admin, nil
 }
 
@@ -321,6 +453,29 @@ func (c *Controller) client(name string) (kubernetes.Interface, error) {
 	return client, nil
 }
 
+func (c *Controller) dynamicClient(name string) (dynamic.Interface, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.dynamicClients[name]
+	if ok {
+		return client, nil
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(
+		c.config, &clientcmd.ConfigOverrides{CurrentContext: name}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err = c.dynamicClientLoader(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	c.dynamicClients[name] = client
+	return client, nil
+}
+
 func (c *Controller) populateCreationTimestamp(ctx context.Context, cluster *api.Cluster, client kubernetes.Interface) error {
 	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -336,50 +491,58 @@ func (c *Controller) populateCreationTimestamp(ctx context.Context, cluster *api
 	}
 
 	cluster.Status.CreationTimestamp = minTime
+	cluster.Status.Nodes = len(nodes.Items)
 
 	return nil
 }
 
-func (c *Controller) populateLocalRegistryHosting(ctx context.Context, cluster *api.Cluster, client kubernetes.Interface) error {
+// Reads the registry that the cluster advertises, and returns
+// the registry that's listening on its port, if any.
+func (c *Controller) populateLocalRegistryHosting(ctx context.Context, cluster *api.Cluster, client kubernetes.Interface) (*api.Registry, error) {
 	hosting, err := localregistry.Discover(ctx, client.CoreV1())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	cluster.Status.LocalRegistryHosting = &hosting
 
 	if hosting.Host == "" {
-		return nil
+		return nil, nil
 	}
 
 	// Let's try to find the registry corresponding to this cluster.
+	// Registries that aren't on localhost aren't ours to look for.
 	var port int
 	_, err = fmt.Sscanf(hosting.Host, "localhost:%d", &port)
 	if err != nil || port == 0 {
-		return err
+		return nil, nil
 	}
 
 	registryCtl, err := c.registryController(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	registryList, err := registryCtl.List(ctx, registry.ListOptions{FieldSelector: fmt.Sprintf("port=%d", port)})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(registryList.Items) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	cluster.Registry = registryList.Items[0].Name
 
-	return nil
+	return &registryList.Items[0], nil
 }
 
-func (c *Controller) populateMachineStatus(ctx context.Context, cluster *api.Cluster) error {
-	machine, err := c.machine(ctx, cluster.Name, Product(cluster.Product))
+// Populates the machine status of the cluster.
+//
+// The machine is looked up by key, because populateClusterSpec
+// may be filling in the rest of the cluster concurrently.
+func (c *Controller) populateMachineStatus(ctx context.Context, cluster *api.Cluster, key *api.Cluster) error {
+	machine, err := c.machine(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -388,15 +551,29 @@ func (c *Controller) populateMachineStatus(ctx context.Context, cluster *api.Clu
 	if err != nil {
 		return err
 	}
+	memory, err := machine.Memory(ctx)
+	if err != nil {
+		return err
+	}
+	disk, err := machine.Disk(ctx)
+	if err != nil {
+		return err
+	}
 	cluster.Status.CPUs = cpu
+	cluster.Status.Memory = memory
+	cluster.Status.Disk = disk
 	return nil
 }
 
+// Reads the spec that ctlptl recorded on the cluster.
+//
+// Returns errClusterSpecNotFound if there isn't one, e.g.,
+// because ctlptl didn't create the cluster.
 func (c *Controller) populateClusterSpec(ctx context.Context, cluster *api.Cluster, client kubernetes.Interface) error {
 	cMap, err := client.CoreV1().ConfigMaps("kube-public").Get(ctx, clusterSpecConfigMap, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
-			return nil
+			return errClusterSpecNotFound
 		}
 		return err
 	}
@@ -407,9 +584,33 @@ func (c *Controller) populateClusterSpec(ctx context.Context, cluster *api.Clust
 		return err
 	}
 
+	// We can't detect plugin products from the kubeconfig,
+	// so trust the product that created the cluster.
+	if spec.Product != "" && !hasBuiltinAdmin(Product(cluster.Product)) && isPluginProduct(Product(spec.Product)) {
+		cluster.Product = spec.Product
+	}
+
+	cluster.Labels = spec.Labels
+	cluster.Annotations = spec.Annotations
 	cluster.KubernetesVersion = spec.KubernetesVersion
+	cluster.NodeImage = spec.NodeImage
 	cluster.MinCPUs = spec.MinCPUs
+	cluster.MinMemory = spec.MinMemory
+	cluster.MinDisk = spec.MinDisk
+	cluster.TTL = spec.TTL
+	cluster.Nodes = spec.Nodes
+	cluster.PortMappings = spec.PortMappings
+	cluster.Ingress = spec.Ingress
+	cluster.Addons = spec.Addons
+	cluster.PreloadImages = spec.PreloadImages
+	cluster.RegistryMirrors = spec.RegistryMirrors
+	cluster.FeatureGates = spec.FeatureGates
+	cluster.APIServer = spec.APIServer
+	cluster.Kubelet = spec.Kubelet
+	cluster.Engine = spec.Engine
 	cluster.KindV1Alpha4Cluster = spec.KindV1Alpha4Cluster
+	cluster.K3DV1Alpha3Simple = spec.K3DV1Alpha3Simple
+	cluster.Minikube = spec.Minikube
 	return nil
 }
 
@@ -462,11 +663,17 @@ func (c *Controller) populateCluster(ctx context.Context, cluster *api.Cluster)
 
 	client, err := c.client(cluster.Name)
 	if err != nil {
-		klog.V(4).Infof("WARNING: creating cluster %s client: %v\n", name, err)
+		cluster.Status.Conditions = appendConditions(nil,
+			conditionFalse(api.ClusterConditionAPIServerReachable, "ClientConfigInvalid", err))
 		return
 	}
+	machineKey := &api.Cluster{Name: name, Product: cluster.Product}
 	wg := sync.WaitGroup{}
+	machineCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var apiServerCond, registryCond, specCond, machineCond, nodesCond, ingressCond *api.Condition
 
 	wg.Add(1)
 	go func() {
@@ -476,10 +683,12 @@ func (c *Controller) populateCluster(ctx context.Context, cluster *api.Cluster)
 		if err != nil {
 			// Cancel all other fetching.
 			cancel()
+			apiServerCond = conditionFalse(api.ClusterConditionAPIServerReachable, "APIServerUnreachable", err)
 			return
 		}
 
 		cluster.Status.KubernetesVersion = v.GitVersion
+		apiServerCond = conditionTrue(api.ClusterConditionAPIServerReachable, "Reachable")
 	}()
 
 	wg.Add(1)
@@ -488,43 +697,103 @@ func (c *Controller) populateCluster(ctx context.Context, cluster *api.Cluster)
 
 		err := c.populateCreationTimestamp(ctx, cluster, client)
 		if err != nil {
-			klog.V(4).Infof("WARNING: reading cluster %s creation time: %v\n", name, err)
+			nodesCond = conditionFalse(api.ClusterConditionNodesReadable, "NodesUnreadable", err)
+			return
 		}
+		nodesCond = conditionTrue(api.ClusterConditionNodesReadable, "Read")
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		err := c.populateLocalRegistryHosting(ctx, cluster, client)
-		if err != nil {
-			klog.V(4).Infof("WARNING: reading cluster %s registry: %v\n", name, err)
+		reg, err := c.populateLocalRegistryHosting(ctx, cluster, client)
+		hosting := ""
+		if cluster.Status.LocalRegistryHosting != nil {
+			hosting = cluster.Status.LocalRegistryHosting.Host
 		}
+		registryCond = registryConnectedCondition(hosting, reg, err)
 	}()
 
+	// The machine doesn't depend on the API server,
+	// so don't stop asking if the health check fails.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := c.populateMachineStatus(ctx, cluster)
-		if err != nil {
-			klog.V(4).Infof("WARNING: reading cluster %s machine: %v\n", name, err)
-		}
+		machineCond = c.populateMachineCondition(machineCtx, cluster, machineKey)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		err := c.populateClusterSpec(ctx, cluster, client)
+		switch {
+		case err == errClusterSpecNotFound:
+			specCond = conditionUnknown(api.ClusterConditionSpecRecorded, "NotRecorded",
+				"the cluster wasn't created by ctlptl, or its spec isn't readable")
+		case err != nil:
+			specCond = conditionFalse(api.ClusterConditionSpecRecorded, "SpecUnreadable", err)
+		default:
+			specCond = conditionTrue(api.ClusterConditionSpecRecorded, "Recorded")
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dclient, err := c.dynamicClient(name)
 		if err != nil {
-			klog.V(4).Infof("WARNING: reading cluster %s spec: %v\n", name, err)
+			ingressCond = conditionFalse(api.ClusterConditionIngressReadable, "ClientConfigInvalid", err)
+			return
+		}
+		err = c.populateIngressStatus(ctx, cluster, dclient)
+		if err != nil {
+			ingressCond = conditionFalse(api.ClusterConditionIngressReadable, "IngressUnreadable", err)
+			return
 		}
+		ingressCond = conditionTrue(api.ClusterConditionIngressReadable, "Read")
 	}()
 
 	wg.Wait()
 
+	// If the spec told us that a plugin manages this cluster,
+	// ask the plugin about the machine.
+	if cluster.Product != machineKey.Product {
+		machineCond = c.populateMachineCondition(machineCtx, cluster, &api.Cluster{Name: name, Product: cluster.Product})
+	}
+
+	// If we can't reach the API server, we can't know anything
+	// we'd read from it.
+	if apiServerCond != nil && apiServerCond.Status != metav1.ConditionTrue {
+		msg := "the API server is unreachable"
+		if registryCond != nil {
+			registryCond = conditionUnknown(api.ClusterConditionRegistryConnected, "APIServerUnreachable", msg)
+		}
+		specCond = conditionUnknown(api.ClusterConditionSpecRecorded, "APIServerUnreachable", msg)
+		nodesCond = conditionUnknown(api.ClusterConditionNodesReadable, "APIServerUnreachable", msg)
+		ingressCond = conditionUnknown(api.ClusterConditionIngressReadable, "APIServerUnreachable", msg)
+	}
+
+	cluster.Status.Conditions = appendConditions(nil,
+		machineCond, apiServerCond, nodesCond, registryCond, specCond, ingressCond)
 	cluster.Status.Current = c.configCurrent() == cluster.Name
 }
 
+// Reads the machine status, and reports whether we could reach the machine.
+//
+// Returns nil for products where ctlptl doesn't know how to find the machine.
+func (c *Controller) populateMachineCondition(ctx context.Context, cluster *api.Cluster, key *api.Cluster) *api.Condition {
+	err := c.populateMachineStatus(ctx, cluster, key)
+	product := Product(key.Product)
+	if !hasBuiltinAdmin(product) && !isPluginProduct(product) {
+		return nil
+	}
+	if err != nil {
+		return conditionFalse(api.ClusterConditionMachineReachable, "MachineUnreachable", err)
+	}
+	return conditionTrue(api.ClusterConditionMachineReachable, "Reachable")
+}
+
 func FillDefaults(cluster *api.Cluster) {
 	// If the name is in the Kind config, but not in the main config,
 	// lift it up to the main config.
@@ -532,6 +801,11 @@ func FillDefaults(cluster *api.Cluster) {
 		cluster.Name = fmt.Sprintf("kind-%s", cluster.KindV1Alpha4Cluster.Name)
 	}
 
+	// Same for the K3D config.
+	if cluster.K3DV1Alpha3Simple != nil && cluster.Name == "" && cluster.K3DV1Alpha3Simple.Name != "" {
+		cluster.Name = fmt.Sprintf("k3d-%s", cluster.K3DV1Alpha3Simple.Name)
+	}
+
 	// Create a default name if one isn't in the YAML.
 	// The default name is determined by the underlying product.
 	if cluster.Name == "" {
@@ -542,15 +816,32 @@ func FillDefaults(cluster *api.Cluster) {
 	if cluster.KindV1Alpha4Cluster != nil {
 		cluster.KindV1Alpha4Cluster.Name = strings.TrimPrefix(cluster.Name, "kind-")
 	}
+
+	// Override the K3D config if necessary.
+	if cluster.K3DV1Alpha3Simple != nil {
+		cluster.K3DV1Alpha3Simple.Name = strings.TrimPrefix(cluster.Name, "k3d-")
+	}
 }
 
 // TODO(nick): Add more registry-supporting clusters.
+//
+// Plugins decide for themselves.
 func supportsRegistry(product Product) bool {
-	return product == ProductKIND || product == ProductMinikube
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D ||
+		product == ProductMicroK8s || isPluginProduct(product)
+}
+
+// Only kind and minikube know how to run on podman.
+func supportsEngine(product Product, engine docker.Engine) bool {
+	if engine == docker.EngineDocker {
+		return true
+	}
+	return product == ProductKIND || product == ProductMinikube || isPluginProduct(product)
 }
 
 func supportsKubernetesVersion(product Product, version string) bool {
-	return product == ProductKIND || product == ProductMinikube
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D ||
+		product == ProductRancherDesktop || isPluginProduct(product)
 }
 
 func (c *Controller) canReconcileK8sVersion(ctx context.Context, desired, existing *api.Cluster) bool {
@@ -575,42 +866,220 @@ func (c *Controller) canReconcileK8sVersion(ctx context.Context, desired, existi
 		return dv.Major == ev.Major && dv.Minor == ev.Minor
 	}
 
+	// On K3D, the reported version has a k3s build tag (e.g., v1.21.2+k3s1),
+	// so only compare the Kubernetes part.
+	if Product(desired.Product) == ProductK3D {
+		return k3sVersionMatches(desired.KubernetesVersion, existing.Status.KubernetesVersion)
+	}
+
+	// Rancher Desktop can switch Kubernetes versions in place,
+	// so the machine handles it on restart.
+	if Product(desired.Product) == ProductRancherDesktop {
+		return true
+	}
+
 	return false
 }
 
-func (c *Controller) deleteIfIrreconcilable(ctx context.Context, desired, existing *api.Cluster) error {
+// Products that can upgrade Kubernetes on an existing cluster.
+//
+// k3d can't swap the images of running nodes, so it needs a new cluster.
+func supportsUpgrade(product Product) bool {
+	return product == ProductMinikube
+}
+
+// Whether Apply should upgrade the existing cluster to the desired
+// Kubernetes version in place.
+//
+// Downgrades always need a new cluster.
+func (c *Controller) needsK8sUpgrade(ctx context.Context, desired, existing *api.Cluster) bool {
+	if !supportsUpgrade(Product(desired.Product)) || c.canReconcileK8sVersion(ctx, desired, existing) {
+		return false
+	}
+
+	dv, err := semver.ParseTolerant(desired.KubernetesVersion)
+	if err != nil {
+		return false
+	}
+	ev, err := semver.ParseTolerant(existing.Status.KubernetesVersion)
+	if err != nil {
+		return false
+	}
+	return dv.GT(ev)
+}
+
+// Rancher Desktop changes the Kubernetes version by restarting the machine.
+func needsK8sVersionRestart(desired, existing *api.Cluster) bool {
+	if Product(desired.Product) != ProductRancherDesktop || desired.KubernetesVersion == "" {
+		return false
+	}
+	return !k3sVersionMatches(desired.KubernetesVersion, existing.Status.KubernetesVersion)
+}
+
+// k3s-based clusters report versions with a k3s build tag (e.g., v1.21.2+k3s1),
+// so only compare the Kubernetes part.
+func k3sVersionMatches(desired, existing string) bool {
+	dv, err := semver.ParseTolerant(desired)
+	if err != nil {
+		return false
+	}
+	ev, err := semver.ParseTolerant(existing)
+	if err != nil {
+		return false
+	}
+	return dv.Major == ev.Major && dv.Minor == ev.Minor && dv.Patch == ev.Patch
+}
+
+// The ways that the existing cluster differs from the desired cluster
+// that we can only fix by deleting the cluster and creating a new one.
+//
+// Returns nothing if the existing cluster can be reconciled.
+func (c *Controller) irreconcilableDifferences(ctx context.Context, desired, existing *api.Cluster) []Difference {
 	if existing.Name == "" {
-		// Nothing to delete
+		// Nothing to recreate
 		return nil
 	}
 
-	needsDelete := false
 	if existing.Product != "" && existing.Product != desired.Product {
-		_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Deleting cluster %s to change admin from %s to %s\n",
-			desired.Name, existing.Product, desired.Product)
-		needsDelete = true
-	} else if desired.Registry != "" && desired.Registry != existing.Registry {
-		// TODO(nick): Ideally, we should be able to patch a cluster
-		// with a registry, but it gets a little hairy.
-		_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Deleting cluster %s to initialize with registry %s\n",
-			desired.Name, desired.Registry)
-		needsDelete = true
-	} else if !c.canReconcileK8sVersion(ctx, desired, existing) {
-		_, _ = fmt.Fprintf(c.iostreams.ErrOut,
-			"Deleting cluster %s because desired Kubernetes version (%s) does not match current (%s)\n",
-			desired.Name, desired.KubernetesVersion, existing.Status.KubernetesVersion)
-		needsDelete = true
-	} else if desired.KindV1Alpha4Cluster != nil && !cmp.Equal(existing.KindV1Alpha4Cluster, desired.KindV1Alpha4Cluster) {
-		_, _ = fmt.Fprintf(c.iostreams.ErrOut,
-			"Deleting cluster %s because desired Kind config does not match current.\nCluster config diff: %s\n",
-			desired.Name, cmp.Diff(existing.KindV1Alpha4Cluster, desired.KindV1Alpha4Cluster))
-		needsDelete = true
-	}
-
-	if !needsDelete {
+		// Nothing else is comparable across products.
+		return []Difference{{
+			Field: "product",
+			Message: fmt.Sprintf("desired product (%s) does not match current (%s)",
+				desired.Product, existing.Product),
+		}}
+	}
+
+	var diffs []Difference
+	if desired.Engine != "" && existing.Engine != "" && desired.Engine != existing.Engine {
+		diffs = append(diffs, Difference{
+			Field: "engine",
+			Message: fmt.Sprintf("desired container engine (%s) does not match current (%s)",
+				desired.Engine, existing.Engine),
+		})
+	}
+	if registryChanged(desired, existing) && !supportsRegistryAttach(Product(desired.Product)) {
+		current := existing.Registry
+		if current == "" {
+			current = "none"
+		}
+		diffs = append(diffs, Difference{
+			Field:   "registry",
+			Message: fmt.Sprintf("desired registry (%s) does not match current (%s)", desired.Registry, current),
+		})
+	}
+	if !c.canReconcileK8sVersion(ctx, desired, existing) && !c.needsK8sUpgrade(ctx, desired, existing) {
+		diffs = append(diffs, Difference{
+			Field: "kubernetesVersion",
+			Message: fmt.Sprintf("desired Kubernetes version (%s) does not match current (%s)",
+				desired.KubernetesVersion, existing.Status.KubernetesVersion),
+		})
+	}
+	if desired.NodeImage != "" && desired.NodeImage != existing.NodeImage {
+		diffs = append(diffs, Difference{
+			Field: "nodeImage",
+			Message: fmt.Sprintf("desired node image (%s) does not match current (%s)",
+				desired.NodeImage, existing.NodeImage),
+		})
+	}
+	if !nodesMatch(desired, existing) {
+		current := nodesString(existing.Nodes)
+		if existing.Nodes == nil {
+			current = fmt.Sprintf("%d nodes", existing.Status.Nodes)
+		}
+		diffs = append(diffs, Difference{
+			Field: "nodes",
+			Message: fmt.Sprintf("desired nodes (%s) do not match current (%s)",
+				nodesString(desired.Nodes), current),
+		})
+	}
+	if len(desired.PortMappings) > 0 && !cmp.Equal(existing.PortMappings, desired.PortMappings) {
+		diffs = append(diffs, Difference{
+			Field:   "portMappings",
+			Message: "desired port mappings do not match current",
+			Diff:    cmp.Diff(existing.PortMappings, desired.PortMappings),
+		})
+	}
+	if !extraArgsMatch(desired, existing) {
+		diffs = append(diffs, Difference{
+			Field:   "featureGates",
+			Message: "desired feature gates or extra args do not match current",
+			Diff:    cmp.Diff(extraArgsConfig(existing), extraArgsConfig(desired)),
+		})
+	}
+	if len(desired.RegistryMirrors) > 0 && !cmp.Equal(existing.RegistryMirrors, desired.RegistryMirrors) {
+		diffs = append(diffs, Difference{
+			Field:   "registryMirrors",
+			Message: "desired registry mirrors do not match current",
+			Diff:    cmp.Diff(existing.RegistryMirrors, desired.RegistryMirrors),
+		})
+	}
+	// Compare against the controller that ctlptl installed, not the
+	// IngressClasses, because k3d clusters come with their own traefik.
+	if desired.Ingress != nil && existing.Ingress != nil &&
+		existing.Ingress.Controller != desired.Ingress.Controller {
+		diffs = append(diffs, Difference{
+			Field: "ingress",
+			Message: fmt.Sprintf("desired ingress controller (%s) does not match current (%s)",
+				desired.Ingress.Controller, existing.Ingress.Controller),
+		})
+	}
+	if desired.KindV1Alpha4Cluster != nil && !cmp.Equal(existing.KindV1Alpha4Cluster, desired.KindV1Alpha4Cluster) {
+		diffs = append(diffs, Difference{
+			Field:   "kindV1Alpha4Cluster",
+			Message: "desired Kind config does not match current",
+			Diff:    cmp.Diff(existing.KindV1Alpha4Cluster, desired.KindV1Alpha4Cluster),
+		})
+	}
+	if desired.K3DV1Alpha3Simple != nil && !cmp.Equal(existing.K3DV1Alpha3Simple, desired.K3DV1Alpha3Simple) {
+		diffs = append(diffs, Difference{
+			Field:   "k3dV1Alpha3Simple",
+			Message: "desired K3D config does not match current",
+			Diff:    cmp.Diff(existing.K3DV1Alpha3Simple, desired.K3DV1Alpha3Simple),
+		})
+	}
+	if desired.Minikube != nil &&
+		!cmp.Equal(minikubeRecreateConfig(existing.Minikube), minikubeRecreateConfig(desired.Minikube)) {
+		diffs = append(diffs, Difference{
+			Field:   "minikube",
+			Message: "desired Minikube config does not match current",
+			Diff:    cmp.Diff(existing.Minikube, desired.Minikube),
+		})
+	}
+	return diffs
+}
+
+// Deletes the existing cluster if it can't be reconciled with the desired cluster.
+//
+// Deleting a cluster loses everything in it, so we only do it if the
+// user confirms. Otherwise, returns an IrreconcilableError.
+func (c *Controller) deleteIfIrreconcilable(ctx context.Context, desired, existing *api.Cluster) error {
+	diffs := c.irreconcilableDifferences(ctx, desired, existing)
+	if len(diffs) == 0 {
 		return nil
 	}
 
+	irreconcilable := &IrreconcilableError{Name: desired.Name, Differences: diffs}
+	confirmed := false
+	if c.confirmRecreate != nil {
+		var err error
+		confirmed, err = c.confirmRecreate(ctx, irreconcilable)
+		if err != nil {
+			return err
+		}
+	}
+	if !confirmed {
+		return irreconcilable
+	}
+
+	for _, diff := range diffs {
+		if diff.Diff != "" {
+			_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Deleting cluster %s because %s.\nCluster config diff: %s\n",
+				desired.Name, diff.Message, diff.Diff)
+		} else {
+			_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Deleting cluster %s because %s\n", desired.Name, diff.Message)
+		}
+	}
+
 	err := c.Delete(ctx, desired.Name)
 	if err != nil {
 		return err
@@ -632,27 +1101,156 @@ func (c *Controller) ensureRegistryExists(ctx context.Context, name string) (*ap
 	})
 }
 
-// Compare the desired cluster against the existing cluster, and reconcile
-// the two to match.
-func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Cluster, error) {
+// Labels, annotations, or a TTL in the desired cluster that
+// don't match the existing cluster.
+func metadataChanged(desired, existing *api.Cluster) bool {
+	return (desired.Labels != nil && !cmp.Equal(desired.Labels, existing.Labels, cmpopts.EquateEmpty())) ||
+		(desired.Annotations != nil && !cmp.Equal(desired.Annotations, existing.Annotations, cmpopts.EquateEmpty())) ||
+		(desired.TTL != "" && desired.TTL != existing.TTL)
+}
+
+// Copies the labels, annotations, and TTL that the desired cluster sets
+// onto the existing cluster.
+func updateMetadata(desired, existing *api.Cluster) {
+	if desired.Labels != nil {
+		existing.Labels = desired.Labels
+	}
+	if desired.Annotations != nil {
+		existing.Annotations = desired.Annotations
+	}
+	if desired.TTL != "" {
+		existing.TTL = desired.TTL
+	}
+}
+
+// Explains why the machine needs to restart to give the existing cluster
+// the desired resources.
+//
+// Returns an empty reason if the machine has what the cluster needs.
+func restartReason(desired, existing *api.Cluster, minMemory, minDisk int64) string {
+	existingStatus := existing.Status
+	if existingStatus.CPUs < desired.MinCPUs {
+		return fmt.Sprintf("for CPUs (want %d, have %d)", desired.MinCPUs, existingStatus.CPUs)
+	}
+	if !hasMinMemory(existingStatus.Memory, minMemory) {
+		return fmt.Sprintf("for memory (want %s, have %s)",
+			bytesString(minMemory), bytesString(existingStatus.Memory))
+	}
+	if existingStatus.Disk < minDisk {
+		return fmt.Sprintf("for disk (want %s, have %s)",
+			bytesString(minDisk), bytesString(existingStatus.Disk))
+	}
+	if needsK8sVersionRestart(desired, existing) {
+		return fmt.Sprintf("to change Kubernetes version to %s", desired.KubernetesVersion)
+	}
+	if needsMinikubeResize(desired, existing) {
+		return fmt.Sprintf("to change memory to %s", desired.Minikube.Memory)
+	}
+	return ""
+}
+
+// Checks the desired cluster for config errors that we can catch
+// before we touch anything.
+func validateCluster(desired *api.Cluster) error {
 	if desired.Product == "" {
-		return nil, fmt.Errorf("product field must be non-empty")
+		return fmt.Errorf("product field must be non-empty")
 	}
-	if desired.Registry != "" && !supportsRegistry(Product(desired.Product)) {
-		return nil, fmt.Errorf("product %s does not support a registry", desired.Product)
+	if desiredRegistryName(desired) != "" && !supportsRegistry(Product(desired.Product)) {
+		return fmt.Errorf("product %s does not support a registry", desired.Product)
 	}
 	if desired.KubernetesVersion != "" && !supportsKubernetesVersion(Product(desired.Product), desired.KubernetesVersion) {
-		return nil, fmt.Errorf("product %s does not support a custom Kubernetes version", desired.Product)
+		return fmt.Errorf("product %s does not support a custom Kubernetes version", desired.Product)
+	}
+	if desired.NodeImage != "" && Product(desired.Product) != ProductKIND {
+		return fmt.Errorf("nodeImage may only be set on clusters with product: kind. Actual product: %s", desired.Product)
 	}
 	if desired.KindV1Alpha4Cluster != nil && Product(desired.Product) != ProductKIND {
-		return nil, fmt.Errorf("kind config may only be set on clusters with product: kind. Actual product: %s", desired.Product)
+		return fmt.Errorf("kind config may only be set on clusters with product: kind. Actual product: %s", desired.Product)
+	}
+	if desired.K3DV1Alpha3Simple != nil && Product(desired.Product) != ProductK3D {
+		return fmt.Errorf("k3d config may only be set on clusters with product: k3d. Actual product: %s", desired.Product)
+	}
+	if desired.Minikube != nil && Product(desired.Product) != ProductMinikube {
+		return fmt.Errorf("minikube config may only be set on clusters with product: minikube. Actual product: %s", desired.Product)
+	}
+	err := api.ValidateMetadata(desired.Labels, desired.Annotations)
+	if err != nil {
+		return err
+	}
+	err = validateK3dConfig(desired)
+	if err != nil {
+		return err
+	}
+	err = validateMinikubeRegistry(desired)
+	if err != nil {
+		return err
+	}
+	err = validateNodes(desired)
+	if err != nil {
+		return err
+	}
+	err = validatePortMappings(desired)
+	if err != nil {
+		return err
+	}
+	err = validateIngress(desired)
+	if err != nil {
+		return err
+	}
+	err = validateAddons(desired)
+	if err != nil {
+		return err
+	}
+	err = validatePreloadImages(desired)
+	if err != nil {
+		return err
+	}
+	err = validateRegistryMirrors(desired)
+	if err != nil {
+		return err
+	}
+	err = validateExtraArgs(desired)
+	if err != nil {
+		return err
+	}
+	return validateTTL(desired)
+}
+
+// Compare the desired cluster against the existing cluster, and reconcile
+// the two to match.
+func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Cluster, error) {
+	err := validateCluster(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	minMemory, err := minMemoryBytes(desired)
+	if err != nil {
+		return nil, err
+	}
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return nil, err
 	}
 
 	FillDefaults(desired)
 
+	err = c.useEngine(desired.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerClient, err := c.getDockerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsEngine(Product(desired.Product), dockerClient.Engine()) {
+		return nil, fmt.Errorf("product %s does not support container engine %s", desired.Product, dockerClient.Engine())
+	}
+
 	// Fetch the machine driver for this product and cluster name,
 	// and use it to apply the constraints to the underlying VM.
-	machine, err := c.machine(ctx, desired.Name, Product(desired.Product))
+	machine, err := c.machine(ctx, desired)
 	if err != nil {
 		return nil, err
 	}
@@ -673,9 +1271,8 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Clus
 		existingCluster = &api.Cluster{}
 	}
 
-	// If we can't reconcile the two clusters, delete it now.
-	// TODO(nick): Check for a --force flag, and only delete the cluster
-	// if there's a --force.
+	// If we can't reconcile the two clusters, delete it now,
+	// but only if the user confirms.
 	err = c.deleteIfIrreconcilable(ctx, desired, existingCluster)
 	if err != nil {
 		return nil, err
@@ -690,7 +1287,7 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Clus
 
 	existingStatus := existingCluster.Status
 	needsRestart := existingStatus.CreationTimestamp.Time.IsZero() ||
-		existingStatus.CPUs < desired.MinCPUs
+		restartReason(desired, existingCluster, minMemory, minDisk) != ""
 	if needsRestart {
 		err := machine.Restart(ctx, desired, existingCluster)
 		if err != nil {
@@ -699,19 +1296,40 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Clus
 	}
 
 	var reg *api.Registry
-	if desired.Registry != "" {
-		reg, err = c.ensureRegistryExists(ctx, desired.Registry)
+	if name := desiredRegistryName(desired); name != "" {
+		reg, err = c.ensureRegistryExists(ctx, name)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	mirrors, err := c.ensureRegistryMirrorsExist(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+
 	// Configure the cluster to match what we want.
 	needsCreate := existingStatus.CreationTimestamp.Time.IsZero() ||
 		desired.Name != existingCluster.Name ||
 		desired.Product != existingCluster.Product
 	if needsCreate {
-		err := admin.Create(ctx, desired, reg)
+		// Fail early if something on the host already has the ports we need.
+		if len(desired.PortMappings) > 0 && dockerClient.IsLocalHost() {
+			err := checkHostPortsAvailable(desired.PortMappings)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(mirrors) > 0 {
+			mirrorAdmin, ok := admin.(registryMirrorAdmin)
+			if !ok {
+				return nil, fmt.Errorf("product %s does not support registry mirrors", desired.Product)
+			}
+			err = mirrorAdmin.CreateWithMirrors(ctx, desired, reg, mirrors)
+		} else {
+			err = admin.Create(ctx, desired, reg)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -746,22 +1364,155 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Cluster) (*api.Clus
 			return nil, err
 		}
 
-		err = c.writeClusterSpec(ctx, desired)
+		// The ingress controller and addons are recorded in the spec
+		// as they're installed.
+		spec := desired.DeepCopy()
+		spec.Ingress = nil
+		spec.Addons = nil
+		err = c.writeClusterSpec(ctx, spec)
 		if err != nil {
 			return nil, errors.Wrap(err, "configuring cluster")
 		}
 
-		if desired.Registry != "" {
+		if reg != nil {
 			err = c.createRegistryHosting(ctx, admin, desired, reg)
 			if err != nil {
 				return nil, errors.Wrap(err, "configuring cluster registry")
 			}
 		}
+
+		if len(desired.PreloadImages) > 0 {
+			err = c.preloadImages(ctx, admin, desired, reg)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// On some products, we can upgrade Kubernetes without recreating the cluster.
+	upgraded := false
+	if !needsCreate && c.needsK8sUpgrade(ctx, desired, existingCluster) {
+		err = c.upgradeCluster(ctx, admin, desired, existingCluster)
+		if err != nil {
+			return nil, errors.Wrap(err, "upgrading cluster")
+		}
+		upgraded = true
+	}
+
+	if !needsCreate && (needsRestart || upgraded) {
+		err = c.restoreRegistryConfig(ctx, admin, desired, existingCluster, reg, mirrors)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring cluster registry")
+		}
+	}
+
+	// On some products, we can swap the registry without recreating the cluster.
+	if !needsCreate && registryChanged(desired, existingCluster) {
+		err = c.reattachRegistry(ctx, admin, desired, existingCluster, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring cluster registry")
+		}
+	}
+
+	// Labels, annotations, and the TTL can change without recreating the cluster.
+	if !needsCreate && metadataChanged(desired, existingCluster) {
+		updateMetadata(desired, existingCluster)
+		err = c.writeClusterSpec(ctx, existingCluster)
+		if err != nil {
+			return nil, errors.Wrap(err, "updating cluster metadata")
+		}
+	}
+
+	// Ingress controllers can be added to an existing cluster.
+	if desired.Ingress != nil && (needsCreate || existingCluster.Ingress == nil) {
+		err = c.installIngress(ctx, desired)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring cluster ingress")
+		}
+
+		spec := existingCluster
+		if needsCreate {
+			spec = desired.DeepCopy()
+			spec.Addons = nil
+		}
+		spec.Ingress = desired.Ingress.DeepCopy()
+		err = c.writeClusterSpec(ctx, spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "recording cluster ingress")
+		}
+	}
+
+	if len(desired.Addons) > 0 {
+		spec := existingCluster.DeepCopy()
+		if needsCreate {
+			spec = desired.DeepCopy()
+			spec.Addons = nil
+		}
+
+		addons := newAddons(desired.Addons, spec.Addons)
+		if len(addons) > 0 {
+			err = c.installAddons(ctx, spec, addons)
+			if err != nil {
+				return nil, errors.Wrap(err, "configuring cluster addons")
+			}
+		}
 	}
 
 	return c.Get(ctx, desired.Name)
 }
 
+// Configures the registry and mirrors again on an existing cluster that
+// restarted, for products that reset the node config when they start.
+func (c *Controller) restoreRegistryConfig(ctx context.Context, admin Admin, desired, existing *api.Cluster, reg *api.Registry, mirrors []registryMirror) error {
+	restorer, ok := admin.(registryConfigRestorer)
+	if !ok {
+		return nil
+	}
+
+	// A new registry gets attached after this. If the config doesn't name a
+	// registry or mirrors, the cluster keeps the ones it was created with.
+	var err error
+	if registryChanged(desired, existing) {
+		reg = nil
+	} else if reg == nil && existing.Registry != "" {
+		reg, err = c.ensureRegistryExists(ctx, existing.Registry)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(mirrors) == 0 && len(existing.RegistryMirrors) > 0 {
+		mirrors, err = c.ensureRegistryMirrorsExist(ctx, existing)
+		if err != nil {
+			return err
+		}
+	}
+
+	if reg == nil && len(mirrors) == 0 {
+		return nil
+	}
+	return restorer.RestoreRegistryConfig(ctx, desired, reg, mirrors)
+}
+
+// Upgrades the existing cluster to the desired Kubernetes version,
+// and records the new version in the cluster spec.
+func (c *Controller) upgradeCluster(ctx context.Context, admin Admin, desired, existing *api.Cluster) error {
+	upgrader, ok := admin.(upgrader)
+	if !ok {
+		return fmt.Errorf("product %s can't upgrade Kubernetes on an existing cluster", desired.Product)
+	}
+
+	_, _ = fmt.Fprintf(c.iostreams.ErrOut, " ⏫ Upgrading cluster %s from Kubernetes %s to %s\n",
+		desired.Name, existing.Status.KubernetesVersion, desired.KubernetesVersion)
+	err := upgrader.Upgrade(ctx, desired, existing)
+	if err != nil {
+		return err
+	}
+
+	existing.KubernetesVersion = desired.KubernetesVersion
+	return c.writeClusterSpec(ctx, existing)
+}
+
 // Writes the cluster spec to the cluster itself, so
 // we can read it later to determine how the cluster was initialized.
 func (c *Controller) writeClusterSpec(ctx context.Context, cluster *api.Cluster) error {
@@ -793,7 +1544,7 @@ func (c *Controller) writeClusterSpec(ctx context.Context, cluster *api.Cluster)
 }
 
 // Create a configmap on the cluster, so that other tools know that a registry
-// has been configured.
+// has been configured. Replaces the configmap for any previous registry.
 func (c *Controller) createRegistryHosting(ctx context.Context, admin Admin, cluster *api.Cluster, reg *api.Registry) error {
 	hosting, err := admin.LocalRegistryHosting(ctx, cluster, reg)
 	if err != nil {
@@ -813,13 +1564,18 @@ func (c *Controller) createRegistryHosting(ctx context.Context, admin Admin, clu
 		return err
 	}
 
-	_, err = client.CoreV1().ConfigMaps("kube-public").Create(ctx, &corev1.ConfigMap{
+	configMaps := client.CoreV1().ConfigMaps("kube-public")
+	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "local-registry-hosting",
+			Name:      localRegistryHostingConfigMap,
 			Namespace: "kube-public",
 		},
 		Data: map[string]string{"localRegistryHosting.v1": string(data)},
-	}, metav1.CreateOptions{})
+	}
+	_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	}
 	if err != nil {
 		return err
 	}
@@ -836,7 +1592,7 @@ func (c *Controller) Delete(ctx context.Context, name string) error {
 		return err
 	}
 
-	admin, err := c.admin(ctx, Product(existing.Product))
+	admin, err := c.adminForCluster(ctx, existing)
 	if err != nil {
 		return err
 	}
@@ -869,6 +1625,7 @@ func (c *Controller) reloadConfigs() error {
 	defer c.mu.Unlock()
 	c.config = config
 	c.clients = make(map[string]kubernetes.Interface)
+	c.dynamicClients = make(map[string]dynamic.Interface)
 	return nil
 }
 
@@ -901,6 +1658,10 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Cluste
 	if err != nil {
 		return nil, err
 	}
+	labelSelector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
 
 	config := c.configCopy()
 	names := make([]string, 0, len(c.config.Contexts))
@@ -927,6 +1688,11 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Cluste
 				return nil
 			}
 			c.populateCluster(ctx, cluster)
+
+			// Labels are in the cluster spec, so we can only match them after we've read it.
+			if !labelSelector.Matches(labels.Set(cluster.Labels)) {
+				return nil
+			}
 			all[i] = cluster
 			return nil
 		})
@@ -954,7 +1720,11 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Cluste
 // If the current cluster is on a remote docker instance,
 // we need a port-forwarder to connect it.
 func (c *Controller) maybeCreateForwarderForCurrentCluster(ctx context.Context, errOut io.Writer) error {
-	if docker.IsLocalHost(docker.GetHostEnv()) {
+	dcli, err := c.getDockerClient(ctx)
+	if err != nil {
+		return err
+	}
+	if dcli.IsLocalHost() {
 		return nil
 	}
 
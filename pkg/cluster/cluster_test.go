@@ -4,26 +4,42 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
 	"github.com/tilt-dev/ctlptl/pkg/registry"
 	"github.com/tilt-dev/localregistry-go"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 )
@@ -36,6 +52,43 @@ func TestClusterGet(t *testing.T) {
 	assert.Equal(t, cluster.Product, "microk8s")
 }
 
+func TestClusterGetUnreadableNodesAndIngress(t *testing.T) {
+	f := newFixture(t)
+	f.fakeK8s.PrependReactor("list", "nodes",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("nodes is forbidden")
+		})
+	f.fakeDynamic.PrependReactor("list", "ingressclasses",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("ingressclasses is forbidden")
+		})
+
+	cluster, err := f.controller.Get(context.Background(), "microk8s")
+	require.NoError(t, err)
+
+	var nodesCond, ingressCond api.Condition
+	for _, c := range cluster.Status.Conditions {
+		switch c.Type {
+		case api.ClusterConditionNodesReadable:
+			nodesCond = c
+		case api.ClusterConditionIngressReadable:
+			ingressCond = c
+		}
+	}
+	assert.Equal(t, api.Condition{
+		Type:    api.ClusterConditionNodesReadable,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NodesUnreadable",
+		Message: "nodes is forbidden",
+	}, nodesCond)
+	assert.Equal(t, api.Condition{
+		Type:    api.ClusterConditionIngressReadable,
+		Status:  metav1.ConditionFalse,
+		Reason:  "IngressUnreadable",
+		Message: "ingressclasses is forbidden",
+	}, ingressCond)
+}
+
 func TestClusterCurrent(t *testing.T) {
 	c := newFakeController(t)
 	cluster, err := c.Current(context.Background())
@@ -59,6 +112,52 @@ func TestDeleteClusterContext(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func TestDeleteUsesRecordedEngine(t *testing.T) {
+	f := newFixture(t)
+
+	var loaded docker.Engine
+	f.controller.dockerClientLoader = func(ctx context.Context, engine docker.Engine) (dockerClient, error) {
+		loaded = engine
+		return &fakeDockerClient{engine: engine}, nil
+	}
+
+	admin, err := f.controller.adminForCluster(context.Background(), &api.Cluster{
+		Name:    "kind-kind",
+		Product: string(ProductKIND),
+		Engine:  "podman",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, docker.EnginePodman, loaded)
+	assert.Equal(t, docker.EnginePodman, admin.(*kindAdmin).engine)
+
+	// Clusters on the connected engine share the cached admin.
+	loaded = ""
+	admin, err = f.controller.adminForCluster(context.Background(), &api.Cluster{
+		Name:    "kind-kind",
+		Product: string(ProductKIND),
+		Engine:  "docker",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, docker.Engine(""), loaded)
+	assert.Equal(t, docker.EngineDocker, admin.(*kindAdmin).engine)
+}
+
+func TestIrreconcilableEngine(t *testing.T) {
+	f := newFixture(t)
+	existing := &api.Cluster{Name: "kind-kind", Product: string(ProductKIND), Engine: "docker"}
+
+	diffs := f.controller.irreconcilableDifferences(context.Background(),
+		&api.Cluster{Name: "kind-kind", Product: string(ProductKIND), Engine: "podman"}, existing)
+	require.Equal(t, 1, len(diffs))
+	assert.Equal(t, "engine", diffs[0].Field)
+	assert.Contains(t, diffs[0].Message, "desired container engine (podman) does not match current (docker)")
+
+	// An unspecified engine means "whatever's there".
+	diffs = f.controller.irreconcilableDifferences(context.Background(),
+		&api.Cluster{Name: "kind-kind", Product: string(ProductKIND)}, existing)
+	assert.Equal(t, 0, len(diffs))
+}
+
 func TestClusterList(t *testing.T) {
 	c := newFakeController(t)
 	clusters, err := c.List(context.Background(), ListOptions{})
@@ -91,228 +190,1348 @@ func TestClusterGetMissing(t *testing.T) {
 	}
 }
 
-func TestClusterApplyKIND(t *testing.T) {
+func TestClusterApplyKIND(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, false, f.d4m.started)
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
+	assert.Equal(t, "kind-kind", result.Name)
+}
+
+func TestClusterApplyFailsToStart(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	out := bytes.NewBuffer(nil)
+	f.controller.iostreams.ErrOut = out
+
+	assert.Equal(t, false, f.d4m.started)
+	_ = f.newFakeAdmin(ProductKIND)
+
+	// Pretend that the kube-public namespace is never created.
+	err := f.fakeK8s.CoreV1().Namespaces().Delete(
+		context.Background(), "kube-public", metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timed out waiting for cluster to start")
+		assert.Contains(t, out.String(), "Waiting 0s for Kubernetes cluster \"kind-kind\" to start")
+	}
+}
+
+func TestClusterApplyKINDWithCluster(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "linux"
+
+	f.dockerClient.started = true
+
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "kind-registry",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "kind-kind", result.Name)
+	assert.Equal(t, "kind-registry", result.Registry)
+	assert.Equal(t, "kind-registry", kindAdmin.createdRegistry.Name)
+	assert.Equal(t, 5000, kindAdmin.createdRegistry.Status.ContainerPort)
+	assert.Equal(t, "kind-registry", f.registryCtl.lastApply.Name)
+}
+
+func TestClusterApplyAttachRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+	})
+	require.NoError(t, err)
+	kindAdmin.created = nil
+
+	actions, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "kind-registry",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "create registry kind-registry"},
+		{Description: "attach registry kind-registry"},
+	}, actions)
+
+	// Adding a registry connects the existing cluster to it in place.
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "kind-registry",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Equal(t, "kind-registry", kindAdmin.attached.Name)
+	assert.Equal(t, "kind-registry", result.Registry)
+	assert.Equal(t, "localhost:5000", result.Status.LocalRegistryHosting.Host)
+	assert.Equal(t, []api.Condition{
+		{Type: api.ClusterConditionMachineReachable, Status: metav1.ConditionTrue, Reason: "Reachable"},
+		{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue, Reason: "Reachable"},
+		{Type: api.ClusterConditionNodesReadable, Status: metav1.ConditionTrue, Reason: "Read"},
+		{Type: api.ClusterConditionRegistryConnected, Status: metav1.ConditionTrue, Reason: "Connected"},
+		{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionTrue, Reason: "Recorded"},
+		{Type: api.ClusterConditionIngressReadable, Status: metav1.ConditionTrue, Reason: "Read"},
+	}, result.Status.Conditions)
+}
+
+func TestClusterApplyDetachRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "kind-registry",
+	})
+	require.NoError(t, err)
+	kindAdmin.created = nil
+
+	actions, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "none",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "detach registry kind-registry"},
+	}, actions)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductKIND),
+		Registry: "none",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Nil(t, kindAdmin.attached)
+	assert.Equal(t, "kind-registry", kindAdmin.detached.Name)
+	assert.Equal(t, "", result.Registry)
+	assert.Equal(t, "", result.Status.LocalRegistryHosting.Host)
+	assert.Contains(t, f.errOut.String(), "Disconnecting cluster kind-kind from registry kind-registry")
+}
+
+func TestRegistryConnectedCondition(t *testing.T) {
+	assert.Nil(t, registryConnectedCondition("", nil, nil))
+	assert.Nil(t, registryConnectedCondition("registry.example.com:5000", nil, nil))
+
+	running := &api.Registry{Name: "kind-registry", Status: api.RegistryStatus{State: "running"}}
+	assert.Equal(t, "Connected", registryConnectedCondition("localhost:5000", running, nil).Reason)
+
+	exited := &api.Registry{Name: "kind-registry", Status: api.RegistryStatus{State: "exited"}}
+	cond := registryConnectedCondition("localhost:5000", exited, nil)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "RegistryNotRunning", cond.Reason)
+	assert.Equal(t, "registry kind-registry is exited", cond.Message)
+
+	cond = registryConnectedCondition("localhost:5000", nil, nil)
+	assert.Equal(t, "RegistryNotFound", cond.Reason)
+	assert.Equal(t, "no registry is listening on localhost:5000", cond.Message)
+
+	cond = registryConnectedCondition("localhost:5000", nil, fmt.Errorf("docker is down"))
+	assert.Equal(t, "RegistryDiscoveryFailed", cond.Reason)
+}
+
+func TestClusterApplyDockerDesktop(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, false, f.d4m.started)
+	assert.Equal(t, 1, f.dockerClient.ncpu)
+	f, _ = controllerApply(f, ProductDockerDesktop, 3)
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, 3, f.dockerClient.ncpu)
+}
+
+func TestClusterApplyDockerDesktopCPUOnly(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	err := f.d4m.Open(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, 1, f.dockerClient.ncpu)
+	f, _ = controllerApply(f, ProductDockerDesktop, 3)
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, 3, f.dockerClient.ncpu)
+}
+
+func TestClusterApplyDockerDesktopStartClusterOnly(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, false, f.d4m.started)
+	assert.Equal(t, 1, f.dockerClient.ncpu)
+	f, _ = controllerApply(f, ProductDockerDesktop, 0)
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, 1, f.dockerClient.ncpu)
+}
+
+func TestClusterApplyDockerDesktopMemory(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:   string(ProductDockerDesktop),
+		MinMemory: "8Gi",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8*gibibyte), f.dockerClient.memTotal)
+	assert.Equal(t, 1, f.d4m.settingsWriteCount)
+
+	// Applying again shouldn't change anything.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:   string(ProductDockerDesktop),
+		MinMemory: "8Gi",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.d4m.settingsWriteCount)
+}
+
+func TestClusterApplyMinMemoryLinux(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "linux"
+	f.dockerClient.started = true
+	f.dockerClient.memTotal = 2 * gibibyte
+	f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:   string(ProductKIND),
+		MinMemory: "8Gi",
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Cannot automatically set minimum memory to 8Gi on this platform")
+	}
+}
+
+func TestClusterApplyMinMemoryLinuxKernelReserved(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "linux"
+	f.dockerClient.started = true
+	// An 8Gi machine, minus what the kernel keeps for itself.
+	f.dockerClient.memTotal = 8*gibibyte - 200*mebibyte
+	f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:   string(ProductKIND),
+		MinMemory: "8Gi",
+	})
+	require.NoError(t, err)
+}
+
+func TestClusterApplyInvalidMinMemory(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:   string(ProductDockerDesktop),
+		MinMemory: "lots",
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "parsing minMemory")
+	}
+}
+
+func controllerApply(f *fixture, product Product, cpus int) (*fixture, error) {
+	cluster := &api.Cluster{
+		Product: string(product),
+		MinCPUs: cpus,
+	}
+	_, err := f.controller.Apply(context.Background(), cluster)
+	return f, err
+}
+
+func TestClusterApplyDockerDesktopNoRestart(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, 0, f.d4m.settingsWriteCount)
+
+	// Pretend the cluster isn't running.
+	err := f.fakeK8s.Tracker().Delete(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "nodes"}, "", "node-1")
+	assert.NoError(t, err)
+	f, _ = controllerApply(f, ProductDockerDesktop, 0)
+	assert.Equal(t, 1, f.d4m.settingsWriteCount)
+	f, _ = controllerApply(f, ProductDockerDesktop, 0)
+	assert.Equal(t, 1, f.d4m.settingsWriteCount)
+}
+
+func TestClusterApplyMinikubeRestartRestoresRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	minikubeAdmin := f.newFakeAdmin(ProductMinikube)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductMinikube),
+		Registry: "kind-registry",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, minikubeAdmin.restored)
+	minikubeAdmin.created = nil
+
+	// `minikube start` rewrites the containerd config, so the restart
+	// has to put the registry back, even if the config doesn't name it.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductMinikube),
+		MinCPUs: 3,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, minikubeAdmin.created)
+	require.NotNil(t, minikubeAdmin.restored)
+	assert.Equal(t, "kind-registry", minikubeAdmin.restored.Name)
+}
+
+func TestClusterApplyMinikubeUpgradeRestoresRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	minikubeAdmin := f.newFakeAdmin(ProductMinikube)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.14.0",
+		Registry:          "kind-registry",
+	})
+	require.NoError(t, err)
+	minikubeAdmin.created = nil
+
+	// The upgrade runs `minikube start` too.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.15.0",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, minikubeAdmin.created)
+	assert.Equal(t, "v1.15.0", minikubeAdmin.upgraded.KubernetesVersion)
+	require.NotNil(t, minikubeAdmin.restored)
+	assert.Equal(t, "kind-registry", minikubeAdmin.restored.Name)
+}
+
+func TestClusterApplyMinikubeVersion(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, false, f.d4m.started)
+	minikubeAdmin := f.newFakeAdmin(ProductMinikube)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.14.0",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, true, f.d4m.started)
+	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
+	assert.Equal(t, "minikube", result.Name)
+	assert.Equal(t, "minikube", f.config.CurrentContext)
+
+	minikubeAdmin.created = nil
+
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.14.0",
+	})
+	assert.NoError(t, err)
+
+	// Make sure we don't recreate the cluster.
+	assert.Nil(t, minikubeAdmin.created)
+
+	// Now, bump the version and make sure we upgrade the cluster in place.
+	out := bytes.NewBuffer(nil)
+	f.controller.iostreams.ErrOut = out
+
+	actions, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.15.0",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "upgrade Kubernetes from v1.14.0 to v1.15.0"},
+	}, actions)
+
+	result, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.15.0",
+	})
+	assert.NoError(t, err)
+
+	assert.Nil(t, minikubeAdmin.created)
+	assert.Nil(t, minikubeAdmin.deleted)
+	assert.Equal(t, "v1.15.0", minikubeAdmin.upgraded.KubernetesVersion)
+	assert.Equal(t, "v1.15.0", result.KubernetesVersion)
+	assert.Equal(t, "v1.15.0", result.Status.KubernetesVersion)
+	assert.Contains(t, out.String(), "Upgrading cluster minikube from Kubernetes v1.14.0 to v1.15.0")
+
+	// Minikube can't downgrade, so make sure we re-create the cluster.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductMinikube),
+		KubernetesVersion: "v1.14.0",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
+	assert.Contains(t, out.String(),
+		"Deleting cluster minikube because desired Kubernetes version (v1.14.0) "+
+			"does not match current (v1.15.0)")
+}
+
+func TestCanReconcileK3dVersion(t *testing.T) {
+	c := newFakeController(t)
+	desired := &api.Cluster{Product: string(ProductK3D), KubernetesVersion: "v1.21.2"}
+	existing := &api.Cluster{Status: api.ClusterStatus{KubernetesVersion: "v1.21.2+k3s1"}}
+	assert.True(t, c.canReconcileK8sVersion(context.Background(), desired, existing))
+
+	existing.Status.KubernetesVersion = "v1.21.1+k3s1"
+	assert.False(t, c.canReconcileK8sVersion(context.Background(), desired, existing))
+
+	// k3d needs a new cluster for a new version.
+	assert.False(t, c.needsK8sUpgrade(context.Background(), desired, existing))
+}
+
+func TestFillDefaultsKindConfig(t *testing.T) {
+	c := &api.Cluster{
+		Product: "kind",
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Name: "my-cluster",
+		},
+	}
+	FillDefaults(c)
+	assert.Equal(t, "kind-my-cluster", c.Name)
+
+	c.KindV1Alpha4Cluster.Name = "your-cluster"
+	FillDefaults(c)
+	assert.Equal(t, "my-cluster", c.KindV1Alpha4Cluster.Name)
+}
+
+func TestFillDefaultsK3dConfig(t *testing.T) {
+	c := &api.Cluster{
+		Product: "k3d",
+		K3DV1Alpha3Simple: &k3dv1alpha3.SimpleConfig{
+			Name: "my-cluster",
+		},
+	}
+	FillDefaults(c)
+	assert.Equal(t, "k3d-my-cluster", c.Name)
+
+	c.K3DV1Alpha3Simple.Name = "your-cluster"
+	FillDefaults(c)
+	assert.Equal(t, "my-cluster", c.K3DV1Alpha3Simple.Name)
+}
+
+func TestClusterApplyKindConfig(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	assert.Equal(t, false, f.d4m.started)
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	cluster := &api.Cluster{
+		Product: string(ProductKIND),
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+			},
+		},
+	}
+	_, err := f.controller.Apply(context.Background(), cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
+	kindAdmin.created = nil
+
+	// Assert that re-applying the same config doesn't create a new cluster.
+	_, err = f.controller.Apply(context.Background(), cluster)
+	assert.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+
+	// Assert that applying a different config deletes and re-creates.
+	cluster2 := &api.Cluster{
+		Product: string(ProductKIND),
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+				v1alpha4.Node{Role: "worker"},
+			},
+		},
+	}
+
+	f.errOut.Truncate(0)
+	_, err = f.controller.Apply(context.Background(), cluster2)
+	assert.NoError(t, err)
+	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
+	assert.Equal(t, "kind-kind", kindAdmin.deleted.Name)
+	assert.Contains(t, f.errOut.String(), "desired Kind config does not match current")
+}
+
+func TestClusterApplyRefusesRecreate(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	kindAdmin.created = nil
+
+	var confirmed *IrreconcilableError
+	f.controller.SetConfirmRecreate(func(ctx context.Context, irreconcilable *IrreconcilableError) (bool, error) {
+		confirmed = irreconcilable
+		return false, nil
+	})
+
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductKIND),
+		KubernetesVersion: "v1.20.0",
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+				v1alpha4.Node{Role: "worker"},
+			},
+		},
+	})
+	require.Error(t, err)
+	irreconcilable, ok := err.(*IrreconcilableError)
+	require.True(t, ok)
+	assert.Equal(t, confirmed, irreconcilable)
+	assert.Equal(t, "kind-kind", irreconcilable.Name)
+	require.Equal(t, 2, len(irreconcilable.Differences))
+	assert.Equal(t, "kubernetesVersion", irreconcilable.Differences[0].Field)
+	assert.Equal(t, "kindV1Alpha4Cluster", irreconcilable.Differences[1].Field)
+	assert.Contains(t, irreconcilable.Differences[1].Diff, "worker")
+	assert.Contains(t, err.Error(), "Re-run with --force")
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Nil(t, kindAdmin.created)
+
+	// Without a way to confirm, Apply refuses too.
+	f.controller.SetConfirmRecreate(nil)
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:           string(ProductKIND),
+		KubernetesVersion: "v1.20.0",
+	})
+	require.Error(t, err)
+	assert.Nil(t, kindAdmin.deleted)
+}
+
+func TestClusterPlanKindConfig(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	cluster := &api.Cluster{
+		Product: string(ProductKIND),
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+			},
+		},
+	}
+	actions, err := f.controller.Plan(context.Background(), cluster.DeepCopy())
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{{Description: "create cluster"}}, actions)
+	assert.Nil(t, kindAdmin.created)
+
+	_, err = f.controller.Apply(context.Background(), cluster.DeepCopy())
+	require.NoError(t, err)
+	kindAdmin.created = nil
+
+	actions, err = f.controller.Plan(context.Background(), cluster.DeepCopy())
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	cluster2 := &api.Cluster{
+		Product: string(ProductKIND),
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{
+				v1alpha4.Node{Role: "control-plane"},
+				v1alpha4.Node{Role: "worker"},
+			},
+		},
+		Addons: []string{"./crds.yaml"},
+	}
+	actions, err = f.controller.Plan(context.Background(), cluster2)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(actions))
+	assert.Equal(t, "recreate cluster because desired Kind config does not match current", actions[0].Description)
+	assert.Contains(t, actions[0].Diff, "worker")
+	assert.Equal(t, "create cluster", actions[1].Description)
+	assert.Equal(t, "apply addon ./crds.yaml", actions[2].Description)
+
+	// Planning doesn't touch the cluster.
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.NotContains(t, f.errOut.String(), "Deleting cluster")
+}
+
+func TestClusterPlanRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	f.newFakeAdmin(ProductKIND)
+
+	actions, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product:         string(ProductKIND),
+		Registry:        "ctlptl-registry",
+		RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "create registry ctlptl-registry"},
+		{Description: "create registry ctlptl-dockerhub-cache as a pull-through cache for docker.io"},
+		{Description: "create cluster"},
+	}, actions)
+	assert.Nil(t, f.registryCtl.lastApply)
+
+	// A plain registry can't become a pull-through cache.
+	f.registryCtl.lastApply = &api.Registry{Name: "ctlptl-dockerhub-cache"}
+	_, err = f.controller.Plan(context.Background(), &api.Cluster{
+		Product:         string(ProductKIND),
+		RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "registry ctlptl-dockerhub-cache already exists and is not a pull-through cache")
+	}
+}
+
+func TestClusterPlanDockerDesktopCPUs(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	f.dockerClient.started = true
+
+	actions, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product: string(ProductDockerDesktop),
+		MinCPUs: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "restart Docker Desktop for CPUs (want 3, have 1)"},
+	}, actions)
+	assert.Equal(t, false, f.d4m.started)
+	assert.Equal(t, 1, f.dockerClient.ncpu)
+}
+
+func TestClusterPlanDockerDesktopNotRunning(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	// Plan doesn't start Docker Desktop, so it can't read the CPUs.
+	_, err := f.controller.Plan(context.Background(), &api.Cluster{
+		Product: string(ProductDockerDesktop),
+		MinCPUs: 3,
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "reading Docker Desktop resources")
+	}
+	assert.Equal(t, false, f.d4m.started)
+}
+
+func TestClusterApplyNodes(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	cluster := &api.Cluster{
+		Product: string(ProductKIND),
+		Nodes:   &api.ClusterNodes{Workers: 1},
+	}
+	_, err := f.controller.Apply(context.Background(), cluster)
+	assert.NoError(t, err)
+	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
+	kindAdmin.created = nil
+
+	// Assert that re-applying the same nodes doesn't create a new cluster.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Nodes:   &api.ClusterNodes{ControlPlanes: 1, Workers: 1},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+
+	// Assert that applying different nodes deletes and re-creates.
+	f.errOut.Truncate(0)
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Nodes:   &api.ClusterNodes{Workers: 2},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
+	assert.Equal(t, "kind-kind", kindAdmin.deleted.Name)
+	assert.Contains(t, f.errOut.String(),
+		"desired nodes (controlPlanes: 1, workers: 2) do not match current (controlPlanes: 1, workers: 1)")
+}
+
+func TestClusterApplyPortMappingsInUse(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		PortMappings: []api.PortMapping{
+			{HostPort: port, ContainerPort: 80, ListenAddress: "127.0.0.1"},
+		},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is already in use")
+	}
+	assert.Nil(t, kindAdmin.created)
+}
+
+func TestClusterApplyIngress(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.fakeK8s.CoreV1().Nodes().Create(context.Background(), &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "kind-control-plane",
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, kindAdmin.created)
+	assert.Equal(t, "nginx", result.Status.IngressController)
+	assert.Contains(t, f.errOut.String(), "Installing nginx ingress controller on cluster kind-kind")
+
+	node, err := f.fakeK8s.CoreV1().Nodes().Get(context.Background(), "kind-control-plane", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "true", node.Labels["ingress-ready"])
+
+	deployment, err := f.fakeDynamic.
+		Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).
+		Namespace("ingress-nginx").
+		Get(context.Background(), "ingress-nginx-controller", metav1.GetOptions{})
+	require.NoError(t, err)
+	nodeSelector, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "spec", "nodeSelector")
+	assert.Equal(t, "true", nodeSelector["ingress-ready"])
+
+	// Applying again leaves the controller alone.
+	kindAdmin.created = nil
+	f.errOut.Reset()
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.NotContains(t, f.errOut.String(), "Installing")
+}
+
+func TestClusterApplyIngressOverBundledTraefik(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{Product: string(ProductKIND)})
+	require.NoError(t, err)
+	kindAdmin.created = nil
+
+	// Like the traefik that comes with k3s.
+	_, err = f.fakeK8s.CoreV1().Nodes().Create(context.Background(), &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "kind-control-plane",
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = f.fakeDynamic.Resource(ingressClassesGVR).Create(context.Background(), &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "IngressClass",
+			"metadata":   map[string]interface{}{"name": "traefik"},
+			"spec":       map[string]interface{}{"controller": "traefik.io/ingress-controller"},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// Adding nginx installs it next to traefik, rather than recreating the cluster.
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Contains(t, f.errOut.String(), "Installing nginx ingress controller on cluster kind-kind")
+	assert.Equal(t, "nginx", result.Ingress.Controller)
+
+	// Applying again leaves the controller alone.
+	f.errOut.Reset()
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.NotContains(t, f.errOut.String(), "Installing")
+
+	// Switching the controller that ctlptl installed needs a new cluster.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "contour"},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, kindAdmin.deleted)
+	assert.Contains(t, f.errOut.String(), "desired ingress controller (contour) does not match current (nginx)")
+}
+
+func TestClusterApplyIngressValidation(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Ingress: &api.ClusterIngress{Controller: "haproxy"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `ingress: unknown controller "haproxy". Must be one of: contour, nginx, traefik`)
+	}
+
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductDockerDesktop),
+		Ingress: &api.ClusterIngress{Controller: "nginx"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "product docker-desktop does not support ingress")
+	}
+}
+
+func TestClusterApplyAddons(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	dir := t.TempDir()
+	metricsServer := filepath.Join(dir, "metrics-server.yaml")
+	require.NoError(t, ioutil.WriteFile(metricsServer, []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: metrics-server
+  namespace: kube-system
+spec:
+  replicas: 1
+`), 0644))
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(config, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: team-config
+data:
+  team: dev
+`), 0644))
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Addons:  []string{metricsServer},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, kindAdmin.created)
+	assert.Contains(t, f.errOut.String(), "Applying addon "+metricsServer)
+
+	_, err = f.fakeDynamic.
+		Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).
+		Namespace("kube-system").
+		Get(context.Background(), "metrics-server", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	cm, err := f.fakeK8s.CoreV1().ConfigMaps("kube-public").Get(context.Background(), clusterSpecConfigMap, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data["cluster.v1alpha1"], metricsServer)
+
+	// Re-applying only installs the new addon.
+	kindAdmin.created = nil
+	f.errOut.Reset()
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Addons:  []string{metricsServer, config},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.NotContains(t, f.errOut.String(), "Applying addon "+metricsServer)
+	assert.Contains(t, f.errOut.String(), "Applying addon "+config)
+
+	_, err = f.fakeDynamic.
+		Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace("default").
+		Get(context.Background(), "team-config", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	cm, err = f.fakeK8s.CoreV1().ConfigMaps("kube-public").Get(context.Background(), clusterSpecConfigMap, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data["cluster.v1alpha1"], config)
+}
+
+func TestClusterApplyAddonsCustomResources(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	f.newFakeAdmin(ProductKIND)
+
+	// The cluster only serves the CRD's kind once the CRD is created.
+	f.fakeDynamic.PrependReactor("create", "customresourcedefinitions",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			f.fakeK8s.Resources = append(f.fakeK8s.Resources, &metav1.APIResourceList{
+				GroupVersion: "example.dev/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "widgets", Kind: "Widget", Namespaced: true},
+				},
+			})
+			return false, nil, nil
+		})
+
+	addon := filepath.Join(t.TempDir(), "widgets.yaml")
+	require.NoError(t, ioutil.WriteFile(addon, []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.dev
+---
+apiVersion: example.dev/v1
+kind: Widget
+metadata:
+  name: my-widget
+  namespace: default
+`), 0644))
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Addons:  []string{addon},
+	})
+	require.NoError(t, err)
+
+	_, err = f.fakeDynamic.
+		Resource(schema.GroupVersionResource{Group: "example.dev", Version: "v1", Resource: "widgets"}).
+		Namespace("default").
+		Get(context.Background(), "my-widget", metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestClusterApplyPreloadImagesToRegistry(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+	f.dockerClient.images = map[string]bool{"my-app:dev": true}
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:       string(ProductKIND),
+		Registry:      "kind-registry",
+		PreloadImages: []string{"nginx:1.21", "my-app:dev"},
+	})
+	require.NoError(t, err)
+
+	// Images already on the host aren't pulled again.
+	assert.Equal(t, []string{"nginx:1.21"}, f.dockerClient.pulled)
+
+	pushed := append([]string{}, f.dockerClient.pushed...)
+	sort.Strings(pushed)
+	assert.Equal(t, []string{
+		"localhost:5000/library/my-app:dev",
+		"localhost:5000/library/nginx:1.21",
+	}, pushed)
+	assert.Empty(t, kindAdmin.loaded)
+	assert.Contains(t, f.errOut.String(), "Preloaded 2 images into cluster kind-kind")
+}
+
+func TestClusterApplyPreloadImagesWithLoader(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:       string(ProductKIND),
+		PreloadImages: []string{"nginx:1.21", "redis:6"},
+	})
+	require.NoError(t, err)
+
+	sort.Strings(kindAdmin.loaded)
+	assert.Equal(t, []string{"nginx:1.21", "redis:6"}, kindAdmin.loaded)
+	assert.Empty(t, f.dockerClient.pushed)
+
+	// Re-applying an existing cluster doesn't preload again.
+	kindAdmin.loaded = nil
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:       string(ProductKIND),
+		PreloadImages: []string{"nginx:1.21", "redis:6"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, kindAdmin.loaded)
+}
+
+func TestClusterApplyPreloadImagesValidation(t *testing.T) {
+	f := newFixture(t)
+	f.newFakeAdmin(ProductMicroK8s)
+
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:       string(ProductMicroK8s),
+		PreloadImages: []string{"nginx:1.21"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "product microk8s can't load images without a registry")
+	}
+
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:       string(ProductKIND),
+		PreloadImages: []string{"Not An Image"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `preloadImages: invalid image "Not An Image"`)
+	}
+}
+
+func TestClusterApplyRegistryMirrors(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:         string(ProductKIND),
+		RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"docker.io": "ctlptl-dockerhub-cache"}, result.RegistryMirrors)
+	require.Equal(t, 1, len(kindAdmin.mirrors))
+	assert.Equal(t, "docker.io", kindAdmin.mirrors[0].host)
+	assert.Equal(t, "ctlptl-dockerhub-cache", kindAdmin.mirrors[0].registry.Name)
+	assert.Equal(t, "https://registry-1.docker.io", f.registryCtl.lastApply.ProxyRemoteURL)
+
+	// Changing the mirrors recreates the cluster.
+	kindAdmin.created = nil
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:         string(ProductKIND),
+		RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache", "gcr.io": "ctlptl-gcr-cache"},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, kindAdmin.deleted)
+	assert.NotNil(t, kindAdmin.created)
+	assert.Equal(t, 2, len(kindAdmin.mirrors))
+}
+
+func TestClusterApplyFeatureGatesChange(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:      string(ProductKIND),
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"EphemeralContainers": true}, result.FeatureGates)
+
+	// Re-applying the same gates leaves the cluster alone.
+	kindAdmin.created = nil
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:      string(ProductKIND),
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+
+	// Adding an API server flag recreates it.
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:      string(ProductKIND),
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+		APIServer:    &api.ClusterComponent{ExtraArgs: map[string]string{"v": "4"}},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, kindAdmin.deleted)
+	assert.NotNil(t, kindAdmin.created)
+	assert.Contains(t, f.errOut.String(), "desired feature gates or extra args do not match current")
+}
+
+func TestClusterApplyLabels(t *testing.T) {
+	f := newFixture(t)
+	f.dmachine.os = "darwin"
+	kindAdmin := f.newFakeAdmin(ProductKIND)
+
+	// The other clusters in the kubeconfig don't have the labels.
+	for _, server := range []string{"http://microk8s.localhost/", "http://docker-desktop.localhost/"} {
+		f.otherK8s[server] = fake.NewSimpleClientset(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1", CreationTimestamp: metav1.Time{Time: time.Now()}},
+		})
+	}
+
+	result, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Labels:  map[string]string{"team": "payments"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments"}, result.Labels)
+
+	clusters, err := f.controller.List(context.Background(), ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(clusters.Items))
+
+	clusters, err = f.controller.List(context.Background(), ListOptions{LabelSelector: "team=payments"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(clusters.Items))
+	assert.Equal(t, "kind-kind", clusters.Items[0].Name)
+
+	// Changing the labels updates the spec without recreating the cluster.
+	kindAdmin.created = nil
+	result, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product:     string(ProductKIND),
+		Labels:      map[string]string{"team": "billing"},
+		Annotations: map[string]string{"owner": "jane@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Equal(t, map[string]string{"team": "billing"}, result.Labels)
+	assert.Equal(t, map[string]string{"owner": "jane@example.com"}, result.Annotations)
+
+	clusters, err = f.controller.List(context.Background(), ListOptions{LabelSelector: "team=payments"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(clusters.Items))
+}
+
+func TestClusterApplyTTL(t *testing.T) {
 	f := newFixture(t)
 	f.dmachine.os = "darwin"
-
-	assert.Equal(t, false, f.d4m.started)
 	kindAdmin := f.newFakeAdmin(ProductKIND)
 
 	result, err := f.controller.Apply(context.Background(), &api.Cluster{
 		Product: string(ProductKIND),
+		TTL:     "8h",
 	})
-	assert.NoError(t, err)
-	assert.Equal(t, true, f.d4m.started)
-	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
-	assert.Equal(t, "kind-kind", result.Name)
+	require.NoError(t, err)
+	assert.Equal(t, "8h", result.TTL)
+
+	// Extending the TTL doesn't recreate the cluster.
+	kindAdmin.created = nil
+	result, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		TTL:     "24h",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, kindAdmin.created)
+	assert.Nil(t, kindAdmin.deleted)
+	assert.Equal(t, "24h", result.TTL)
 }
 
-func TestClusterApplyFailsToStart(t *testing.T) {
+func TestClusterApplyLabelsValidation(t *testing.T) {
 	f := newFixture(t)
 	f.dmachine.os = "darwin"
+	f.newFakeAdmin(ProductKIND)
 
-	out := bytes.NewBuffer(nil)
-	f.controller.iostreams.ErrOut = out
-
-	assert.Equal(t, false, f.d4m.started)
-	_ = f.newFakeAdmin(ProductKIND)
-
-	// Pretend that the kube-public namespace is never created.
-	err := f.fakeK8s.CoreV1().Namespaces().Delete(
-		context.Background(), "kube-public", metav1.DeleteOptions{})
-	require.NoError(t, err)
-
-	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
 		Product: string(ProductKIND),
+		Labels:  map[string]string{"team": "not a valid value"},
 	})
 	if assert.Error(t, err) {
-		assert.Contains(t, err.Error(), "timed out waiting for cluster to start")
-		assert.Contains(t, out.String(), "Waiting 0s for Kubernetes cluster \"kind-kind\" to start")
+		assert.Contains(t, err.Error(), `labels: invalid value "not a valid value" for team`)
 	}
 }
 
-func TestClusterApplyKINDWithCluster(t *testing.T) {
+func TestClusterApplyRegistryMirrorsValidation(t *testing.T) {
 	f := newFixture(t)
-	f.dmachine.os = "linux"
-
-	f.dockerClient.started = true
-
-	kindAdmin := f.newFakeAdmin(ProductKIND)
-
-	result, err := f.controller.Apply(context.Background(), &api.Cluster{
-		Product:  string(ProductKIND),
-		Registry: "kind-registry",
-	})
-	assert.NoError(t, err)
-	assert.Equal(t, "kind-kind", result.Name)
-	assert.Equal(t, "kind-registry", result.Registry)
-	assert.Equal(t, "kind-registry", kindAdmin.createdRegistry.Name)
-	assert.Equal(t, 5000, kindAdmin.createdRegistry.Status.ContainerPort)
-	assert.Equal(t, "kind-registry", f.registryCtl.lastApply.Name)
+	f.newFakeAdmin(ProductKIND)
+
+	for _, tc := range []struct {
+		name    string
+		cluster *api.Cluster
+		err     string
+	}{
+		{
+			name: "product",
+			cluster: &api.Cluster{
+				Product:         string(ProductDockerDesktop),
+				RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache"},
+			},
+			err: "product docker-desktop does not support registry mirrors",
+		},
+		{
+			name: "host",
+			cluster: &api.Cluster{
+				Product:         string(ProductKIND),
+				RegistryMirrors: map[string]string{"https://docker.io": "ctlptl-dockerhub-cache"},
+			},
+			err: `registryMirrors: "https://docker.io" must be a registry host`,
+		},
+		{
+			name: "shared mirror",
+			cluster: &api.Cluster{
+				Product:         string(ProductKIND),
+				RegistryMirrors: map[string]string{"docker.io": "ctlptl-cache", "gcr.io": "ctlptl-cache"},
+			},
+			err: "registryMirrors: docker.io and gcr.io can't share the mirror ctlptl-cache",
+		},
+		{
+			name: "cluster registry",
+			cluster: &api.Cluster{
+				Product:         string(ProductKIND),
+				Registry:        "ctlptl-registry",
+				RegistryMirrors: map[string]string{"docker.io": "ctlptl-registry"},
+			},
+			err: "registryMirrors: docker.io can't use the cluster registry ctlptl-registry as a mirror",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := f.controller.Apply(context.Background(), tc.cluster)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
 }
 
-func TestClusterApplyDockerDesktop(t *testing.T) {
+func TestClusterApplyNodesValidation(t *testing.T) {
 	f := newFixture(t)
-	f.dmachine.os = "darwin"
-
-	assert.Equal(t, false, f.d4m.started)
-	assert.Equal(t, 1, f.dockerClient.ncpu)
-	f, _ = controllerApply(f, ProductDockerDesktop, 3)
-	assert.Equal(t, true, f.d4m.started)
-	assert.Equal(t, 3, f.dockerClient.ncpu)
-}
 
-func TestClusterApplyDockerDesktopCPUOnly(t *testing.T) {
-	f := newFixture(t)
-	f.dmachine.os = "darwin"
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductDockerDesktop),
+		Nodes:   &api.ClusterNodes{Workers: 1},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "product docker-desktop does not support nodes")
+	}
 
-	err := f.d4m.Open(context.Background())
-	require.NoError(t, err)
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductMinikube),
+		Nodes:   &api.ClusterNodes{ControlPlanes: 3},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "minikube clusters only support 1 control plane")
+	}
 
-	assert.Equal(t, true, f.d4m.started)
-	assert.Equal(t, 1, f.dockerClient.ncpu)
-	f, _ = controllerApply(f, ProductDockerDesktop, 3)
-	assert.Equal(t, true, f.d4m.started)
-	assert.Equal(t, 3, f.dockerClient.ncpu)
+	_, err = f.controller.Apply(context.Background(), &api.Cluster{
+		Product: string(ProductKIND),
+		Nodes:   &api.ClusterNodes{Workers: 1},
+		KindV1Alpha4Cluster: &v1alpha4.Cluster{
+			Nodes: []v1alpha4.Node{{Role: "control-plane"}},
+		},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "nodes may not be set together with kindV1Alpha4Cluster.nodes")
+	}
 }
 
-func TestClusterApplyDockerDesktopStartClusterOnly(t *testing.T) {
+func TestClusterApplyMinikubeConfig(t *testing.T) {
 	f := newFixture(t)
 	f.dmachine.os = "darwin"
 
-	assert.Equal(t, false, f.d4m.started)
-	assert.Equal(t, 1, f.dockerClient.ncpu)
-	f, _ = controllerApply(f, ProductDockerDesktop, 0)
-	assert.Equal(t, true, f.d4m.started)
-	assert.Equal(t, 1, f.dockerClient.ncpu)
-}
+	minikubeAdmin := f.newFakeAdmin(ProductMinikube)
 
-func controllerApply(f *fixture, product Product, cpus int) (*fixture, error) {
 	cluster := &api.Cluster{
-		Product: string(product),
-		MinCPUs: cpus,
+		Product: string(ProductMinikube),
+		Minikube: &api.MinikubeCluster{
+			ContainerRuntime: "docker",
+		},
 	}
 	_, err := f.controller.Apply(context.Background(), cluster)
-	return f, err
-}
+	assert.NoError(t, err)
+	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
+	minikubeAdmin.created = nil
 
-func TestClusterApplyDockerDesktopNoRestart(t *testing.T) {
-	f := newFixture(t)
-	f.dmachine.os = "darwin"
+	// Assert that re-applying the same config doesn't create a new cluster.
+	_, err = f.controller.Apply(context.Background(), cluster)
+	assert.NoError(t, err)
+	assert.Nil(t, minikubeAdmin.created)
+	assert.Nil(t, minikubeAdmin.deleted)
 
-	assert.Equal(t, 0, f.d4m.settingsWriteCount)
+	// Assert that applying a different config deletes and re-creates.
+	cluster2 := &api.Cluster{
+		Product: string(ProductMinikube),
+		Minikube: &api.MinikubeCluster{
+			ContainerRuntime: "containerd",
+		},
+	}
 
-	// Pretend the cluster isn't running.
-	err := f.fakeK8s.Tracker().Delete(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "nodes"}, "", "node-1")
+	f.errOut.Truncate(0)
+	_, err = f.controller.Apply(context.Background(), cluster2)
 	assert.NoError(t, err)
-	f, _ = controllerApply(f, ProductDockerDesktop, 0)
-	assert.Equal(t, 1, f.d4m.settingsWriteCount)
-	f, _ = controllerApply(f, ProductDockerDesktop, 0)
-	assert.Equal(t, 1, f.d4m.settingsWriteCount)
+	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
+	assert.Equal(t, "minikube", minikubeAdmin.deleted.Name)
+	assert.Contains(t, f.errOut.String(), "desired Minikube config does not match current")
 }
 
-func TestClusterApplyMinikubeVersion(t *testing.T) {
+func TestClusterApplyMinikubeMemory(t *testing.T) {
 	f := newFixture(t)
 	f.dmachine.os = "darwin"
 
-	assert.Equal(t, false, f.d4m.started)
 	minikubeAdmin := f.newFakeAdmin(ProductMinikube)
 
-	result, err := f.controller.Apply(context.Background(), &api.Cluster{
-		Product:           string(ProductMinikube),
-		KubernetesVersion: "v1.14.0",
+	_, err := f.controller.Apply(context.Background(), &api.Cluster{
+		Product:  string(ProductMinikube),
+		Minikube: &api.MinikubeCluster{Memory: "2g"},
 	})
 	assert.NoError(t, err)
-	assert.Equal(t, true, f.d4m.started)
 	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
-	assert.Equal(t, "minikube", result.Name)
-	assert.Equal(t, "minikube", f.config.CurrentContext)
-
 	minikubeAdmin.created = nil
 
+	// Assert that changing the memory resizes in place, rather than re-creating.
 	_, err = f.controller.Apply(context.Background(), &api.Cluster{
-		Product:           string(ProductMinikube),
-		KubernetesVersion: "v1.14.0",
+		Product:  string(ProductMinikube),
+		Minikube: &api.MinikubeCluster{Memory: "4g"},
 	})
 	assert.NoError(t, err)
-
-	// Make sure we don't recreate the cluster.
 	assert.Nil(t, minikubeAdmin.created)
-
-	// Now, change the version and make sure we re-create the cluster.
-	out := bytes.NewBuffer(nil)
-	f.controller.iostreams.ErrOut = out
-
-	_, err = f.controller.Apply(context.Background(), &api.Cluster{
-		Product:           string(ProductMinikube),
-		KubernetesVersion: "v1.15.0",
-	})
-	assert.NoError(t, err)
-
-	assert.Equal(t, "minikube", minikubeAdmin.created.Name)
-	assert.Contains(t, out.String(),
-		"Deleting cluster minikube because desired Kubernetes version (v1.15.0) "+
-			"does not match current (v1.14.0)")
-}
-
-func TestFillDefaultsKindConfig(t *testing.T) {
-	c := &api.Cluster{
-		Product: "kind",
-		KindV1Alpha4Cluster: &v1alpha4.Cluster{
-			Name: "my-cluster",
-		},
-	}
-	FillDefaults(c)
-	assert.Equal(t, "kind-my-cluster", c.Name)
-
-	c.KindV1Alpha4Cluster.Name = "your-cluster"
-	FillDefaults(c)
-	assert.Equal(t, "my-cluster", c.KindV1Alpha4Cluster.Name)
+	assert.Nil(t, minikubeAdmin.deleted)
 }
 
-func TestClusterApplyKindConfig(t *testing.T) {
+func TestClusterApplyK3dConfig(t *testing.T) {
 	f := newFixture(t)
-	f.dmachine.os = "darwin"
-
-	assert.Equal(t, false, f.d4m.started)
-	kindAdmin := f.newFakeAdmin(ProductKIND)
+	k3dAdmin := f.newFakeAdmin(ProductK3D)
 
 	cluster := &api.Cluster{
-		Product: string(ProductKIND),
-		KindV1Alpha4Cluster: &v1alpha4.Cluster{
-			Nodes: []v1alpha4.Node{
-				v1alpha4.Node{Role: "control-plane"},
-			},
+		Product: string(ProductK3D),
+		K3DV1Alpha3Simple: &k3dv1alpha3.SimpleConfig{
+			Agents: 1,
 		},
 	}
 	_, err := f.controller.Apply(context.Background(), cluster)
 	assert.NoError(t, err)
-	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
-	kindAdmin.created = nil
+	assert.Equal(t, "k3d-k3s-default", k3dAdmin.created.Name)
+	k3dAdmin.created = nil
 
 	// Assert that re-applying the same config doesn't create a new cluster.
 	_, err = f.controller.Apply(context.Background(), cluster)
 	assert.NoError(t, err)
-	assert.Nil(t, kindAdmin.created)
-	assert.Nil(t, kindAdmin.deleted)
+	assert.Nil(t, k3dAdmin.created)
+	assert.Nil(t, k3dAdmin.deleted)
 
 	// Assert that applying a different config deletes and re-creates.
 	cluster2 := &api.Cluster{
-		Product: string(ProductKIND),
-		KindV1Alpha4Cluster: &v1alpha4.Cluster{
-			Nodes: []v1alpha4.Node{
-				v1alpha4.Node{Role: "control-plane"},
-				v1alpha4.Node{Role: "worker"},
-			},
+		Product: string(ProductK3D),
+		K3DV1Alpha3Simple: &k3dv1alpha3.SimpleConfig{
+			Agents: 2,
 		},
 	}
 
 	f.errOut.Truncate(0)
 	_, err = f.controller.Apply(context.Background(), cluster2)
 	assert.NoError(t, err)
-	assert.Equal(t, "kind-kind", kindAdmin.created.Name)
-	assert.Equal(t, "kind-kind", kindAdmin.deleted.Name)
-	assert.Contains(t, f.errOut.String(), "desired Kind config does not match current")
+	assert.Equal(t, "k3d-k3s-default", k3dAdmin.created.Name)
+	assert.Equal(t, "k3d-k3s-default", k3dAdmin.deleted.Name)
+	assert.Contains(t, f.errOut.String(), "desired K3D config does not match current")
 }
 
 type fixture struct {
@@ -325,6 +1544,10 @@ type fixture struct {
 	config       *clientcmdapi.Config
 	registryCtl  *fakeRegistryController
 	fakeK8s      *fake.Clientset
+	fakeDynamic  *dynamicfake.FakeDynamicClient
+
+	// Clients for clusters that shouldn't share fakeK8s, by server URL.
+	otherK8s map[string]kubernetes.Interface
 }
 
 func newFixture(t *testing.T) *fixture {
@@ -374,9 +1597,20 @@ func newFixture(t *testing.T) *fixture {
 		},
 	}
 	fakeK8s := fake.NewSimpleClientset(node, ns)
+	otherK8s := make(map[string]kubernetes.Interface)
 	clientLoader := clientLoader(func(restConfig *rest.Config) (kubernetes.Interface, error) {
+		client, ok := otherK8s[restConfig.Host]
+		if ok {
+			return client, nil
+		}
 		return fakeK8s, nil
 	})
+	fakeDynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ingressClassesGVR: "IngressClassList"})
+	fakeDynamic.PrependReactor("create", "*", markWorkloadReady)
+	dynamicClientLoader := dynamicClientLoader(func(restConfig *rest.Config) (dynamic.Interface, error) {
+		return fakeDynamic, nil
+	})
 
 	registryCtl := &fakeRegistryController{}
 	controller := &Controller{
@@ -384,13 +1618,20 @@ func newFixture(t *testing.T) *fixture {
 		admins:                      make(map[Product]Admin),
 		config:                      *config,
 		configWriter:                configWriter,
+		dockerClient:                dockerClient,
 		dmachine:                    dmachine,
 		configLoader:                configLoader,
 		clientLoader:                clientLoader,
 		clients:                     make(map[string]kubernetes.Interface),
+		dynamicClientLoader:         dynamicClientLoader,
+		dynamicClients:              make(map[string]dynamic.Interface),
 		registryCtl:                 registryCtl,
 		waitForKubeConfigTimeout:    time.Millisecond,
 		waitForClusterCreateTimeout: time.Millisecond,
+		waitForReadyTimeout:         time.Millisecond,
+
+		// Most tests recreate clusters as if the user passed --force.
+		confirmRecreate: AlwaysRecreate,
 	}
 	return &fixture{
 		t:            t,
@@ -402,7 +1643,29 @@ func newFixture(t *testing.T) *fixture {
 		config:       config,
 		registryCtl:  registryCtl,
 		fakeK8s:      fakeK8s,
+		fakeDynamic:  fakeDynamic,
+		otherK8s:     otherK8s,
+	}
+}
+
+// Pretend that workloads and CRDs become ready as soon as they're created.
+func markWorkloadReady(action k8stesting.Action) (bool, runtime.Object, error) {
+	obj, ok := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+	if !ok {
+		return false, nil, nil
 	}
+	switch obj.GetKind() {
+	case "Deployment":
+		_ = unstructured.SetNestedField(obj.Object, int64(1), "status", "availableReplicas")
+	case "DaemonSet":
+		_ = unstructured.SetNestedField(obj.Object, int64(1), "status", "desiredNumberScheduled")
+		_ = unstructured.SetNestedField(obj.Object, int64(1), "status", "numberReady")
+	case "CustomResourceDefinition":
+		_ = unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}, "status", "conditions")
+	}
+	return false, nil, nil
 }
 
 func (f *fixture) newFakeAdmin(p Product) *fakeAdmin {
@@ -419,12 +1682,26 @@ type fakeDockerClient struct {
 	isRemoteHost bool
 	started      bool
 	ncpu         int
+	memTotal     int64
+	engine       docker.Engine
+
+	mu     sync.Mutex
+	images map[string]bool
+	pulled []string
+	pushed []string
 }
 
 func (c *fakeDockerClient) IsLocalHost() bool {
 	return !c.isRemoteHost
 }
 
+func (c *fakeDockerClient) Engine() docker.Engine {
+	if c.engine == "" {
+		return docker.EngineDocker
+	}
+	return c.engine
+}
+
 func (c *fakeDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
 	if !c.started {
 		return types.Version{}, fmt.Errorf("not started")
@@ -438,7 +1715,7 @@ func (c *fakeDockerClient) Info(ctx context.Context) (types.Info, error) {
 		return types.Info{}, fmt.Errorf("not started")
 	}
 
-	return types.Info{NCPU: c.ncpu}, nil
+	return types.Info{NCPU: c.ncpu, MemTotal: c.memTotal}, nil
 }
 
 func (c *fakeDockerClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
@@ -449,6 +1726,43 @@ func (d *fakeDockerClient) ContainerRemove(ctx context.Context, id string, optio
 	return nil
 }
 
+func (c *fakeDockerClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.images[image] {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("No such image: %s", image))
+	}
+	return types.ImageInspect{ID: image}, nil, nil
+}
+
+func (c *fakeDockerClient) ImagePull(ctx context.Context, image string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.images == nil {
+		c.images = make(map[string]bool)
+	}
+	c.images[image] = true
+	c.pulled = append(c.pulled, image)
+	return ioutil.NopCloser(strings.NewReader(`{"status":"Pulling"}`)), nil
+}
+
+func (c *fakeDockerClient) ImageTag(ctx context.Context, source, target string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.images[source] {
+		return errdefs.NotFound(fmt.Errorf("No such image: %s", source))
+	}
+	c.images[target] = true
+	return nil
+}
+
+func (c *fakeDockerClient) ImagePush(ctx context.Context, image string, options types.ImagePushOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pushed = append(c.pushed, image)
+	return ioutil.NopCloser(strings.NewReader(`{"status":"Pushed"}`)), nil
+}
+
 type fakeD4MClient struct {
 	lastSettings       map[string]interface{}
 	docker             *fakeDockerClient
@@ -460,6 +1774,10 @@ type fakeD4MClient struct {
 func (c *fakeD4MClient) writeSettings(ctx context.Context, settings map[string]interface{}) error {
 	c.lastSettings = settings
 	c.docker.ncpu = settings["cpu"].(int)
+	memoryMiB, ok := settings["memory"]
+	if ok {
+		c.docker.memTotal = int64(memoryMiB.(int)) * mebibyte
+	}
 	c.settingsWriteCount++
 	return nil
 }
@@ -487,6 +1805,40 @@ func (c *fakeD4MClient) ensureMinCPU(settings map[string]interface{}, desired in
 
 }
 
+func (c *fakeD4MClient) ensureMinMemory(settings map[string]interface{}, desiredMiB int) (bool, error) {
+	memory, ok := settings["memory"]
+	if ok && memory.(int) >= desiredMiB {
+		return false, nil
+	}
+	settings["memory"] = desiredMiB
+	return true, nil
+}
+
+func (c *fakeD4MClient) ensureMinDisk(settings map[string]interface{}, desiredMiB int) (bool, error) {
+	disk, ok := settings["disk"]
+	if ok && disk.(int) >= desiredMiB {
+		return false, nil
+	}
+	settings["disk"] = desiredMiB
+	return true, nil
+}
+
+func (c *fakeD4MClient) memoryMiB(settings map[string]interface{}) (int, error) {
+	memory, ok := settings["memory"]
+	if !ok {
+		return int(c.docker.memTotal / mebibyte), nil
+	}
+	return memory.(int), nil
+}
+
+func (c *fakeD4MClient) diskSizeMiB(settings map[string]interface{}) (int, error) {
+	disk, ok := settings["disk"]
+	if !ok {
+		return 0, nil
+	}
+	return disk.(int), nil
+}
+
 func (c *fakeD4MClient) ResetCluster(ctx context.Context) error {
 	c.resetCount++
 	return nil
@@ -505,8 +1857,18 @@ type fakeAdmin struct {
 	created         *api.Cluster
 	createdRegistry *api.Registry
 	deleted         *api.Cluster
+	restored        *api.Registry
 	config          *clientcmdapi.Config
 	fakeK8s         *fake.Clientset
+
+	mirrors []registryMirror
+
+	attached *api.Registry
+	detached *api.Registry
+	upgraded *api.Cluster
+
+	mu     sync.Mutex
+	loaded []string
 }
 
 func newFakeAdmin(config *clientcmdapi.Config, fakeK8s *fake.Clientset) *fakeAdmin {
@@ -531,6 +1893,11 @@ func (a *fakeAdmin) Create(ctx context.Context, config *api.Cluster, registry *a
 	return nil
 }
 
+func (a *fakeAdmin) CreateWithMirrors(ctx context.Context, config *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
+	a.mirrors = mirrors
+	return a.Create(ctx, config, registry)
+}
+
 func (a *fakeAdmin) LocalRegistryHosting(ctx context.Context, cluster *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
 	return &localregistry.LocalRegistryHostingV1{
 		Host: fmt.Sprintf("localhost:%d", registry.Status.HostPort),
@@ -538,6 +1905,37 @@ func (a *fakeAdmin) LocalRegistryHosting(ctx context.Context, cluster *api.Clust
 	}, nil
 }
 
+func (a *fakeAdmin) Upgrade(ctx context.Context, desired, existing *api.Cluster) error {
+	a.upgraded = desired.DeepCopy()
+	a.fakeK8s.Discovery().(*discoveryfake.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: desired.KubernetesVersion,
+	}
+	return nil
+}
+
+func (a *fakeAdmin) AttachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	a.attached = registry.DeepCopy()
+	return nil
+}
+
+func (a *fakeAdmin) RestoreRegistryConfig(ctx context.Context, cluster *api.Cluster, registry *api.Registry, mirrors []registryMirror) error {
+	a.restored = registry.DeepCopy()
+	a.mirrors = mirrors
+	return nil
+}
+
+func (a *fakeAdmin) DetachRegistry(ctx context.Context, cluster *api.Cluster, registry *api.Registry) error {
+	a.detached = registry.DeepCopy()
+	return nil
+}
+
+func (a *fakeAdmin) LoadImage(ctx context.Context, cluster *api.Cluster, image string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.loaded = append(a.loaded, image)
+	return nil
+}
+
 func (a *fakeAdmin) Delete(ctx context.Context, config *api.Cluster) error {
 	a.deleted = config.DeepCopy()
 	delete(a.config.Contexts, config.Name)
@@ -552,6 +1950,7 @@ func (c *fakeRegistryController) List(ctx context.Context, options registry.List
 	list := &api.RegistryList{}
 	if c.lastApply != nil {
 		item := c.lastApply.DeepCopy()
+		item.Status.State = "running"
 		list.Items = append(list.Items, *item)
 	}
 	return list, nil
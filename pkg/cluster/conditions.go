@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func conditionTrue(conditionType, reason string) *api.Condition {
+	return &api.Condition{Type: conditionType, Status: metav1.ConditionTrue, Reason: reason}
+}
+
+func conditionFalse(conditionType, reason string, err error) *api.Condition {
+	return &api.Condition{Type: conditionType, Status: metav1.ConditionFalse, Reason: reason, Message: err.Error()}
+}
+
+func conditionUnknown(conditionType, reason, message string) *api.Condition {
+	return &api.Condition{Type: conditionType, Status: metav1.ConditionUnknown, Reason: reason, Message: message}
+}
+
+// Whether the registry that the cluster advertises is up.
+//
+// Returns nil if the cluster doesn't advertise a registry,
+// or advertises one that ctlptl doesn't manage.
+func registryConnectedCondition(hosting string, reg *api.Registry, err error) *api.Condition {
+	t := api.ClusterConditionRegistryConnected
+	if err != nil {
+		return conditionFalse(t, "RegistryDiscoveryFailed", err)
+	}
+	if reg != nil {
+		if reg.Status.State != "running" {
+			return conditionFalse(t, "RegistryNotRunning",
+				fmt.Errorf("registry %s is %s", reg.Name, reg.Status.State))
+		}
+		return conditionTrue(t, "Connected")
+	}
+
+	var port int
+	_, err = fmt.Sscanf(hosting, "localhost:%d", &port)
+	if err != nil || port == 0 {
+		return nil
+	}
+	return conditionFalse(t, "RegistryNotFound", fmt.Errorf("no registry is listening on %s", hosting))
+}
+
+// Adds the conditions in order, skipping the ones we don't know about.
+func appendConditions(conditions []api.Condition, toAdd ...*api.Condition) []api.Condition {
+	for _, c := range toAdd {
+		if c != nil {
+			conditions = append(conditions, *c)
+		}
+	}
+	return conditions
+}
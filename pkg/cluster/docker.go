@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"context"
+	"io"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -10,29 +11,35 @@ import (
 
 type dockerClient interface {
 	IsLocalHost() bool
+	Engine() docker.Engine
 	ServerVersion(ctx context.Context) (types.Version, error)
 	Info(ctx context.Context) (types.Info, error)
 	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
 	ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error
+	ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ImageTag(ctx context.Context, image, ref string) error
+	ImagePush(ctx context.Context, ref string, options types.ImagePushOptions) (io.ReadCloser, error)
 }
 
 type dockerWrapper struct {
 	*client.Client
 	isLocalHost bool
+	engine      docker.Engine
 }
 
-func (w *dockerWrapper) IsLocalHost() bool { return w.isLocalHost }
+func (w *dockerWrapper) IsLocalHost() bool     { return w.isLocalHost }
+func (w *dockerWrapper) Engine() docker.Engine { return w.engine }
 
-func newDockerWrapperFromEnv(ctx context.Context) (*dockerWrapper, error) {
-	c, err := client.NewClientWithOpts(client.FromEnv)
+func newDockerWrapper(ctx context.Context, engine docker.Engine) (*dockerWrapper, error) {
+	c, err := docker.NewClient(ctx, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	c.NegotiateAPIVersion(ctx)
-	isLocalHost := docker.IsLocalHost(docker.GetHostEnv())
 	return &dockerWrapper{
 		Client:      c,
-		isLocalHost: isLocalHost,
+		isLocalHost: engine.IsLocalHost(),
+		engine:      engine,
 	}, nil
 }
@@ -299,31 +299,77 @@ func (c DockerDesktopClient) setK8sEnabled(settings map[string]interface{}, newV
 }
 
 func (c DockerDesktopClient) ensureMinCPU(settings map[string]interface{}, desired int) (changed bool, err error) {
-	cpusSetting, err := c.lookupMapAt(settings, "vm.resources.cpus")
+	return c.ensureMinResource(settings, "cpus", "cpus", desired)
+}
+
+func (c DockerDesktopClient) ensureMinMemory(settings map[string]interface{}, desiredMiB int) (changed bool, err error) {
+	return c.ensureMinResource(settings, "memoryMiB", "memory (MiB)", desiredMiB)
+}
+
+func (c DockerDesktopClient) ensureMinDisk(settings map[string]interface{}, desiredMiB int) (changed bool, err error) {
+	return c.ensureMinResource(settings, "diskSizeMiB", "disk (MiB)", desiredMiB)
+}
+
+func (c DockerDesktopClient) memoryMiB(settings map[string]interface{}) (int, error) {
+	return c.resourceValue(settings, "memoryMiB")
+}
+
+func (c DockerDesktopClient) diskSizeMiB(settings map[string]interface{}) (int, error) {
+	return c.resourceValue(settings, "diskSizeMiB")
+}
+
+// Reads the setting at vm.resources.<key>.
+func (c DockerDesktopClient) resourceValue(settings map[string]interface{}, key string) (int, error) {
+	path := fmt.Sprintf("vm.resources.%s", key)
+	setting, err := c.lookupMapAt(settings, path)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
-	value, ok := cpusSetting["value"].(float64)
+	value, ok := setting["value"].(float64)
 	if !ok {
-		return false, fmt.Errorf("expected number at DockerDesktop setting vm.resources.cpus.value, got: %T",
-			cpusSetting["value"])
+		return 0, fmt.Errorf("expected number at DockerDesktop setting %s.value, got: %T",
+			path, setting["value"])
 	}
-	max, ok := cpusSetting["max"].(float64)
+	return int(value), nil
+}
+
+// Raises the setting at vm.resources.<key> to at least the desired value.
+//
+// Some resources (like disk) don't have a max.
+func (c DockerDesktopClient) ensureMinResource(settings map[string]interface{}, key, name string, desired int) (changed bool, err error) {
+	path := fmt.Sprintf("vm.resources.%s", key)
+	setting, err := c.lookupMapAt(settings, path)
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := setting["value"].(float64)
 	if !ok {
-		return false, fmt.Errorf("expected number at DockerDesktop setting vm.resources.cpus.max, got: %T",
-			cpusSetting["max"])
+		return false, fmt.Errorf("expected number at DockerDesktop setting %s.value, got: %T",
+			path, setting["value"])
 	}
 
-	if desired > int(max) {
-		return false, fmt.Errorf("desired cpus (%d) greater than max allowed (%d)", desired, int(max))
+	maxVal, hasMax := setting["max"]
+	if hasMax {
+		max, ok := maxVal.(float64)
+		if !ok {
+			return false, fmt.Errorf("expected number at DockerDesktop setting %s.max, got: %T",
+				path, maxVal)
+		}
+
+		if desired > int(max) {
+			return false, fmt.Errorf("desired %s (%d) greater than max allowed (%d)", name, desired, int(max))
+		}
 	}
 
 	if desired <= int(value) {
 		return false, nil
 	}
 
-	cpusSetting["value"] = desired
+	// Settings decoded from JSON hold numbers as float64,
+	// so store it the same way for the next read.
+	setting["value"] = float64(desired)
 	return true, nil
 }
 
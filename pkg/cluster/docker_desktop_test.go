@@ -114,6 +114,74 @@ func TestMaxCPUs(t *testing.T) {
 	}
 }
 
+func TestMinMemory(t *testing.T) {
+	f := newD4MFixture(t)
+	defer f.TearDown()
+
+	ctx := context.Background()
+	settings, err := f.d4m.settings(ctx)
+	require.NoError(t, err)
+
+	memory, err := f.d4m.memoryMiB(settings)
+	require.NoError(t, err)
+	assert.Equal(t, 8192, memory)
+
+	changed, err := f.d4m.ensureMinMemory(settings, 12288)
+	assert.True(t, changed)
+	require.NoError(t, err)
+
+	memory, err = f.d4m.memoryMiB(settings)
+	require.NoError(t, err)
+	assert.Equal(t, 12288, memory)
+
+	err = f.d4m.writeSettings(ctx, settings)
+	require.NoError(t, err)
+
+	expected := strings.Replace(postSettingsJSON,
+		`"memoryMiB":8192`,
+		`"memoryMiB":12288`, 1)
+	assert.Equal(t,
+		f.postSettings,
+		f.readerToMap(strings.NewReader(expected)))
+}
+
+func TestMaxMemory(t *testing.T) {
+	f := newD4MFixture(t)
+	defer f.TearDown()
+
+	ctx := context.Background()
+	settings, err := f.d4m.settings(ctx)
+	require.NoError(t, err)
+
+	changed, err := f.d4m.ensureMinMemory(settings, 32768)
+	assert.False(t, changed)
+	if assert.Error(t, err) {
+		assert.Equal(t, err.Error(), "desired memory (MiB) (32768) greater than max allowed (16384)")
+	}
+}
+
+func TestMinDisk(t *testing.T) {
+	f := newD4MFixture(t)
+	defer f.TearDown()
+
+	ctx := context.Background()
+	settings, err := f.d4m.settings(ctx)
+	require.NoError(t, err)
+
+	size, err := f.d4m.diskSizeMiB(settings)
+	require.NoError(t, err)
+	assert.Equal(t, 61035, size)
+
+	// Disk has no max.
+	changed, err := f.d4m.ensureMinDisk(settings, 102400)
+	assert.True(t, changed)
+	require.NoError(t, err)
+
+	changed, err = f.d4m.ensureMinDisk(settings, 1024)
+	assert.False(t, changed)
+	require.NoError(t, err)
+}
+
 func TestLookupMap(t *testing.T) {
 	f := newD4MFixture(t)
 	defer f.TearDown()
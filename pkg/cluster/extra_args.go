@@ -0,0 +1,144 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func supportsExtraArgs(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube
+}
+
+func apiServerExtraArgs(cluster *api.Cluster) map[string]string {
+	if cluster.APIServer == nil {
+		return nil
+	}
+	return cluster.APIServer.ExtraArgs
+}
+
+func kubeletExtraArgs(cluster *api.Cluster) map[string]string {
+	if cluster.Kubelet == nil {
+		return nil
+	}
+	return cluster.Kubelet.ExtraArgs
+}
+
+func validateExtraArgs(desired *api.Cluster) error {
+	apiServerArgs := apiServerExtraArgs(desired)
+	kubeletArgs := kubeletExtraArgs(desired)
+	if len(desired.FeatureGates) == 0 && len(apiServerArgs) == 0 && len(kubeletArgs) == 0 {
+		return nil
+	}
+
+	if !supportsExtraArgs(Product(desired.Product)) {
+		return fmt.Errorf("product %s does not support featureGates or extraArgs", desired.Product)
+	}
+
+	for gate := range desired.FeatureGates {
+		if gate == "" || strings.ContainsAny(gate, "=, ") {
+			return fmt.Errorf("featureGates: invalid feature gate %q", gate)
+		}
+	}
+
+	for component, args := range map[string]map[string]string{
+		"apiServer": apiServerArgs,
+		"kubelet":   kubeletArgs,
+	} {
+		for flag := range args {
+			if flag == "" || strings.ContainsAny(flag, "= ") {
+				return fmt.Errorf("%s.extraArgs: invalid flag %q", component, flag)
+			}
+			if strings.HasPrefix(flag, "-") {
+				return fmt.Errorf("%s.extraArgs: flag %q must not start with dashes", component, flag)
+			}
+		}
+	}
+	return nil
+}
+
+// The feature gates as a flag value, e.g., A=true,B=false
+func featureGatesFlag(featureGates map[string]bool) string {
+	gates := make([]string, 0, len(featureGates))
+	for gate, enabled := range featureGates {
+		gates = append(gates, fmt.Sprintf("%s=%t", gate, enabled))
+	}
+	sort.Strings(gates)
+	return strings.Join(gates, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Kubeadm patches that add the extra args to the API server and kubelet.
+//
+// Kind merges each patch into the kubeadm config with the same kind.
+func kubeadmExtraArgsPatches(cluster *api.Cluster) []string {
+	var patches []string
+	if args := apiServerExtraArgs(cluster); len(args) > 0 {
+		patches = append(patches, kubeadmPatch("ClusterConfiguration", "apiServer", "extraArgs", args))
+	}
+	if args := kubeletExtraArgs(cluster); len(args) > 0 {
+		// The control plane reads the kubelet args from the InitConfiguration,
+		// and the workers read them from the JoinConfiguration.
+		patches = append(patches,
+			kubeadmPatch("InitConfiguration", "nodeRegistration", "kubeletExtraArgs", args),
+			kubeadmPatch("JoinConfiguration", "nodeRegistration", "kubeletExtraArgs", args))
+	}
+	return patches
+}
+
+func kubeadmPatch(kind, section, field string, args map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "kind: %s\n%s:\n  %s:\n", kind, section, field)
+	for _, flag := range sortedKeys(args) {
+		fmt.Fprintf(&b, "    %q: %q\n", flag, args[flag])
+	}
+	return b.String()
+}
+
+// Minikube's --extra-config flag values for the API server and kubelet.
+func minikubeExtraConfigs(cluster *api.Cluster) []string {
+	var result []string
+	apiServerArgs := apiServerExtraArgs(cluster)
+	for _, flag := range sortedKeys(apiServerArgs) {
+		result = append(result, fmt.Sprintf("apiserver.%s=%s", flag, apiServerArgs[flag]))
+	}
+	kubeletArgs := kubeletExtraArgs(cluster)
+	for _, flag := range sortedKeys(kubeletArgs) {
+		result = append(result, fmt.Sprintf("kubelet.%s=%s", flag, kubeletArgs[flag]))
+	}
+	return result
+}
+
+// The feature gates and extra args, for comparing clusters.
+type extraArgs struct {
+	FeatureGates map[string]bool
+	APIServer    map[string]string
+	Kubelet      map[string]string
+}
+
+func extraArgsConfig(cluster *api.Cluster) extraArgs {
+	return extraArgs{
+		FeatureGates: cluster.FeatureGates,
+		APIServer:    apiServerExtraArgs(cluster),
+		Kubelet:      kubeletExtraArgs(cluster),
+	}
+}
+
+// Feature gates and extra args are only read when the cluster starts,
+// so a cluster that doesn't match needs to be recreated.
+func extraArgsMatch(desired, existing *api.Cluster) bool {
+	return cmp.Equal(extraArgsConfig(existing), extraArgsConfig(desired), cmpopts.EquateEmpty())
+}
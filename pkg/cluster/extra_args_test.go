@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func TestValidateExtraArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		cluster *api.Cluster
+		err     string
+	}{
+		{"empty", &api.Cluster{Product: "docker-desktop"}, ""},
+		{"valid", &api.Cluster{
+			Product:      "kind",
+			FeatureGates: map[string]bool{"EphemeralContainers": true},
+			APIServer:    &api.ClusterComponent{ExtraArgs: map[string]string{"v": "4"}},
+		}, ""},
+		{"product", &api.Cluster{
+			Product:      "k3d",
+			FeatureGates: map[string]bool{"EphemeralContainers": true},
+		}, "product k3d does not support featureGates or extraArgs"},
+		{"gate", &api.Cluster{
+			Product:      "minikube",
+			FeatureGates: map[string]bool{"A=true": true},
+		}, `featureGates: invalid feature gate "A=true"`},
+		{"dashes", &api.Cluster{
+			Product: "minikube",
+			Kubelet: &api.ClusterComponent{ExtraArgs: map[string]string{"--max-pods": "200"}},
+		}, `kubelet.extraArgs: flag "--max-pods" must not start with dashes`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateExtraArgs(tc.cluster)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestFeatureGatesFlag(t *testing.T) {
+	assert.Equal(t, "EphemeralContainers=true,ServiceTopology=false",
+		featureGatesFlag(map[string]bool{"ServiceTopology": false, "EphemeralContainers": true}))
+}
+
+func TestKubeadmExtraArgsPatches(t *testing.T) {
+	patches := kubeadmExtraArgsPatches(&api.Cluster{
+		APIServer: &api.ClusterComponent{ExtraArgs: map[string]string{
+			"v":              "4",
+			"audit-log-path": "/var/log/audit.log",
+		}},
+		Kubelet: &api.ClusterComponent{ExtraArgs: map[string]string{"max-pods": "200"}},
+	})
+	assert.Equal(t, []string{
+		`kind: ClusterConfiguration
+apiServer:
+  extraArgs:
+    "audit-log-path": "/var/log/audit.log"
+    "v": "4"
+`,
+		`kind: InitConfiguration
+nodeRegistration:
+  kubeletExtraArgs:
+    "max-pods": "200"
+`,
+		`kind: JoinConfiguration
+nodeRegistration:
+  kubeletExtraArgs:
+    "max-pods": "200"
+`,
+	}, patches)
+
+	assert.Empty(t, kubeadmExtraArgsPatches(&api.Cluster{}))
+}
+
+func TestExtraArgsMatch(t *testing.T) {
+	gates := &api.Cluster{FeatureGates: map[string]bool{"EphemeralContainers": true}}
+	assert.True(t, extraArgsMatch(&api.Cluster{}, &api.Cluster{APIServer: &api.ClusterComponent{}}))
+	assert.True(t, extraArgsMatch(gates, gates.DeepCopy()))
+	assert.False(t, extraArgsMatch(gates, &api.Cluster{}))
+	assert.False(t, extraArgsMatch(&api.Cluster{}, gates))
+	assert.False(t, extraArgsMatch(
+		&api.Cluster{Kubelet: &api.ClusterComponent{ExtraArgs: map[string]string{"max-pods": "200"}}},
+		&api.Cluster{Kubelet: &api.ClusterComponent{ExtraArgs: map[string]string{"max-pods": "100"}}}))
+}
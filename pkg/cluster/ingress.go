@@ -0,0 +1,286 @@
+package cluster
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+//go:embed ingress/*.yaml
+var ingressManifests embed.FS
+
+// The node label that the ingress proxy is pinned to on kind.
+//
+// This matches the convention in the kind docs:
+// https://kind.sigs.k8s.io/docs/user/ingress/
+const ingressReadyLabel = "ingress-ready"
+
+type ingressController struct {
+	// The value of spec.controller on the controller's IngressClass.
+	className string
+
+	// The workload that receives traffic from outside the cluster.
+	proxyKind string
+	proxyName string
+}
+
+var ingressControllers = map[string]ingressController{
+	"nginx": {
+		className: "k8s.io/ingress-nginx",
+		proxyKind: "Deployment",
+		proxyName: "ingress-nginx-controller",
+	},
+	"contour": {
+		className: "projectcontour.io/ingress-controller",
+		proxyKind: "DaemonSet",
+		proxyName: "envoy",
+	},
+	"traefik": {
+		className: "traefik.io/ingress-controller",
+		proxyKind: "Deployment",
+		proxyName: "traefik",
+	},
+}
+
+var ingressClassesGVR = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingressclasses"}
+
+func supportsIngress(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D
+}
+
+func ingressControllerNames() []string {
+	names := make([]string, 0, len(ingressControllers))
+	for name := range ingressControllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validateIngress(desired *api.Cluster) error {
+	if desired.Ingress == nil {
+		return nil
+	}
+
+	if !supportsIngress(Product(desired.Product)) {
+		return fmt.Errorf("product %s does not support ingress", desired.Product)
+	}
+
+	_, ok := ingressControllers[desired.Ingress.Controller]
+	if !ok {
+		return fmt.Errorf("ingress: unknown controller %q. Must be one of: %s",
+			desired.Ingress.Controller, strings.Join(ingressControllerNames(), ", "))
+	}
+	return nil
+}
+
+// Reads the bundled manifest for the controller, adjusted for the product.
+func ingressObjects(name string, product Product) ([]*unstructured.Unstructured, error) {
+	controller, ok := ingressControllers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ingress controller %q", name)
+	}
+
+	data, err := ingressManifests.ReadFile(fmt.Sprintf("ingress/%s.yaml", name))
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := decodeManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s ingress manifest: %v", name, err)
+	}
+
+	for _, obj := range objs {
+		if obj.GetKind() == controller.proxyKind && obj.GetName() == controller.proxyName {
+			err := exposeIngressProxy(obj, product)
+			if err != nil {
+				return nil, fmt.Errorf("configuring %s ingress for %s: %v", name, product, err)
+			}
+		}
+	}
+	return objs, nil
+}
+
+// The bundled manifests expose the proxy with a LoadBalancer service,
+// which is enough on k3d.
+//
+// kind and minikube don't have load balancers, so we bind the proxy's
+// ports on the node, where portMappings can reach them.
+func exposeIngressProxy(obj *unstructured.Unstructured, product Product) error {
+	if product != ProductKIND && product != ProductMinikube {
+		return nil
+	}
+
+	podSpec := []string{"spec", "template", "spec"}
+	containers, _, err := unstructured.NestedSlice(obj.Object, append(podSpec, "containers")...)
+	if err != nil {
+		return err
+	}
+
+	hostPorts := map[string]int64{"http": 80, "https": 443}
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ports, _, err := unstructured.NestedSlice(container, "ports")
+		if err != nil {
+			return err
+		}
+		for _, p := range ports {
+			port, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := port["name"].(string)
+			hostPort, ok := hostPorts[name]
+			if ok {
+				port["hostPort"] = hostPort
+			}
+		}
+		err = unstructured.SetNestedSlice(container, ports, "ports")
+		if err != nil {
+			return err
+		}
+	}
+	err = unstructured.SetNestedSlice(obj.Object, containers, append(podSpec, "containers")...)
+	if err != nil {
+		return err
+	}
+
+	if product != ProductKIND {
+		return nil
+	}
+
+	// On kind, the port mappings are on the control plane node,
+	// so that's where the proxy needs to run.
+	nodeSelector, _, err := unstructured.NestedStringMap(obj.Object, append(podSpec, "nodeSelector")...)
+	if err != nil {
+		return err
+	}
+	if nodeSelector == nil {
+		nodeSelector = make(map[string]string)
+	}
+	nodeSelector[ingressReadyLabel] = "true"
+	err = unstructured.SetNestedStringMap(obj.Object, nodeSelector, append(podSpec, "nodeSelector")...)
+	if err != nil {
+		return err
+	}
+
+	tolerations, _, err := unstructured.NestedSlice(obj.Object, append(podSpec, "tolerations")...)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"} {
+		tolerations = append(tolerations, map[string]interface{}{
+			"key":      key,
+			"operator": "Exists",
+			"effect":   "NoSchedule",
+		})
+	}
+	return unstructured.SetNestedSlice(obj.Object, tolerations, append(podSpec, "tolerations")...)
+}
+
+// Labels the kind control plane nodes, which have the port mappings,
+// so that the ingress proxy runs there.
+func labelIngressNodes(ctx context.Context, client kubernetes.Interface) error {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, ingressReadyLabel))
+	labeled := 0
+	for _, node := range nodes.Items {
+		_, isControlPlane := node.Labels["node-role.kubernetes.io/control-plane"]
+		_, isMaster := node.Labels["node-role.kubernetes.io/master"]
+		if !isControlPlane && !isMaster {
+			continue
+		}
+
+		_, err := client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("labeling node %s: %v", node.Name, err)
+		}
+		labeled++
+	}
+
+	if labeled == 0 {
+		return fmt.Errorf("no control plane nodes to run the ingress controller on")
+	}
+	return nil
+}
+
+// Installs the ingress controller from the bundled manifests,
+// and waits for it to become ready.
+func (c *Controller) installIngress(ctx context.Context, cluster *api.Cluster) error {
+	name := cluster.Ingress.Controller
+	product := Product(cluster.Product)
+	objs, err := ingressObjects(name, product)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(c.iostreams.ErrOut, " 🚦 Installing %s ingress controller on cluster %s\n", name, cluster.Name)
+
+	if product == ProductKIND {
+		client, err := c.client(cluster.Name)
+		if err != nil {
+			return err
+		}
+		err = labelIngressNodes(ctx, client)
+		if err != nil {
+			return err
+		}
+	}
+
+	dclient, err := c.dynamicClient(cluster.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		err := applyUnstructured(ctx, dclient, builtinRESTMapper, obj)
+		if err != nil {
+			return fmt.Errorf("applying %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+
+	return c.waitForObjectsReady(ctx, dclient, builtinRESTMapper, objs, fmt.Sprintf("%s ingress controller", name))
+}
+
+// Reports the ingress controller by looking for its IngressClass.
+func (c *Controller) populateIngressStatus(ctx context.Context, cluster *api.Cluster, dclient dynamic.Interface) error {
+	classes, err := dclient.Resource(ingressClassesGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Clusters before Kubernetes 1.19 don't have IngressClasses.
+			return nil
+		}
+		return err
+	}
+
+	for _, class := range classes.Items {
+		controllerName, _, _ := unstructured.NestedString(class.Object, "spec", "controller")
+		for _, name := range ingressControllerNames() {
+			if ingressControllers[name].className == controllerName {
+				cluster.Status.IngressController = name
+				return nil
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIngressManifests(t *testing.T) {
+	for _, name := range ingressControllerNames() {
+		t.Run(name, func(t *testing.T) {
+			objs, err := ingressObjects(name, ProductK3D)
+			require.NoError(t, err)
+
+			foundProxy := false
+			foundClass := false
+			for _, obj := range objs {
+				gvk := obj.GroupVersionKind()
+				_, err := builtinRESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+				assert.NoError(t, err)
+
+				controller := ingressControllers[name]
+				if obj.GetKind() == controller.proxyKind && obj.GetName() == controller.proxyName {
+					foundProxy = true
+				}
+				if obj.GetKind() == "IngressClass" {
+					className, _, _ := unstructured.NestedString(obj.Object, "spec", "controller")
+					foundClass = className == controller.className
+				}
+			}
+			assert.True(t, foundProxy, "proxy workload")
+			assert.True(t, foundClass, "ingress class")
+		})
+	}
+}
+
+func proxyPodSpec(t *testing.T, name string, product Product) map[string]interface{} {
+	objs, err := ingressObjects(name, product)
+	require.NoError(t, err)
+
+	controller := ingressControllers[name]
+	for _, obj := range objs {
+		if obj.GetKind() == controller.proxyKind && obj.GetName() == controller.proxyName {
+			podSpec, _, err := unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+			require.NoError(t, err)
+			return podSpec
+		}
+	}
+	t.Fatalf("no proxy workload for %s", name)
+	return nil
+}
+
+func proxyHostPorts(t *testing.T, podSpec map[string]interface{}) []int64 {
+	var result []int64
+	containers, _, _ := unstructured.NestedSlice(podSpec, "containers")
+	for _, c := range containers {
+		ports, _, _ := unstructured.NestedSlice(c.(map[string]interface{}), "ports")
+		for _, p := range ports {
+			hostPort, ok, _ := unstructured.NestedInt64(p.(map[string]interface{}), "hostPort")
+			if ok {
+				result = append(result, hostPort)
+			}
+		}
+	}
+	return result
+}
+
+func TestIngressObjectsKind(t *testing.T) {
+	podSpec := proxyPodSpec(t, "contour", ProductKIND)
+	assert.Equal(t, []int64{80, 443}, proxyHostPorts(t, podSpec))
+
+	nodeSelector, _, _ := unstructured.NestedStringMap(podSpec, "nodeSelector")
+	assert.Equal(t, map[string]string{"ingress-ready": "true"}, nodeSelector)
+
+	tolerations, _, _ := unstructured.NestedSlice(podSpec, "tolerations")
+	assert.Len(t, tolerations, 2)
+}
+
+func TestIngressObjectsKindKeepsNodeSelector(t *testing.T) {
+	podSpec := proxyPodSpec(t, "nginx", ProductKIND)
+	nodeSelector, _, _ := unstructured.NestedStringMap(podSpec, "nodeSelector")
+	assert.Equal(t, map[string]string{"ingress-ready": "true", "kubernetes.io/os": "linux"}, nodeSelector)
+}
+
+func TestIngressObjectsMinikube(t *testing.T) {
+	podSpec := proxyPodSpec(t, "traefik", ProductMinikube)
+	assert.Equal(t, []int64{80, 443}, proxyHostPorts(t, podSpec))
+
+	_, hasNodeSelector := podSpec["nodeSelector"]
+	assert.False(t, hasNodeSelector)
+}
+
+func TestIngressObjectsK3d(t *testing.T) {
+	podSpec := proxyPodSpec(t, "traefik", ProductK3D)
+	assert.Empty(t, proxyHostPorts(t, podSpec))
+}
@@ -0,0 +1,309 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	kindcluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	kindversion "sigs.k8s.io/kind/pkg/cmd/kind/version"
+	kindlog "sigs.k8s.io/kind/pkg/log"
+
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+)
+
+// The operations ctlptl needs from kind.
+//
+// Cluster names here are kind names, without the kind- context prefix.
+type kindClient interface {
+	// The kind version, with a v prefix (e.g., v0.9.0).
+	version(ctx context.Context) (string, error)
+	create(ctx context.Context, name string, config *v1alpha4.Cluster, nodeImage string) error
+	delete(ctx context.Context, name string) error
+	listNodes(ctx context.Context, name string) ([]string, error)
+
+	// Copies an image from the host's container engine onto the nodes.
+	loadImage(ctx context.Context, name string, image string) error
+}
+
+// Picks the kind implementation to use.
+//
+// We prefer to run kind in-process. But if the user has a kind binary
+// at a different version than the one we link against, they've probably
+// pinned it on purpose, so we shell out to it instead.
+func newKindClient(ctx context.Context, iostreams genericclioptions.IOStreams, engine docker.Engine) kindClient {
+	lib := newKindLibraryClient(iostreams, engine)
+	cli := newKindCLIClient(iostreams, engine)
+
+	cliVersion, err := cli.version(ctx)
+	if err != nil {
+		// No usable kind binary.
+		return lib
+	}
+
+	libVersion, _ := lib.version(ctx)
+	if cliVersion != libVersion {
+		klog.V(2).Infof("Found kind %s on PATH (ctlptl links kind %s). Using the kind CLI.", cliVersion, libVersion)
+		return cli
+	}
+	return lib
+}
+
+// Drives kind through its Go library.
+type kindLibraryClient struct {
+	provider *kindcluster.Provider
+	engine   docker.Engine
+}
+
+func newKindLibraryClient(iostreams genericclioptions.IOStreams, engine docker.Engine) kindLibraryClient {
+	runtime := kindcluster.ProviderWithDocker()
+	if engine == docker.EnginePodman {
+		runtime = kindcluster.ProviderWithPodman()
+	}
+	return kindLibraryClient{
+		provider: kindcluster.NewProvider(
+			kindcluster.ProviderWithLogger(kindLogger{w: iostreams.ErrOut}),
+			runtime),
+		engine: engine,
+	}
+}
+
+func (c kindLibraryClient) version(ctx context.Context) (string, error) {
+	return "v" + kindversion.Version(), nil
+}
+
+func (c kindLibraryClient) create(ctx context.Context, name string, config *v1alpha4.Cluster, nodeImage string) error {
+	opts := []kindcluster.CreateOption{
+		kindcluster.CreateWithV1Alpha4Config(config),
+		kindcluster.CreateWithDisplayUsage(true),
+		kindcluster.CreateWithDisplaySalutation(true),
+	}
+	if nodeImage != "" {
+		opts = append(opts, kindcluster.CreateWithNodeImage(nodeImage))
+	}
+	return c.provider.Create(name, opts...)
+}
+
+func (c kindLibraryClient) delete(ctx context.Context, name string) error {
+	return c.provider.Delete(name, "")
+}
+
+func (c kindLibraryClient) listNodes(ctx context.Context, name string) ([]string, error) {
+	nodes, err := c.provider.ListNodes(name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node.String())
+	}
+	return result, nil
+}
+
+// Saves the image to an archive, then imports it on each node,
+// like `kind load docker-image`.
+func (c kindLibraryClient) loadImage(ctx context.Context, name string, image string) error {
+	nodes, err := c.provider.ListInternalNodes(name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found for kind cluster %s", name)
+	}
+
+	dir, err := ioutil.TempDir("", "ctlptl-image-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	archive := filepath.Join(dir, "image.tar")
+	out, err := exec.CommandContext(ctx, c.engine.CLI(), "save", "-o", archive, image).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "saving image %s: %s", image, strings.TrimSpace(string(out)))
+	}
+
+	for _, node := range nodes {
+		err := func() error {
+			f, err := os.Open(archive)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return nodeutils.LoadImageArchive(node, f)
+		}()
+		if err != nil {
+			return errors.Wrapf(err, "loading image %s on node %s", image, node.String())
+		}
+	}
+	return nil
+}
+
+// Drives kind through the kind CLI.
+type kindCLIClient struct {
+	iostreams genericclioptions.IOStreams
+	engine    docker.Engine
+}
+
+func newKindCLIClient(iostreams genericclioptions.IOStreams, engine docker.Engine) kindCLIClient {
+	return kindCLIClient{
+		iostreams: iostreams,
+		engine:    engine,
+	}
+}
+
+// Kind picks its node provider from the environment.
+//
+// https://kind.sigs.k8s.io/docs/user/rootless/
+func (c kindCLIClient) env() []string {
+	env := os.Environ()
+	if c.engine == docker.EnginePodman {
+		env = append(env, "KIND_EXPERIMENTAL_PROVIDER=podman")
+	}
+	return env
+}
+
+func (c kindCLIClient) run(ctx context.Context, iostreams genericclioptions.IOStreams, args ...string) error {
+	cmd := exec.CommandContext(ctx, "kind", args...)
+	cmd.Env = c.env()
+	cmd.Stdin = iostreams.In
+	cmd.Stdout = iostreams.Out
+	cmd.Stderr = iostreams.ErrOut
+	return cmd.Run()
+}
+
+var kindVersionRe = regexp.MustCompile(`v\d+\.\d+\.\d+(-[0-9A-Za-z.+-]+)?`)
+
+// Parses the output of `kind version`, which looks like:
+//
+// kind v0.11.1 go1.16.4 darwin/amd64
+func parseKindVersion(out string) (string, error) {
+	v := kindVersionRe.FindString(out)
+	if v == "" {
+		return "", fmt.Errorf("parsing kind version output: %s", out)
+	}
+	return v, nil
+}
+
+func (c kindCLIClient) version(ctx context.Context) (string, error) {
+	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	err := c.run(ctx, genericclioptions.IOStreams{Out: out, ErrOut: errOut}, "version")
+	if err != nil {
+		return "", errors.Wrapf(err, "kind version: %s", errOut.String())
+	}
+	return parseKindVersion(out.String())
+}
+
+func (c kindCLIClient) create(ctx context.Context, name string, config *v1alpha4.Cluster, nodeImage string) error {
+	buf := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buf)
+	err := encoder.Encode(config)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"create", "cluster", "--name", name}
+	if nodeImage != "" {
+		args = append(args, "--image", nodeImage)
+	}
+	args = append(args, "--config", "-")
+
+	return c.run(ctx, genericclioptions.IOStreams{In: buf, Out: c.iostreams.Out, ErrOut: c.iostreams.ErrOut}, args...)
+}
+
+func (c kindCLIClient) delete(ctx context.Context, name string) error {
+	return c.run(ctx, c.iostreams, "delete", "cluster", "--name", name)
+}
+
+func (c kindCLIClient) listNodes(ctx context.Context, name string) ([]string, error) {
+	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	err := c.run(ctx, genericclioptions.IOStreams{Out: out, ErrOut: errOut}, "get", "nodes", "--name", name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "kind get nodes: %s", errOut.String())
+	}
+
+	// When there are no nodes, kind prints a message on stderr and nothing on stdout.
+	result := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+func (c kindCLIClient) loadImage(ctx context.Context, name string, image string) error {
+	errOut := bytes.NewBuffer(nil)
+	err := c.run(ctx, genericclioptions.IOStreams{Out: ioutil.Discard, ErrOut: errOut},
+		"load", "docker-image", image, "--name", name)
+	if err != nil {
+		return errors.Wrapf(err, "kind load docker-image: %s", strings.TrimSpace(errOut.String()))
+	}
+	return nil
+}
+
+// Adapts kind's logger to our iostreams, so that kind's progress output
+// goes to the same place as the CLI's.
+type kindLogger struct {
+	w io.Writer
+}
+
+var _ kindlog.Logger = kindLogger{}
+
+func (l kindLogger) Warn(message string) {
+	_, _ = fmt.Fprintln(l.w, message)
+}
+
+func (l kindLogger) Warnf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+func (l kindLogger) Error(message string) {
+	_, _ = fmt.Fprintln(l.w, message)
+}
+
+func (l kindLogger) Errorf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+func (l kindLogger) V(level kindlog.Level) kindlog.InfoLogger {
+	// V(0) is for normal user-facing messages. Higher levels follow our -v flag.
+	return kindInfoLogger{w: l.w, enabled: level == 0 || klog.V(klog.Level(level)).Enabled()}
+}
+
+type kindInfoLogger struct {
+	w       io.Writer
+	enabled bool
+}
+
+func (l kindInfoLogger) Info(message string) {
+	if l.enabled {
+		_, _ = fmt.Fprintln(l.w, message)
+	}
+}
+
+func (l kindInfoLogger) Infof(format string, args ...interface{}) {
+	if l.enabled {
+		_, _ = fmt.Fprintf(l.w, format+"\n", args...)
+	}
+}
+
+func (l kindInfoLogger) Enabled() bool {
+	return l.enabled
+}
@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed kind_images.yaml
+var embeddedKindImages []byte
+
+// Overrides the URL to fetch extra kind node images from.
+const kindImagesURLEnv = "CTLPTL_KIND_IMAGES_URL"
+
+// Maps a kind version (e.g., v0.11.1) to a table of
+// Kubernetes major.minor versions (e.g., 1.21) to node images.
+type KindNodeImageTable map[string]map[string]string
+
+// Copies the entries of other into the table, replacing any
+// entries that are already there.
+func (t KindNodeImageTable) merge(other KindNodeImageTable) {
+	for kindVersion, images := range other {
+		existing, ok := t[kindVersion]
+		if !ok {
+			existing = make(map[string]string, len(images))
+			t[kindVersion] = existing
+		}
+		for k8sVersion, image := range images {
+			existing[k8sVersion] = image
+		}
+	}
+}
+
+// The kind versions in the table, newest first.
+func (t KindNodeImageTable) KindVersions() []string {
+	result := make([]string, 0, len(t))
+	for v := range t {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return versionGreater(result[i], result[j])
+	})
+	return result
+}
+
+// The Kubernetes versions for a kind version, newest first.
+func (t KindNodeImageTable) K8sVersions(kindVersion string) []string {
+	result := make([]string, 0, len(t[kindVersion]))
+	for v := range t[kindVersion] {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return versionGreater(result[i], result[j])
+	})
+	return result
+}
+
+func versionGreater(a, b string) bool {
+	av, aErr := semver.ParseTolerant(a)
+	bv, bErr := semver.ParseTolerant(b)
+	if aErr != nil || bErr != nil {
+		return a > b
+	}
+	return av.GT(bv)
+}
+
+func parseKindNodeImageTable(contents []byte) (KindNodeImageTable, error) {
+	table := KindNodeImageTable{}
+	err := yaml.Unmarshal(contents, &table)
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// The user-level file that overrides or extends the built-in table.
+func kindImagesUserPath() (string, error) {
+	dir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".ctlptl", "kind-images.yaml"), nil
+}
+
+// Loads the kind node image table.
+//
+// Starts with the table built into ctlptl, then layers on the table at
+// $CTLPTL_KIND_IMAGES_URL (if set), then ~/.ctlptl/kind-images.yaml (if it exists).
+func LoadKindNodeImageTable(ctx context.Context) (KindNodeImageTable, error) {
+	table, err := parseKindNodeImageTable(embeddedKindImages)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading built-in kind images")
+	}
+
+	url := os.Getenv(kindImagesURLEnv)
+	if url != "" {
+		remote, err := fetchKindNodeImageTable(ctx, url)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading kind images from %s", url)
+		}
+		table.merge(remote)
+	}
+
+	userPath, err := kindImagesUserPath()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := ioutil.ReadFile(userPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "reading kind images from %s", userPath)
+	}
+	if err == nil {
+		user, err := parseKindNodeImageTable(contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading kind images from %s", userPath)
+		}
+		table.merge(user)
+	}
+
+	return table, nil
+}
+
+func fetchKindNodeImageTable(ctx context.Context, url string) (KindNodeImageTable, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseKindNodeImageTable(contents)
+}
@@ -6,25 +6,117 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"os/exec"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
+	cexec "github.com/tilt-dev/ctlptl/internal/exec"
 	"github.com/tilt-dev/ctlptl/pkg/api"
 	"github.com/tilt-dev/ctlptl/pkg/docker"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	klog "k8s.io/klog/v2"
 )
 
 type Machine interface {
 	CPUs(ctx context.Context) (int, error)
+
+	// The memory and disk of the machine, in bytes.
+	// Returns 0 if the machine doesn't know.
+	Memory(ctx context.Context) (int64, error)
+	Disk(ctx context.Context) (int64, error)
+
 	EnsureExists(ctx context.Context) error
 	Restart(ctx context.Context, desired, existing *api.Cluster) error
 }
 
+const mebibyte = 1024 * 1024
+const gibibyte = 1024 * mebibyte
+
+// Parses a Kubernetes quantity (e.g., 8Gi) into bytes.
+// An empty quantity is 0.
+func quantityBytes(field, q string) (int64, error) {
+	if q == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(q)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %v", field, err)
+	}
+	if quantity.Sign() < 0 {
+		return 0, fmt.Errorf("parsing %s: must not be negative: %s", field, q)
+	}
+	return quantity.Value(), nil
+}
+
+func minMemoryBytes(cluster *api.Cluster) (int64, error) {
+	return quantityBytes("minMemory", cluster.MinMemory)
+}
+
+func minDiskBytes(cluster *api.Cluster) (int64, error) {
+	return quantityBytes("minDisk", cluster.MinDisk)
+}
+
+// Machines that report the memory the kernel sees, like Docker on Linux,
+// report a bit less than they have, because the kernel keeps some for itself.
+// So a machine with 8Gi should meet a minMemory of 8Gi.
+const memoryTolerance = 0.05
+
+func hasMinMemory(memory, minMemory int64) bool {
+	return float64(memory) >= float64(minMemory)*(1-memoryTolerance)
+}
+
+// Formats bytes as a Kubernetes quantity, e.g., 8Gi
+func bytesString(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+// Rounds up to the nearest unit, for settings that don't take bytes.
+func ceilDiv(bytes int64, unit int64) int {
+	return int((bytes + unit - 1) / unit)
+}
+
+// Checks the machine against the desired memory and disk, for machines
+// that can't resize themselves.
+//
+// We ask the machine, rather than the existing cluster status,
+// because the cluster may not exist yet.
+func checkFixedResources(ctx context.Context, m Machine, desired *api.Cluster) error {
+	minMemory, err := minMemoryBytes(desired)
+	if err != nil {
+		return err
+	}
+	if minMemory != 0 {
+		memory, err := m.Memory(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasMinMemory(memory, minMemory) {
+			return fmt.Errorf("Cannot automatically set minimum memory to %s on this platform", desired.MinMemory)
+		}
+	}
+
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return err
+	}
+	if minDisk != 0 {
+		disk, err := m.Disk(ctx)
+		if err != nil {
+			return err
+		}
+		if disk < minDisk {
+			return fmt.Errorf("Cannot automatically set minimum disk to %s on this platform", desired.MinDisk)
+		}
+	}
+	return nil
+}
+
 type unknownMachine struct {
 	product Product
 }
@@ -37,6 +129,14 @@ func (m unknownMachine) CPUs(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+func (m unknownMachine) Memory(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m unknownMachine) Disk(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
 func (m unknownMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
 	return fmt.Errorf("cluster type %s not configurable", desired.Product)
 }
@@ -49,6 +149,10 @@ type d4mClient interface {
 	ResetCluster(tx context.Context) error
 	setK8sEnabled(settings map[string]interface{}, desired bool) (bool, error)
 	ensureMinCPU(settings map[string]interface{}, desired int) (bool, error)
+	ensureMinMemory(settings map[string]interface{}, desiredMiB int) (bool, error)
+	ensureMinDisk(settings map[string]interface{}, desiredMiB int) (bool, error)
+	memoryMiB(settings map[string]interface{}) (int, error)
+	diskSizeMiB(settings map[string]interface{}) (int, error)
 	Open(ctx context.Context) error
 }
 
@@ -83,14 +187,63 @@ func (m dockerMachine) CPUs(ctx context.Context) (int, error) {
 	return info.NCPU, nil
 }
 
+// Docker reports the memory that the kernel sees, which is less than
+// Docker Desktop's VM was configured with. So ask Docker Desktop.
+func (m dockerMachine) Memory(ctx context.Context) (int64, error) {
+	if m.isLocalDockerDesktop() {
+		settings, err := m.d4m.settings(ctx)
+		if err != nil {
+			return 0, err
+		}
+		memoryMiB, err := m.d4m.memoryMiB(settings)
+		if err != nil {
+			return 0, err
+		}
+		return int64(memoryMiB) * mebibyte, nil
+	}
+
+	info, err := m.dockerClient.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return info.MemTotal, nil
+}
+
+// Docker doesn't report its disk size, but Docker Desktop does.
+func (m dockerMachine) Disk(ctx context.Context) (int64, error) {
+	if !m.isLocalDockerDesktop() {
+		return 0, nil
+	}
+
+	settings, err := m.d4m.settings(ctx)
+	if err != nil {
+		return 0, err
+	}
+	diskSizeMiB, err := m.d4m.diskSizeMiB(settings)
+	if err != nil {
+		return 0, err
+	}
+	return int64(diskSizeMiB) * mebibyte, nil
+}
+
+func (m dockerMachine) isLocalDockerDesktop() bool {
+	return m.dockerClient.IsLocalHost() && m.dockerClient.Engine() == docker.EngineDocker &&
+		(m.os == "darwin" || m.os == "windows")
+}
+
 func (m dockerMachine) EnsureExists(ctx context.Context) error {
 	_, err := m.dockerClient.ServerVersion(ctx)
 	if err == nil {
 		return nil
 	}
 
+	engine := m.dockerClient.Engine()
 	if !m.dockerClient.IsLocalHost() {
-		return fmt.Errorf("Detected remote DOCKER_HOST, but no Docker running: %s", docker.GetHostEnv())
+		return fmt.Errorf("Detected remote DOCKER_HOST, but no Docker running: %s", engine.HostEnv())
+	}
+
+	if engine == docker.EnginePodman {
+		return fmt.Errorf("Podman API not running. Please start it with: systemctl --user start podman.socket")
 	}
 
 	klog.V(2).Infoln("No Docker daemon running. Attempting to start Docker.")
@@ -120,7 +273,7 @@ func (m dockerMachine) EnsureExists(ctx context.Context) error {
 func (m dockerMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
 	canChangeCPUs := false
 	isLocalDockerDesktop := false
-	if m.dockerClient.IsLocalHost() && (m.os == "darwin" || m.os == "windows") {
+	if m.isLocalDockerDesktop() {
 		canChangeCPUs = true // DockerForMac and DockerForWindows can change the CPU on the VM
 		isLocalDockerDesktop = true
 	} else if Product(desired.Product) == ProductMinikube {
@@ -132,6 +285,24 @@ func (m dockerMachine) Restart(ctx context.Context, desired, existing *api.Clust
 		return fmt.Errorf("Cannot automatically set minimum CPU to %d on this platform", desired.MinCPUs)
 	}
 
+	minMemory, err := minMemoryBytes(desired)
+	if err != nil {
+		return err
+	}
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return err
+	}
+
+	// Docker Desktop can change the memory and disk on the VM, and minikube
+	// can change them on its own VM or container.
+	if !canChangeCPUs {
+		err := checkFixedResources(ctx, m, desired)
+		if err != nil {
+			return err
+		}
+	}
+
 	if isLocalDockerDesktop {
 		settings, err := m.d4m.settings(ctx)
 		if err != nil {
@@ -151,7 +322,23 @@ func (m dockerMachine) Restart(ctx context.Context, desired, existing *api.Clust
 			return err
 		}
 
-		if k8sChanged || cpuChanged {
+		memoryChanged := false
+		if minMemory != 0 {
+			memoryChanged, err = m.d4m.ensureMinMemory(settings, ceilDiv(minMemory, mebibyte))
+			if err != nil {
+				return err
+			}
+		}
+
+		diskChanged := false
+		if minDisk != 0 {
+			diskChanged, err = m.d4m.ensureMinDisk(settings, ceilDiv(minDisk, mebibyte))
+			if err != nil {
+				return err
+			}
+		}
+
+		if k8sChanged || cpuChanged || memoryChanged || diskChanged {
 			err := m.d4m.writeSettings(ctx, settings)
 			if err != nil {
 				return err
@@ -179,49 +366,441 @@ func (m dockerMachine) Restart(ctx context.Context, desired, existing *api.Clust
 	return nil
 }
 
-// Currently, out Minikube admin only supports Minikube on Docker,
-// so we delegate to the dockerMachine driver.
+// Minikube can run on a container engine, in a VM, or on bare metal.
+//
+// We read the driver from the minikube profile, and only delegate
+// to the dockerMachine driver when the cluster runs on a container engine.
 type minikubeMachine struct {
-	dm   *dockerMachine
-	name string
+	dm     *dockerMachine
+	name   string
+	driver string
+	home   string
+	runner cexec.CmdRunner
+	errOut io.Writer
 }
 
-func newMinikubeMachine(name string, dm *dockerMachine) *minikubeMachine {
-	return &minikubeMachine{
-		name: name,
-		dm:   dm,
+// The driver is the one we'd use to create the cluster, if the profile
+// doesn't exist yet.
+func newMinikubeMachine(name string, driver string, dm *dockerMachine, runner cexec.CmdRunner, errOut io.Writer) (*minikubeMachine, error) {
+	home, err := minikubeHome()
+	if err != nil {
+		return nil, err
 	}
-}
-
-type minikubeSettings struct {
-	CPUs int
+	return &minikubeMachine{
+		name:   name,
+		driver: driver,
+		dm:     dm,
+		home:   home,
+		runner: runner,
+		errOut: errOut,
+	}, nil
 }
 
 func (m *minikubeMachine) CPUs(ctx context.Context) (int, error) {
-	homedir, err := homedir.Dir()
+	settings, err := readMinikubeSettings(m.home, m.name)
 	if err != nil {
 		return 0, err
 	}
-	configPath := filepath.Join(homedir, ".minikube", "profiles", m.name, "config.json")
-	f, err := os.Open(configPath)
+	return settings.CPUs, nil
+}
+
+func (m *minikubeMachine) Memory(ctx context.Context) (int64, error) {
+	settings, err := readMinikubeSettings(m.home, m.name)
 	if err != nil {
 		return 0, err
 	}
-	defer f.Close()
+	return int64(settings.Memory) * mebibyte, nil
+}
 
-	decoder := json.NewDecoder(f)
-	settings := minikubeSettings{}
-	err = decoder.Decode(&settings)
+func (m *minikubeMachine) Disk(ctx context.Context) (int64, error) {
+	settings, err := readMinikubeSettings(m.home, m.name)
 	if err != nil {
 		return 0, err
 	}
-	return settings.CPUs, nil
+	return int64(settings.DiskSize) * mebibyte, nil
+}
+
+// The driver of the existing profile, or the desired driver if there's no profile yet.
+func (m *minikubeMachine) currentDriver() (string, error) {
+	settings, err := readMinikubeSettings(m.home, m.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m.driver, nil
+		}
+		return "", err
+	}
+	if settings.Driver == "" {
+		return m.driver, nil
+	}
+	return settings.Driver, nil
+}
+
+// Whether the container driver runs on the engine that ctlptl is connected to.
+func (m *minikubeMachine) isDockerMachineDriver(driver string) bool {
+	return driver == m.dm.dockerClient.Engine().CLI()
 }
 
 func (m *minikubeMachine) EnsureExists(ctx context.Context) error {
-	return m.dm.EnsureExists(ctx)
+	driver, err := m.currentDriver()
+	if err != nil {
+		return err
+	}
+
+	switch minikubeDriverKindOf(driver) {
+	case minikubeDriverContainer:
+		if m.isDockerMachineDriver(driver) {
+			return m.dm.EnsureExists(ctx)
+		}
+
+		// A container engine that ctlptl isn't connected to. We can't boot it,
+		// but we can make sure it's up before minikube tries to use it.
+		err := m.runner.Run(ctx, driver, "info")
+		if err != nil {
+			return fmt.Errorf("minikube driver %s not running: %v", driver, err)
+		}
+		return nil
+
+	case minikubeDriverBareMetal:
+		if runtime.GOOS != "linux" && driver == "none" {
+			return fmt.Errorf("minikube driver none only works on Linux")
+		}
+		return nil
+	}
+
+	// `minikube start` boots the VM itself.
+	return nil
 }
 
 func (m *minikubeMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
-	return m.dm.Restart(ctx, desired, existing)
+	driver, err := m.currentDriver()
+	if err != nil {
+		return err
+	}
+
+	if minikubeDriverKindOf(driver) == minikubeDriverContainer && m.isDockerMachineDriver(driver) {
+		// Make sure the VM underneath the container engine (e.g., Docker Desktop)
+		// has enough room for the cluster.
+		err := m.dm.Restart(ctx, desired, existing)
+		if err != nil {
+			return err
+		}
+	}
+
+	settings, err := readMinikubeSettings(m.home, m.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No cluster yet. `minikube start` will pick up the CPU and memory flags
+			// when the admin creates it.
+			return nil
+		}
+		return err
+	}
+
+	args, err := minikubeResizeArgs(desired, settings)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(m.errOut, "Resizing minikube cluster %s...\n", m.name)
+	streams := genericclioptions.IOStreams{Out: m.errOut, ErrOut: m.errOut}
+	err = m.runner.RunIO(ctx, streams, "minikube", "stop", "-p", m.name)
+	if err != nil {
+		return errors.Wrap(err, "resizing minikube cluster")
+	}
+
+	err = m.runner.RunIO(ctx, streams, "minikube", append([]string{"start", "-p", m.name}, args...)...)
+	if err != nil {
+		return errors.Wrap(err, "resizing minikube cluster")
+	}
+
+	// Some minikube versions ignore the size flags on an existing profile,
+	// so read the profile back to make sure they took.
+	settings, err = readMinikubeSettings(m.home, m.name)
+	if err != nil {
+		return errors.Wrap(err, "resizing minikube cluster")
+	}
+	remaining, err := minikubeResizeArgs(desired, settings)
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("minikube did not resize cluster %s (still needs %s). "+
+			"Delete the cluster to re-create it with the new size",
+			m.name, strings.Join(remaining, " "))
+	}
+	return nil
+}
+
+// MicroK8s runs directly on the host on Linux, and in a Multipass VM
+// on MacOS and Windows.
+type microk8sMachine struct {
+	runner cexec.CmdRunner
+	errOut io.Writer
+	os     string
+}
+
+func newMicrok8sMachine(runner cexec.CmdRunner, errOut io.Writer) *microk8sMachine {
+	return &microk8sMachine{
+		runner: runner,
+		errOut: errOut,
+		os:     runtime.GOOS,
+	}
+}
+
+// The subset of `multipass info --format json` that we care about.
+type multipassInfo struct {
+	Info map[string]multipassVMInfo `json:"info"`
+}
+
+type multipassVMInfo struct {
+	CPUCount string `json:"cpu_count"`
+	Memory   struct {
+		Total int64 `json:"total"`
+	} `json:"memory"`
+	Disks map[string]struct {
+		Total string `json:"total"`
+	} `json:"disks"`
+}
+
+func (m *microk8sMachine) vmInfo(ctx context.Context) (multipassVMInfo, error) {
+	out, err := exec.CommandContext(ctx, "multipass", "info", "microk8s-vm", "--format", "json").Output()
+	if err != nil {
+		return multipassVMInfo{}, errors.Wrap(err, "reading microk8s VM")
+	}
+
+	info := multipassInfo{}
+	err = json.Unmarshal(out, &info)
+	if err != nil {
+		return multipassVMInfo{}, errors.Wrap(err, "reading microk8s VM")
+	}
+	vm, ok := info.Info["microk8s-vm"]
+	if !ok {
+		return multipassVMInfo{}, fmt.Errorf("reading microk8s VM: not found")
+	}
+	return vm, nil
+}
+
+func (m *microk8sMachine) CPUs(ctx context.Context) (int, error) {
+	if m.os == "linux" {
+		return runtime.NumCPU(), nil
+	}
+
+	vm, err := m.vmInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(vm.CPUCount)
+}
+
+// On Linux, microk8s shares the host's memory and disk, which we don't measure.
+func (m *microk8sMachine) Memory(ctx context.Context) (int64, error) {
+	if m.os == "linux" {
+		return 0, nil
+	}
+
+	vm, err := m.vmInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return vm.Memory.Total, nil
+}
+
+func (m *microk8sMachine) Disk(ctx context.Context) (int64, error) {
+	if m.os == "linux" {
+		return 0, nil
+	}
+
+	vm, err := m.vmInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	for name, disk := range vm.Disks {
+		size, err := strconv.ParseInt(disk.Total, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("reading microk8s VM disk %s: %v", name, err)
+		}
+		total += size
+	}
+	return total, nil
+}
+
+func (m *microk8sMachine) EnsureExists(ctx context.Context) error {
+	_, err := exec.LookPath("microk8s")
+	if err != nil {
+		return fmt.Errorf("microk8s not installed. Please install microk8s with these instructions: https://microk8s.io/docs/getting-started")
+	}
+
+	if m.os == "linux" {
+		return nil
+	}
+
+	// On MacOS and Windows, the microk8s CLI is a thin wrapper around a VM.
+	err = m.runner.Run(ctx, "microk8s", "status")
+	if err == nil {
+		return nil
+	}
+
+	klog.V(2).Infoln("No microk8s VM running. Attempting to install microk8s.")
+	err = m.runner.RunIO(ctx, genericclioptions.IOStreams{Out: m.errOut, ErrOut: m.errOut}, "microk8s", "install")
+	if err != nil {
+		return errors.Wrap(err, "installing microk8s")
+	}
+	return nil
+}
+
+func (m *microk8sMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
+	if existing.Status.CPUs < desired.MinCPUs {
+		return fmt.Errorf("Cannot automatically set minimum CPU to %d on this platform", desired.MinCPUs)
+	}
+	return checkFixedResources(ctx, m, desired)
+}
+
+// Rancher Desktop runs Kubernetes in a VM that we can reconfigure with rdctl.
+type rancherDesktopMachine struct {
+	rd     rdClient
+	errOut io.Writer
+	sleep  sleeper
+}
+
+func newRancherDesktopMachine(rd rdClient, errOut io.Writer) *rancherDesktopMachine {
+	return &rancherDesktopMachine{
+		rd:     rd,
+		errOut: errOut,
+		sleep:  time.Sleep,
+	}
+}
+
+func (m *rancherDesktopMachine) CPUs(ctx context.Context) (int, error) {
+	settings, err := m.rd.settings(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return settings.cpus(), nil
+}
+
+func (m *rancherDesktopMachine) Memory(ctx context.Context) (int64, error) {
+	settings, err := m.rd.settings(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(settings.memoryInGB()) * gibibyte, nil
+}
+
+// rdctl doesn't expose the VM disk size.
+func (m *rancherDesktopMachine) Disk(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *rancherDesktopMachine) EnsureExists(ctx context.Context) error {
+	_, err := exec.LookPath("rdctl")
+	if err != nil {
+		return fmt.Errorf("rdctl not installed. Please install Rancher Desktop with these instructions: https://rancherdesktop.io/")
+	}
+
+	_, err = m.rd.settings(ctx)
+	if err == nil {
+		return nil
+	}
+
+	klog.V(2).Infoln("Rancher Desktop not running. Attempting to start Rancher Desktop.")
+	err = m.rd.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	dur := 120 * time.Second
+	_, _ = fmt.Fprintf(m.errOut, "Waiting %s for Rancher Desktop to boot...\n", duration.ShortHumanDuration(dur))
+	err = wait.Poll(time.Second, dur, func() (bool, error) {
+		_, err := m.rd.settings(ctx)
+		isSuccess := err == nil
+		return isSuccess, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for Rancher Desktop to start")
+	}
+	klog.V(2).Infoln("Rancher Desktop started successfully")
+	return nil
+}
+
+func (m *rancherDesktopMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
+	settings, err := m.rd.settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	minMemory, err := minMemoryBytes(desired)
+	if err != nil {
+		return err
+	}
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return err
+	}
+	if minDisk != 0 {
+		return fmt.Errorf("Cannot automatically set minimum disk to %s on Rancher Desktop", desired.MinDisk)
+	}
+
+	enabled := true
+	target := rancherDesktopTarget{
+		k8sEnabled:  &enabled,
+		k8sVersion:  desired.KubernetesVersion,
+		minCPUs:     desired.MinCPUs,
+		minMemoryGB: ceilDiv(minMemory, gibibyte),
+	}
+	args, err := rancherDesktopSetArgs(settings, target)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	err = m.rd.set(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	dur := 120 * time.Second
+	_, _ = fmt.Fprintf(m.errOut,
+		"Applied new Rancher Desktop settings. Waiting %s for Rancher Desktop to restart...\n",
+		duration.ShortHumanDuration(dur))
+
+	// Sleep for short time to ensure the write takes effect.
+	m.sleep(2 * time.Second)
+
+	// The settings change as soon as they're written, but Kubernetes
+	// keeps running until Rancher Desktop restarts it. So wait for the
+	// backend to go down and come back up.
+	restarting := false
+	err = wait.Poll(time.Second, dur, func() (bool, error) {
+		state, err := m.rd.backendState(ctx)
+		if err != nil {
+			return false, nil
+		}
+		if state != rancherDesktopStateStarted {
+			restarting = true
+			return false, nil
+		}
+		if !restarting {
+			return false, nil
+		}
+
+		settings, err := m.rd.settings(ctx)
+		if err != nil {
+			return false, nil
+		}
+		args, err := rancherDesktopSetArgs(settings, target)
+		if err != nil {
+			return false, err
+		}
+		return len(args) == 0, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Rancher Desktop restart timeout")
+	}
+	return nil
 }
@@ -0,0 +1,253 @@
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// How long we wait for the workloads in a manifest to become ready.
+const waitForReadyTimeout = 5 * time.Minute
+
+// Common kinds, so that we can apply the bundled manifests
+// without asking the cluster for its API resources.
+var builtinRESTMapper = func() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range []schema.GroupVersionKind{
+		{Version: "v1", Kind: "ConfigMap"},
+		{Version: "v1", Kind: "Secret"},
+		{Version: "v1", Kind: "Service"},
+		{Version: "v1", Kind: "ServiceAccount"},
+		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	} {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	for _, gvk := range []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Namespace"},
+		{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+		{Group: "networking.k8s.io", Version: "v1", Kind: "IngressClass"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+	} {
+		mapper.Add(gvk, meta.RESTScopeRoot)
+	}
+	return mapper
+}()
+
+// A REST mapper that knows the common kinds, and asks the cluster
+// about everything else.
+type manifestRESTMapper struct {
+	meta.RESTMapper
+	discovery *restmapper.DeferredDiscoveryRESTMapper
+}
+
+func newManifestRESTMapper(client discovery.DiscoveryInterface) *manifestRESTMapper {
+	discoveryMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client))
+	return &manifestRESTMapper{
+		RESTMapper: meta.FirstHitRESTMapper{
+			MultiRESTMapper: meta.MultiRESTMapper{builtinRESTMapper, discoveryMapper},
+		},
+		discovery: discoveryMapper,
+	}
+}
+
+// Forget what we know about the cluster's API resources,
+// e.g., after we've added new CRDs.
+func (m *manifestRESTMapper) Reset() {
+	m.discovery.Reset()
+}
+
+// Splits a multi-document YAML manifest into objects.
+func decodeManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	var result []*unstructured.Unstructured
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData, err := yamlutil.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		jsonData = bytes.TrimSpace(jsonData)
+		if len(jsonData) == 0 || string(jsonData) == "null" {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		err = obj.UnmarshalJSON(jsonData)
+		if err != nil {
+			return nil, err
+		}
+
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				result = append(result, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+func resourceFor(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return client.Resource(mapping.Resource), nil
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+		obj.SetNamespace(namespace)
+	}
+	return client.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// Creates the object, or replaces it if it already exists.
+func applyUnstructured(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) error {
+	resource, err := resourceFor(client, mapper, obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = resource.Create(ctx, obj, metav1.CreateOptions{})
+	if err == nil || !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = resource.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+// Whether we need to wait for this kind of object to become ready.
+func needsReadyCheck(obj *unstructured.Unstructured) bool {
+	switch obj.GetKind() {
+	case "Deployment", "DaemonSet", "CustomResourceDefinition":
+		return true
+	}
+	return false
+}
+
+// Checks whether a Deployment or DaemonSet has all its pods ready,
+// or whether a CRD has been established.
+//
+// Returns a description of what we're waiting on if it's not ready.
+func objectReady(obj *unstructured.Unstructured) (bool, string) {
+	switch obj.GetKind() {
+	case "Deployment":
+		replicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !ok {
+			replicas = 1
+		}
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		if available < replicas {
+			return false, fmt.Sprintf("deployment %s/%s: %d of %d replicas available",
+				obj.GetNamespace(), obj.GetName(), available, replicas)
+		}
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+		if desired == 0 || ready < desired {
+			return false, fmt.Sprintf("daemonset %s/%s: %d of %d pods ready",
+				obj.GetNamespace(), obj.GetName(), ready, desired)
+		}
+	case "CustomResourceDefinition":
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == "Established" && condition["status"] == "True" {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("customresourcedefinition %s: not established", obj.GetName())
+	}
+	return true, ""
+}
+
+// Polls the objects until they're all ready.
+func (c *Controller) waitForObjectsReady(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper,
+	objs []*unstructured.Unstructured, what string) error {
+	checkReady := func() (bool, string, error) {
+		for _, obj := range objs {
+			if !needsReadyCheck(obj) {
+				continue
+			}
+
+			resource, err := resourceFor(client, mapper, obj)
+			if err != nil {
+				return false, "", err
+			}
+			current, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return false, err.Error(), nil
+			}
+			ready, reason := objectReady(current)
+			if !ready {
+				return false, reason, nil
+			}
+		}
+		return true, "", nil
+	}
+
+	ready, reason, err := checkReady()
+	if err != nil {
+		return err
+	}
+	if ready {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Waiting %s for %s to become ready...\n",
+		duration.ShortHumanDuration(c.waitForReadyTimeout), what)
+	err = wait.Poll(time.Second, c.waitForReadyTimeout, func() (bool, error) {
+		ready, reason, err = checkReady()
+		return ready, err
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for %s: %s", what, reason)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+)
+
+// The subset of the minikube profile config that we care about.
+//
+// Minikube writes this to ~/.minikube/profiles/<name>/config.json
+// when it creates a cluster.
+type minikubeSettings struct {
+	Driver   string
+	CPUs     int
+	Memory   int // in MB
+	DiskSize int // in MB
+}
+
+// The directory where minikube keeps its state.
+//
+// https://minikube.sigs.k8s.io/docs/handbook/config/#environment-variables
+func minikubeHome() (string, error) {
+	home := os.Getenv("MINIKUBE_HOME")
+	if home != "" {
+		if filepath.Base(home) == ".minikube" {
+			return home, nil
+		}
+		return filepath.Join(home, ".minikube"), nil
+	}
+
+	dir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".minikube"), nil
+}
+
+// Reads the profile config for the named cluster.
+//
+// Returns an error that satisfies os.IsNotExist if the profile hasn't been created yet.
+func readMinikubeSettings(minikubeHome, name string) (minikubeSettings, error) {
+	configPath := filepath.Join(minikubeHome, "profiles", name, "config.json")
+	f, err := os.Open(configPath)
+	if err != nil {
+		return minikubeSettings{}, err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	settings := minikubeSettings{}
+	err = decoder.Decode(&settings)
+	if err != nil {
+		return minikubeSettings{}, fmt.Errorf("reading minikube profile %s: %v", configPath, err)
+	}
+	return settings, nil
+}
+
+// The driver we'd pass to `minikube start` for a new cluster.
+func minikubeDesiredDriver(desired *api.Cluster, engine docker.Engine) string {
+	if desired != nil && desired.Minikube != nil && desired.Minikube.Driver != "" {
+		return desired.Minikube.Driver
+	}
+	return engine.CLI()
+}
+
+// Minikube drivers fall into three families, which need different
+// handling to check that the machine is up.
+//
+// https://minikube.sigs.k8s.io/docs/drivers/
+type minikubeDriverKind int
+
+const (
+	// The cluster runs in a container on a Docker-compatible engine.
+	minikubeDriverContainer minikubeDriverKind = iota
+
+	// The cluster runs directly on the host (or a host we ssh into).
+	minikubeDriverBareMetal
+
+	// The cluster runs in a VM that minikube boots itself.
+	minikubeDriverVM
+)
+
+func minikubeDriverKindOf(driver string) minikubeDriverKind {
+	switch driver {
+	case "docker", "podman":
+		return minikubeDriverContainer
+	case "none", "ssh":
+		return minikubeDriverBareMetal
+	}
+	return minikubeDriverVM
+}
+
+// ctlptl connects minikube to registries and mirrors by editing the
+// containerd config inside the node container, so that's the only
+// setup where they work.
+func validateMinikubeRegistry(desired *api.Cluster) error {
+	if Product(desired.Product) != ProductMinikube || desired.Minikube == nil {
+		return nil
+	}
+	if desiredRegistryName(desired) == "" && len(desired.RegistryMirrors) == 0 {
+		return nil
+	}
+
+	runtime := desired.Minikube.ContainerRuntime
+	if runtime != "" && runtime != "containerd" {
+		return fmt.Errorf("minikube: a registry requires containerRuntime: containerd. Actual: %s", runtime)
+	}
+	driver := desired.Minikube.Driver
+	if driver != "" && minikubeDriverKindOf(driver) != minikubeDriverContainer {
+		return fmt.Errorf("minikube: a registry requires a container driver (docker or podman). Actual: %s", driver)
+	}
+	return nil
+}
+
+// Parses a minikube memory string (like "4096", "4096mb", or "4g") into MB.
+//
+// Minikube interprets a number without a unit as MB. Returns 0 for
+// "max" and "no-limit", which don't ask for a fixed amount.
+func minikubeMemoryMB(s string) (int, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(s))
+	if trimmed == "max" || trimmed == "no-limit" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"kb", 1.0 / 1024},
+		{"mb", 1},
+		{"gb", 1024},
+		{"k", 1.0 / 1024},
+		{"m", 1},
+		{"g", 1024},
+	}
+
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(trimmed, u.suffix) {
+			trimmed = strings.TrimSuffix(trimmed, u.suffix)
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid minikube memory %q", s)
+	}
+	return int(n * mult), nil
+}
+
+// The part of the minikube config that we can't change without
+// re-creating the cluster.
+//
+// Memory can be changed in place by restarting minikube.
+func minikubeRecreateConfig(config *api.MinikubeCluster) *api.MinikubeCluster {
+	if config == nil {
+		return nil
+	}
+	result := config.DeepCopy()
+	result.Memory = ""
+	return result
+}
+
+// Whether the desired minikube memory differs from the cluster spec.
+//
+// The CPU check is shared with the other products.
+func needsMinikubeResize(desired, existing *api.Cluster) bool {
+	if Product(desired.Product) != ProductMinikube || desired.Minikube == nil || desired.Minikube.Memory == "" {
+		return false
+	}
+	existingMemory := ""
+	if existing.Minikube != nil {
+		existingMemory = existing.Minikube.Memory
+	}
+	return desired.Minikube.Memory != existingMemory
+}
+
+// The `minikube start` flags that resize an existing profile to match the desired cluster.
+//
+// Returns no flags if the profile already matches.
+func minikubeResizeArgs(desired *api.Cluster, settings minikubeSettings) ([]string, error) {
+	args := []string{}
+	if desired.MinCPUs > settings.CPUs {
+		if minikubeDriverKindOf(settings.Driver) == minikubeDriverBareMetal {
+			return nil, fmt.Errorf("Cannot automatically set minimum CPU to %d on minikube driver %s",
+				desired.MinCPUs, settings.Driver)
+		}
+		args = append(args, fmt.Sprintf("--cpus=%d", desired.MinCPUs))
+	}
+
+	// An explicit minikube memory takes precedence over minMemory.
+	if desired.Minikube != nil && desired.Minikube.Memory != "" {
+		memoryMB, err := minikubeMemoryMB(desired.Minikube.Memory)
+		if err != nil {
+			return nil, err
+		}
+		if memoryMB != 0 && memoryMB != settings.Memory {
+			args = append(args, fmt.Sprintf("--memory=%s", desired.Minikube.Memory))
+		}
+	} else {
+		minMemory, err := minMemoryBytes(desired)
+		if err != nil {
+			return nil, err
+		}
+		minMemoryMB := ceilDiv(minMemory, mebibyte)
+		if minMemoryMB > settings.Memory {
+			if minikubeDriverKindOf(settings.Driver) == minikubeDriverBareMetal {
+				return nil, fmt.Errorf("Cannot automatically set minimum memory to %s on minikube driver %s",
+					desired.MinMemory, settings.Driver)
+			}
+			args = append(args, fmt.Sprintf("--memory=%dmb", minMemoryMB))
+		}
+	}
+
+	// Minikube can't resize the disk of an existing cluster.
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return nil, err
+	}
+	if settings.DiskSize != 0 && ceilDiv(minDisk, mebibyte) > settings.DiskSize {
+		return nil, fmt.Errorf("Cannot automatically set minimum disk to %s on an existing minikube cluster. "+
+			"Delete the cluster to create it with a bigger disk", desired.MinDisk)
+	}
+	return args, nil
+}
@@ -0,0 +1,253 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+type minikubeMachineFixture struct {
+	t      *testing.T
+	home   string
+	calls  [][]string
+	docker *fakeDockerClient
+	m      *minikubeMachine
+
+	// The profile that `minikube start` writes, if any.
+	startedProfile string
+}
+
+func newMinikubeMachineFixture(t *testing.T, driver string) *minikubeMachineFixture {
+	home, err := ioutil.TempDir("", "ctlptl-minikube")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(home)
+	})
+
+	f := &minikubeMachineFixture{t: t, home: home}
+	f.docker = &fakeDockerClient{ncpu: 1}
+	dm := &dockerMachine{
+		dockerClient: f.docker,
+		errOut:       bytes.NewBuffer(nil),
+		sleep:        func(d time.Duration) {},
+		d4m:          &fakeD4MClient{docker: f.docker},
+		os:           "linux",
+	}
+	runner := exec.FakeCmdRunner(func(argv []string) {
+		f.calls = append(f.calls, argv)
+		if len(argv) > 1 && argv[0] == "minikube" && argv[1] == "start" && f.startedProfile != "" {
+			f.writeProfile(f.startedProfile)
+		}
+	})
+	f.m = &minikubeMachine{
+		name:   "minikube",
+		driver: driver,
+		dm:     dm,
+		home:   home,
+		runner: runner,
+		errOut: bytes.NewBuffer(nil),
+	}
+	return f
+}
+
+func (f *minikubeMachineFixture) writeProfile(config string) {
+	dir := filepath.Join(f.home, "profiles", "minikube")
+	require.NoError(f.t, os.MkdirAll(dir, 0755))
+	require.NoError(f.t, ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600))
+}
+
+func TestMinikubeMachineDriverFromProfile(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 4, "Memory": 4096}`)
+
+	// A kvm2 profile shouldn't need docker.
+	err := f.m.EnsureExists(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, f.calls)
+
+	cpus, err := f.m.CPUs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, cpus)
+}
+
+func TestMinikubeMachineDesiredDriver(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "podman")
+
+	// No profile yet, so we check the desired driver.
+	err := f.m.EnsureExists(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"podman", "info"}}, f.calls)
+}
+
+func TestMinikubeMachineResize(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 2, "Memory": 2048}`)
+	f.startedProfile = `{"Name": "minikube", "Driver": "kvm2", "CPUs": 4, "Memory": 4096}`
+
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:     "minikube",
+		Product:  string(ProductMinikube),
+		MinCPUs:  4,
+		Minikube: &api.MinikubeCluster{Memory: "4g"},
+	}, &api.Cluster{})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"minikube", "stop", "-p", "minikube"},
+		{"minikube", "start", "-p", "minikube", "--cpus=4", "--memory=4g"},
+	}, f.calls)
+}
+
+func TestMinikubeMachineResizeIgnored(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 2, "Memory": 2048}`)
+
+	// minikube started the profile, but kept the old size.
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:    "minikube",
+		Product: string(ProductMinikube),
+		MinCPUs: 4,
+	}, &api.Cluster{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "minikube did not resize cluster minikube (still needs --cpus=4)")
+	}
+	assert.Equal(t, 2, len(f.calls))
+}
+
+func TestMinikubeMachineResizeNoop(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 4, "Memory": 4096}`)
+
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:     "minikube",
+		Product:  string(ProductMinikube),
+		MinCPUs:  2,
+		Minikube: &api.MinikubeCluster{Memory: "4096mb"},
+	}, &api.Cluster{})
+	assert.NoError(t, err)
+	assert.Empty(t, f.calls)
+}
+
+func TestMinikubeMachineResizeMinMemory(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 2, "Memory": 2048, "DiskSize": 20000}`)
+	f.startedProfile = `{"Name": "minikube", "Driver": "kvm2", "CPUs": 2, "Memory": 4096, "DiskSize": 20000}`
+
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:      "minikube",
+		Product:   string(ProductMinikube),
+		MinMemory: "4Gi",
+	}, &api.Cluster{})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"minikube", "stop", "-p", "minikube"},
+		{"minikube", "start", "-p", "minikube", "--memory=4096mb"},
+	}, f.calls)
+
+	memory, err := f.m.Memory(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4096*mebibyte), memory)
+}
+
+func TestMinikubeMachineResizeMinDisk(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "kvm2", "CPUs": 2, "Memory": 2048, "DiskSize": 20000}`)
+
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:    "minikube",
+		Product: string(ProductMinikube),
+		MinDisk: "40Gi",
+	}, &api.Cluster{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Cannot automatically set minimum disk to 40Gi on an existing minikube cluster")
+	}
+	assert.Empty(t, f.calls)
+}
+
+func TestMinikubeMachineResizeBareMetal(t *testing.T) {
+	f := newMinikubeMachineFixture(t, "docker")
+	f.writeProfile(`{"Name": "minikube", "Driver": "none", "CPUs": 2}`)
+
+	err := f.m.Restart(context.Background(), &api.Cluster{
+		Name:    "minikube",
+		Product: string(ProductMinikube),
+		MinCPUs: 4,
+	}, &api.Cluster{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Cannot automatically set minimum CPU to 4 on minikube driver none")
+	}
+}
+
+func TestValidateMinikubeRegistry(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		cluster *api.Cluster
+		err     string
+	}{
+		{"defaults", &api.Cluster{
+			Product:  "minikube",
+			Registry: "ctlptl-registry",
+			Minikube: &api.MinikubeCluster{},
+		}, ""},
+		{"no registry", &api.Cluster{
+			Product:  "minikube",
+			Minikube: &api.MinikubeCluster{ContainerRuntime: "docker", Driver: "hyperkit"},
+		}, ""},
+		{"docker runtime", &api.Cluster{
+			Product:  "minikube",
+			Registry: "ctlptl-registry",
+			Minikube: &api.MinikubeCluster{ContainerRuntime: "docker"},
+		}, "minikube: a registry requires containerRuntime: containerd. Actual: docker"},
+		{"vm driver", &api.Cluster{
+			Product:  "minikube",
+			Registry: "ctlptl-registry",
+			Minikube: &api.MinikubeCluster{Driver: "hyperkit"},
+		}, "minikube: a registry requires a container driver (docker or podman). Actual: hyperkit"},
+		{"mirrors", &api.Cluster{
+			Product:         "minikube",
+			RegistryMirrors: map[string]string{"docker.io": "ctlptl-dockerhub-cache"},
+			Minikube:        &api.MinikubeCluster{Driver: "virtualbox"},
+		}, "minikube: a registry requires a container driver (docker or podman). Actual: virtualbox"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMinikubeRegistry(tc.cluster)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tc.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestMinikubeMemoryMB(t *testing.T) {
+	table := []struct {
+		input    string
+		expected int
+	}{
+		{"4096", 4096},
+		{"4096mb", 4096},
+		{"4g", 4096},
+		{"2.5GB", 2560},
+		{"max", 0},
+	}
+	for _, tt := range table {
+		t.Run(tt.input, func(t *testing.T) {
+			actual, err := minikubeMemoryMB(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	_, err := minikubeMemoryMB("lots")
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
+)
+
+// A local registry that caches images from a remote registry.
+type registryMirror struct {
+	// The remote registry host that the nodes pull from, e.g., docker.io
+	host string
+
+	registry *api.Registry
+}
+
+// The URL that the nodes use to reach the mirror.
+func (m registryMirror) endpoint() string {
+	return fmt.Sprintf("http://%s:%d", m.registry.Name, m.registry.Status.ContainerPort)
+}
+
+// An admin that can configure the container runtime on each node
+// to pull images through local registry mirrors.
+type registryMirrorAdmin interface {
+	// Creates the cluster like Create, with the mirrors configured.
+	CreateWithMirrors(ctx context.Context, desired *api.Cluster, registry *api.Registry, mirrors []registryMirror) error
+}
+
+// An admin whose nodes can lose their registry config when the cluster
+// restarts, e.g., because `minikube start` rewrites the containerd config.
+type registryConfigRestorer interface {
+	RestoreRegistryConfig(ctx context.Context, cluster *api.Cluster, registry *api.Registry, mirrors []registryMirror) error
+}
+
+// A registry host, with an optional port, e.g., docker.io or localhost:5000
+var registryHostRegexp = regexp.MustCompile(fmt.Sprintf("^%s$", reference.DomainRegexp))
+
+func supportsRegistryMirrors(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D
+}
+
+// The URL of the registry API for a registry host.
+//
+// Docker Hub serves its API from a different host than its images' names.
+func registryMirrorRemoteURL(host string) string {
+	if host == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return fmt.Sprintf("https://%s", host)
+}
+
+func validateRegistryMirrors(desired *api.Cluster) error {
+	if len(desired.RegistryMirrors) == 0 {
+		return nil
+	}
+
+	if !supportsRegistryMirrors(Product(desired.Product)) {
+		return fmt.Errorf("product %s does not support registry mirrors", desired.Product)
+	}
+
+	mirrorHosts := make(map[string]string, len(desired.RegistryMirrors))
+	for _, host := range registryMirrorHosts(desired) {
+		name := desired.RegistryMirrors[host]
+		if !registryHostRegexp.MatchString(host) {
+			return fmt.Errorf("registryMirrors: %q must be a registry host, like docker.io", host)
+		}
+		if name == "" {
+			return fmt.Errorf("registryMirrors: %s must have a registry name", host)
+		}
+		if name == desired.Registry {
+			return fmt.Errorf("registryMirrors: %s can't use the cluster registry %s as a mirror", host, name)
+		}
+
+		// A pull-through cache can only mirror one remote.
+		otherHost, ok := mirrorHosts[name]
+		if ok {
+			return fmt.Errorf("registryMirrors: %s and %s can't share the mirror %s", otherHost, host, name)
+		}
+		mirrorHosts[name] = host
+	}
+	return nil
+}
+
+func registryMirrorHosts(cluster *api.Cluster) []string {
+	hosts := make([]string, 0, len(cluster.RegistryMirrors))
+	for host := range cluster.RegistryMirrors {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Checks if a pull-through cache exists for each mirror,
+// and creates the ones that don't.
+func (c *Controller) ensureRegistryMirrorsExist(ctx context.Context, desired *api.Cluster) ([]registryMirror, error) {
+	if len(desired.RegistryMirrors) == 0 {
+		return nil, nil
+	}
+
+	regCtl, err := c.registryController(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []registryMirror
+	for _, host := range registryMirrorHosts(desired) {
+		reg, err := regCtl.Apply(ctx, &api.Registry{
+			TypeMeta:       registry.TypeMeta(),
+			Name:           desired.RegistryMirrors[host],
+			ProxyRemoteURL: registryMirrorRemoteURL(host),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating mirror for %s: %v", host, err)
+		}
+		result = append(result, registryMirror{host: host, registry: reg})
+	}
+	return result, nil
+}
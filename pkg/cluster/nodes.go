@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func supportsNodes(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D
+}
+
+// The number of control planes and workers, with defaults filled in.
+func nodeCounts(nodes *api.ClusterNodes) (controlPlanes int, workers int) {
+	if nodes == nil {
+		return 1, 0
+	}
+	controlPlanes = nodes.ControlPlanes
+	if controlPlanes == 0 {
+		controlPlanes = 1
+	}
+	return controlPlanes, nodes.Workers
+}
+
+func nodesString(nodes *api.ClusterNodes) string {
+	controlPlanes, workers := nodeCounts(nodes)
+	return fmt.Sprintf("controlPlanes: %d, workers: %d", controlPlanes, workers)
+}
+
+// Checks that the node topology makes sense for the product, and doesn't
+// conflict with the product-specific config.
+func validateNodes(desired *api.Cluster) error {
+	nodes := desired.Nodes
+	if nodes == nil {
+		return nil
+	}
+
+	product := Product(desired.Product)
+	if !supportsNodes(product) {
+		return fmt.Errorf("product %s does not support nodes", desired.Product)
+	}
+	if nodes.ControlPlanes < 0 || nodes.Workers < 0 {
+		return fmt.Errorf("nodes must not be negative. Actual: %s", nodesString(nodes))
+	}
+
+	switch product {
+	case ProductKIND:
+		if desired.KindV1Alpha4Cluster != nil && len(desired.KindV1Alpha4Cluster.Nodes) > 0 {
+			return fmt.Errorf("nodes may not be set together with kindV1Alpha4Cluster.nodes")
+		}
+	case ProductK3D:
+		if desired.K3DV1Alpha3Simple != nil &&
+			(desired.K3DV1Alpha3Simple.Servers != 0 || desired.K3DV1Alpha3Simple.Agents != 0) {
+			return fmt.Errorf("nodes may not be set together with k3dV1Alpha3Simple.servers or k3dV1Alpha3Simple.agents")
+		}
+	case ProductMinikube:
+		controlPlanes, _ := nodeCounts(nodes)
+		if controlPlanes != 1 {
+			return fmt.Errorf("minikube clusters only support 1 control plane. Actual: %d", controlPlanes)
+		}
+	}
+	return nil
+}
+
+// Whether the existing cluster has the desired nodes.
+//
+// If the existing cluster wasn't created with a node topology,
+// compare against the number of nodes we observed.
+func nodesMatch(desired, existing *api.Cluster) bool {
+	if desired.Nodes == nil {
+		return true
+	}
+
+	desiredControlPlanes, desiredWorkers := nodeCounts(desired.Nodes)
+	if existing.Nodes != nil {
+		existingControlPlanes, existingWorkers := nodeCounts(existing.Nodes)
+		return desiredControlPlanes == existingControlPlanes && desiredWorkers == existingWorkers
+	}
+
+	if existing.Status.Nodes == 0 {
+		// We couldn't count the nodes, so assume the best.
+		return true
+	}
+	return desiredControlPlanes+desiredWorkers == existing.Status.Nodes
+}
@@ -7,6 +7,7 @@ import (
 
 type ListOptions struct {
 	FieldSelector string
+	LabelSelector string
 }
 
 type clusterFields api.Cluster
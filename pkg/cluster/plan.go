@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
+)
+
+// The parts of the cluster spec that Apply updates in place.
+type clusterMetadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	TTL         string
+}
+
+func metadataOf(cluster *api.Cluster) clusterMetadata {
+	return clusterMetadata{
+		Labels:      cluster.Labels,
+		Annotations: cluster.Annotations,
+		TTL:         cluster.TTL,
+	}
+}
+
+// A name for the machine that restarts to resize a cluster, for messages.
+func machineName(product Product) string {
+	switch product {
+	case ProductDockerDesktop:
+		return "Docker Desktop"
+	case ProductRancherDesktop:
+		return "Rancher Desktop"
+	case ProductMinikube, ProductMicroK8s:
+		return string(product)
+	}
+	return "the container engine"
+}
+
+// Compare the desired cluster against the existing cluster, and describe
+// what Apply would do to reconcile the two, without doing it.
+func (c *Controller) Plan(ctx context.Context, desired *api.Cluster) ([]plan.Action, error) {
+	err := validateCluster(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	minMemory, err := minMemoryBytes(desired)
+	if err != nil {
+		return nil, err
+	}
+	minDisk, err := minDiskBytes(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	FillDefaults(desired)
+
+	err = c.useEngine(desired.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerClient, err := c.getDockerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsEngine(Product(desired.Product), dockerClient.Engine()) {
+		return nil, fmt.Errorf("product %s does not support container engine %s", desired.Product, dockerClient.Engine())
+	}
+
+	existing, err := c.Get(ctx, desired.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if existing == nil {
+		existing = &api.Cluster{}
+	}
+
+	var actions []plan.Action
+	diffs := c.irreconcilableDifferences(ctx, desired, existing)
+	for _, diff := range diffs {
+		actions = append(actions, plan.Action{Description: "recreate cluster because " + diff.Message, Diff: diff.Diff})
+	}
+	if len(diffs) > 0 {
+		existing = &api.Cluster{}
+	}
+
+	if !existing.Status.CreationTimestamp.Time.IsZero() {
+		// Get doesn't fail when it can't read the machine. If the cluster
+		// needs minimum resources, read them from the same machine that
+		// Apply would resize, so we don't plan a restart for a machine
+		// we couldn't read.
+		if desired.MinCPUs > 0 || minMemory > 0 || minDisk > 0 {
+			err := c.populateMachineStatus(ctx, existing, desired)
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading %s resources", machineName(Product(desired.Product)))
+			}
+		}
+
+		reason := restartReason(desired, existing, minMemory, minDisk)
+		if reason != "" {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("restart %s %s", machineName(Product(desired.Product)), reason),
+			})
+		}
+	}
+
+	registryActions, err := c.planRegistries(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, registryActions...)
+
+	needsCreate := existing.Status.CreationTimestamp.Time.IsZero() ||
+		desired.Name != existing.Name ||
+		desired.Product != existing.Product
+	appliedAddons := existing.Addons
+	if needsCreate {
+		actions = append(actions, plan.Action{Description: "create cluster"})
+		if len(desired.PreloadImages) > 0 {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("preload %d images", len(desired.PreloadImages)),
+			})
+		}
+		appliedAddons = nil
+	} else {
+		if c.needsK8sUpgrade(ctx, desired, existing) {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("upgrade Kubernetes from %s to %s",
+					existing.Status.KubernetesVersion, desired.KubernetesVersion),
+			})
+		}
+		actions = append(actions, planRegistryAttach(desired, existing)...)
+		if metadataChanged(desired, existing) {
+			updated := existing.DeepCopy()
+			updateMetadata(desired, updated)
+			actions = append(actions, plan.Action{
+				Description: "update cluster labels, annotations, and ttl",
+				Diff:        cmp.Diff(metadataOf(existing), metadataOf(updated)),
+			})
+		}
+	}
+
+	if desired.Ingress != nil && (needsCreate || existing.Ingress == nil) {
+		actions = append(actions, plan.Action{
+			Description: fmt.Sprintf("install %s ingress controller", desired.Ingress.Controller),
+		})
+	}
+
+	for _, addon := range newAddons(desired.Addons, appliedAddons) {
+		actions = append(actions, plan.Action{Description: fmt.Sprintf("apply addon %s", addon)})
+	}
+	return actions, nil
+}
+
+// Describes the registries that Apply would create for the cluster.
+func (c *Controller) planRegistries(ctx context.Context, desired *api.Cluster) ([]plan.Action, error) {
+	name := desiredRegistryName(desired)
+	if name == "" && len(desired.RegistryMirrors) == 0 {
+		return nil, nil
+	}
+
+	regCtl, err := c.registryController(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := regCtl.List(ctx, registry.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]api.Registry, len(list.Items))
+	for _, item := range list.Items {
+		existing[item.Name] = item
+	}
+
+	var actions []plan.Action
+	if name != "" {
+		_, ok := existing[name]
+		if !ok {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("create registry %s", name),
+			})
+		}
+	}
+
+	for _, host := range registryMirrorHosts(desired) {
+		mirror := desired.RegistryMirrors[host]
+		remoteURL := registryMirrorRemoteURL(host)
+		reg, ok := existing[mirror]
+		if !ok {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("create registry %s as a pull-through cache for %s", mirror, host),
+			})
+		} else if reg.ProxyRemoteURL == "" {
+			// Apply won't replace a plain registry, because that would lose its images.
+			return nil, fmt.Errorf("registry %s already exists and is not a pull-through cache. "+
+				"Delete it, or choose another name for the %s mirror", mirror, host)
+		} else if reg.ProxyRemoteURL != remoteURL {
+			actions = append(actions, plan.Action{
+				Description: fmt.Sprintf("recreate registry %s as a pull-through cache for %s", mirror, host),
+			})
+		}
+	}
+	return actions, nil
+}
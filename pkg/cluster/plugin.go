@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/tilt-dev/localregistry-go"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	cexec "github.com/tilt-dev/ctlptl/internal/exec"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+// Products that ctlptl doesn't know how to set up itself can be
+// provided by a plugin: an executable named ctlptl-admin-<product> on PATH.
+//
+// ctlptl invokes the plugin with a single argument naming the method
+// (e.g., `ctlptl-admin-k0s create`), writes a JSON pluginRequest on stdin,
+// and reads a JSON pluginResponse from stdout. Anything the plugin writes
+// to stderr is shown to the user.
+//
+// Methods:
+//
+// ensure-installed: cluster. Fails if the product isn't installed.
+// ensure-exists: cluster. Starts the machine the cluster runs on.
+// cpus: cluster. Responds with cpus.
+// memory: cluster. Responds with memory, in bytes.
+// disk: cluster. Responds with disk, in bytes.
+// restart: cluster, existing. Reconfigures the machine (e.g., to add CPUs).
+// create: cluster, registry (optional). Creates the cluster and its kubeconfig context.
+// local-registry-hosting: cluster, registry. Responds with localRegistryHosting.
+// delete: cluster. Deletes the cluster.
+//
+// A plugin reports a failure by exiting non-zero, and may explain it
+// with the error field of the response.
+const pluginPrefix = "ctlptl-admin-"
+
+const (
+	pluginMethodEnsureInstalled      = "ensure-installed"
+	pluginMethodEnsureExists         = "ensure-exists"
+	pluginMethodCPUs                 = "cpus"
+	pluginMethodMemory               = "memory"
+	pluginMethodDisk                 = "disk"
+	pluginMethodRestart              = "restart"
+	pluginMethodCreate               = "create"
+	pluginMethodLocalRegistryHosting = "local-registry-hosting"
+	pluginMethodDelete               = "delete"
+)
+
+type pluginRequest struct {
+	Cluster  *api.Cluster  `json:"cluster,omitempty"`
+	Existing *api.Cluster  `json:"existing,omitempty"`
+	Registry *api.Registry `json:"registry,omitempty"`
+}
+
+type pluginResponse struct {
+	CPUs                 int                                   `json:"cpus,omitempty"`
+	Memory               int64                                 `json:"memory,omitempty"`
+	Disk                 int64                                 `json:"disk,omitempty"`
+	LocalRegistryHosting *localregistry.LocalRegistryHostingV1 `json:"localRegistryHosting,omitempty"`
+	Error                string                                `json:"error,omitempty"`
+}
+
+// Products with an admin built into ctlptl. We never look
+// for plugins for these.
+func hasBuiltinAdmin(product Product) bool {
+	return product == ProductDockerDesktop ||
+		product == ProductKIND ||
+		product == ProductMinikube ||
+		product == ProductK3D ||
+		product == ProductMicroK8s ||
+		product == ProductRancherDesktop
+}
+
+// Finds the plugin executable for the product.
+func findPlugin(product Product) (string, bool) {
+	if product == "" || product == ProductUnknown || hasBuiltinAdmin(product) ||
+		strings.ContainsAny(string(product), `/\`) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + string(product))
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+func isPluginProduct(product Product) bool {
+	_, ok := findPlugin(product)
+	return ok
+}
+
+type pluginClient struct {
+	path   string
+	runner cexec.CmdRunner
+	errOut io.Writer
+}
+
+func newPluginClient(path string, runner cexec.CmdRunner, errOut io.Writer) pluginClient {
+	return pluginClient{path: path, runner: runner, errOut: errOut}
+}
+
+func (p pluginClient) call(ctx context.Context, method string, req pluginRequest) (pluginResponse, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+
+	out := bytes.NewBuffer(nil)
+	runErr := p.runner.RunIO(ctx,
+		genericclioptions.IOStreams{In: bytes.NewReader(in), Out: out, ErrOut: p.errOut},
+		p.path, method)
+
+	resp := pluginResponse{}
+	var decodeErr error
+	if len(bytes.TrimSpace(out.Bytes())) > 0 {
+		decodeErr = json.Unmarshal(out.Bytes(), &resp)
+	}
+
+	if resp.Error != "" {
+		return pluginResponse{}, fmt.Errorf("%s %s: %s", p.path, method, resp.Error)
+	}
+	if runErr != nil {
+		return pluginResponse{}, fmt.Errorf("%s %s: %v", p.path, method, runErr)
+	}
+	if decodeErr != nil {
+		return pluginResponse{}, fmt.Errorf("%s %s: decoding response: %v", p.path, method, decodeErr)
+	}
+	return resp, nil
+}
+
+// An Admin that delegates to a plugin.
+type pluginAdmin struct {
+	client pluginClient
+}
+
+func newPluginAdmin(client pluginClient) *pluginAdmin {
+	return &pluginAdmin{client: client}
+}
+
+func (a *pluginAdmin) EnsureInstalled(ctx context.Context) error {
+	_, err := a.client.call(ctx, pluginMethodEnsureInstalled, pluginRequest{})
+	return err
+}
+
+func (a *pluginAdmin) Create(ctx context.Context, desired *api.Cluster, registry *api.Registry) error {
+	_, err := a.client.call(ctx, pluginMethodCreate, pluginRequest{Cluster: desired, Registry: registry})
+	return err
+}
+
+func (a *pluginAdmin) LocalRegistryHosting(ctx context.Context, desired *api.Cluster, registry *api.Registry) (*localregistry.LocalRegistryHostingV1, error) {
+	resp, err := a.client.call(ctx, pluginMethodLocalRegistryHosting, pluginRequest{Cluster: desired, Registry: registry})
+	if err != nil {
+		return nil, err
+	}
+	return resp.LocalRegistryHosting, nil
+}
+
+func (a *pluginAdmin) Delete(ctx context.Context, config *api.Cluster) error {
+	_, err := a.client.call(ctx, pluginMethodDelete, pluginRequest{Cluster: config})
+	return err
+}
+
+// A Machine that delegates to a plugin.
+type pluginMachine struct {
+	client  pluginClient
+	cluster *api.Cluster
+}
+
+func newPluginMachine(client pluginClient, cluster *api.Cluster) *pluginMachine {
+	return &pluginMachine{client: client, cluster: cluster}
+}
+
+func (m *pluginMachine) CPUs(ctx context.Context) (int, error) {
+	resp, err := m.client.call(ctx, pluginMethodCPUs, pluginRequest{Cluster: m.cluster})
+	if err != nil {
+		return 0, err
+	}
+	return resp.CPUs, nil
+}
+
+func (m *pluginMachine) Memory(ctx context.Context) (int64, error) {
+	resp, err := m.client.call(ctx, pluginMethodMemory, pluginRequest{Cluster: m.cluster})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Memory, nil
+}
+
+func (m *pluginMachine) Disk(ctx context.Context) (int64, error) {
+	resp, err := m.client.call(ctx, pluginMethodDisk, pluginRequest{Cluster: m.cluster})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Disk, nil
+}
+
+func (m *pluginMachine) EnsureExists(ctx context.Context) error {
+	_, err := m.client.call(ctx, pluginMethodEnsureExists, pluginRequest{Cluster: m.cluster})
+	return err
+}
+
+func (m *pluginMachine) Restart(ctx context.Context, desired, existing *api.Cluster) error {
+	_, err := m.client.call(ctx, pluginMethodRestart, pluginRequest{Cluster: desired, Existing: existing})
+	return err
+}
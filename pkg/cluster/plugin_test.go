@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+// A fake plugin that replies to each method with a canned response.
+type fakePluginRunner struct {
+	calls     [][]string
+	requests  []string
+	responses map[string]string
+	fail      map[string]bool
+}
+
+func (r *fakePluginRunner) Run(ctx context.Context, cmd string, args ...string) error {
+	return r.RunIO(ctx, genericclioptions.IOStreams{}, cmd, args...)
+}
+
+func (r *fakePluginRunner) RunIO(ctx context.Context, iostreams genericclioptions.IOStreams, cmd string, args ...string) error {
+	r.calls = append(r.calls, append([]string{cmd}, args...))
+	if iostreams.In != nil {
+		in, err := ioutil.ReadAll(iostreams.In)
+		if err != nil {
+			return err
+		}
+		r.requests = append(r.requests, string(in))
+	}
+
+	method := args[0]
+	if iostreams.Out != nil {
+		_, _ = fmt.Fprint(iostreams.Out, r.responses[method])
+	}
+	if r.fail[method] {
+		return fmt.Errorf("exit status 1")
+	}
+	return nil
+}
+
+func newFakePluginClient(r *fakePluginRunner) pluginClient {
+	return newPluginClient("ctlptl-admin-fake", r, bytes.NewBuffer(nil))
+}
+
+func TestPluginAdminCreate(t *testing.T) {
+	r := &fakePluginRunner{}
+	a := newPluginAdmin(newFakePluginClient(r))
+
+	err := a.Create(context.Background(), &api.Cluster{Name: "fake-1", Product: "fake"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ctlptl-admin-fake", "create"}}, r.calls)
+	assert.Contains(t, r.requests[0], `"cluster":{"name":"fake-1","product":"fake",`)
+}
+
+func TestPluginAdminLocalRegistryHosting(t *testing.T) {
+	r := &fakePluginRunner{
+		responses: map[string]string{
+			"local-registry-hosting": `{"localRegistryHosting": {"host": "localhost:5000", "hostFromClusterNetwork": "registry:5000"}}`,
+		},
+	}
+	a := newPluginAdmin(newFakePluginClient(r))
+
+	hosting, err := a.LocalRegistryHosting(context.Background(),
+		&api.Cluster{Name: "fake-1", Product: "fake"},
+		&api.Registry{Name: "registry"})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000", hosting.Host)
+	assert.Equal(t, "registry:5000", hosting.HostFromClusterNetwork)
+	assert.Contains(t, r.requests[0], `"registry":{"name":"registry"`)
+}
+
+func TestPluginAdminError(t *testing.T) {
+	r := &fakePluginRunner{
+		responses: map[string]string{"ensure-installed": `{"error": "fake is not installed"}`},
+		fail:      map[string]bool{"ensure-installed": true},
+	}
+	a := newPluginAdmin(newFakePluginClient(r))
+
+	err := a.EnsureInstalled(context.Background())
+	if assert.Error(t, err) {
+		assert.Equal(t, "ctlptl-admin-fake ensure-installed: fake is not installed", err.Error())
+	}
+}
+
+func TestPluginAdminExitError(t *testing.T) {
+	r := &fakePluginRunner{fail: map[string]bool{"delete": true}}
+	a := newPluginAdmin(newFakePluginClient(r))
+
+	err := a.Delete(context.Background(), &api.Cluster{Name: "fake-1", Product: "fake"})
+	if assert.Error(t, err) {
+		assert.Equal(t, "ctlptl-admin-fake delete: exit status 1", err.Error())
+	}
+}
+
+func TestPluginMachineCPUs(t *testing.T) {
+	r := &fakePluginRunner{responses: map[string]string{"cpus": `{"cpus": 3}`}}
+	m := newPluginMachine(newFakePluginClient(r), &api.Cluster{Name: "fake-1", Product: "fake"})
+
+	cpus, err := m.CPUs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, cpus)
+}
+
+func TestPluginMachineRestart(t *testing.T) {
+	r := &fakePluginRunner{}
+	m := newPluginMachine(newFakePluginClient(r), &api.Cluster{Name: "fake-1", Product: "fake"})
+
+	err := m.Restart(context.Background(),
+		&api.Cluster{Name: "fake-1", Product: "fake", MinCPUs: 4},
+		&api.Cluster{Name: "fake-1", Product: "fake", Status: api.ClusterStatus{CPUs: 2}})
+	require.NoError(t, err)
+	assert.Contains(t, r.requests[0], `"cluster":{"name":"fake-1","product":"fake","minCPUs":4,`)
+	assert.Contains(t, r.requests[0], `"existing":{"name":"fake-1","product":"fake",`)
+}
+
+func TestFindPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ctlptl-plugin")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	oldPath := os.Getenv("PATH")
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+	require.NoError(t, os.Setenv("PATH", dir))
+
+	path := filepath.Join(dir, "ctlptl-admin-fake")
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ctlptl-admin-kind"), []byte("#!/bin/sh\n"), 0755))
+
+	found, ok := findPlugin("fake")
+	assert.True(t, ok)
+	assert.Equal(t, path, found)
+
+	_, ok = findPlugin("missing")
+	assert.False(t, ok)
+
+	// Built-in products never use plugins.
+	_, ok = findPlugin(ProductKIND)
+	assert.False(t, ok)
+}
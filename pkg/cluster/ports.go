@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
+)
+
+func supportsPortMappings(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D
+}
+
+// The protocol of the port mapping, upper-cased, with the default filled in.
+func portMappingProtocol(m api.PortMapping) string {
+	if m.Protocol == "" {
+		return "TCP"
+	}
+	return strings.ToUpper(m.Protocol)
+}
+
+func validatePortMappings(desired *api.Cluster) error {
+	if len(desired.PortMappings) == 0 {
+		return nil
+	}
+
+	if !supportsPortMappings(Product(desired.Product)) {
+		return fmt.Errorf("product %s does not support portMappings", desired.Product)
+	}
+
+	seen := make(map[string]bool, len(desired.PortMappings))
+	for _, m := range desired.PortMappings {
+		if m.HostPort < 1 || m.HostPort > 65535 {
+			return fmt.Errorf("portMappings: invalid hostPort %d", m.HostPort)
+		}
+		if m.ContainerPort < 1 || m.ContainerPort > 65535 {
+			return fmt.Errorf("portMappings: invalid containerPort %d", m.ContainerPort)
+		}
+
+		protocol := portMappingProtocol(m)
+		if protocol != "TCP" && protocol != "UDP" && protocol != "SCTP" {
+			return fmt.Errorf("portMappings: invalid protocol %q. Must be one of TCP, UDP, or SCTP", m.Protocol)
+		}
+		if m.ListenAddress != "" && net.ParseIP(m.ListenAddress) == nil {
+			return fmt.Errorf("portMappings: invalid listenAddress %q", m.ListenAddress)
+		}
+
+		key := fmt.Sprintf("%d/%s", m.HostPort, protocol)
+		if seen[key] {
+			return fmt.Errorf("portMappings: host port %s is mapped more than once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Checks that nothing on the host is listening on the mapped ports, so that
+// we can fail before we start creating the cluster.
+func checkHostPortsAvailable(mappings []api.PortMapping) error {
+	for _, m := range mappings {
+		addr := net.JoinHostPort(m.ListenAddress, strconv.Itoa(m.HostPort))
+		protocol := portMappingProtocol(m)
+
+		var err error
+		switch protocol {
+		case "TCP":
+			var l net.Listener
+			l, err = net.Listen("tcp", addr)
+			if err == nil {
+				_ = l.Close()
+			}
+		case "UDP":
+			var conn net.PacketConn
+			conn, err = net.ListenPacket("udp", addr)
+			if err == nil {
+				_ = conn.Close()
+			}
+		default:
+			// The Go standard library can't listen on SCTP,
+			// so leave it to the container engine.
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("portMappings: host port %d/%s is already in use: %v", m.HostPort, protocol, err)
+		}
+	}
+	return nil
+}
+
+// Formats the port mapping for `docker run -p`, which k3d and minikube use:
+//
+// [listenAddress:]hostPort:containerPort/protocol
+func dockerPortSpec(m api.PortMapping) string {
+	spec := fmt.Sprintf("%d:%d/%s", m.HostPort, m.ContainerPort, strings.ToLower(portMappingProtocol(m)))
+	if m.ListenAddress != "" {
+		spec = fmt.Sprintf("%s:%s", m.ListenAddress, spec)
+	}
+	return spec
+}
+
+func kindPortMappings(mappings []api.PortMapping) []v1alpha4.PortMapping {
+	result := make([]v1alpha4.PortMapping, 0, len(mappings))
+	for _, m := range mappings {
+		result = append(result, v1alpha4.PortMapping{
+			HostPort:      int32(m.HostPort),
+			ContainerPort: int32(m.ContainerPort),
+			ListenAddress: m.ListenAddress,
+			Protocol:      v1alpha4.PortMappingProtocol(portMappingProtocol(m)),
+		})
+	}
+	return result
+}
+
+// On k3d, we map the ports on the load balancer, which forwards
+// to all the servers and agents.
+func k3dPortMappings(mappings []api.PortMapping) []k3dv1alpha3.PortWithNodeFilters {
+	result := make([]k3dv1alpha3.PortWithNodeFilters, 0, len(mappings))
+	for _, m := range mappings {
+		result = append(result, k3dv1alpha3.PortWithNodeFilters{
+			Port:        dockerPortSpec(m),
+			NodeFilters: []string{"loadbalancer"},
+		})
+	}
+	return result
+}
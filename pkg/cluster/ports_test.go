@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/api/k3dv1alpha3"
+)
+
+func TestValidatePortMappings(t *testing.T) {
+	table := []struct {
+		name     string
+		product  Product
+		mappings []api.PortMapping
+		expected string
+	}{
+		{"ok", ProductKIND, []api.PortMapping{{HostPort: 8080, ContainerPort: 80}}, ""},
+		{"udp", ProductK3D, []api.PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "udp"}}, ""},
+		{"unsupported product", ProductDockerDesktop, []api.PortMapping{{HostPort: 8080, ContainerPort: 80}},
+			"product docker-desktop does not support portMappings"},
+		{"bad host port", ProductKIND, []api.PortMapping{{HostPort: 0, ContainerPort: 80}},
+			"portMappings: invalid hostPort 0"},
+		{"bad container port", ProductKIND, []api.PortMapping{{HostPort: 8080, ContainerPort: 70000}},
+			"portMappings: invalid containerPort 70000"},
+		{"bad protocol", ProductKIND, []api.PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "http"}},
+			`portMappings: invalid protocol "http"`},
+		{"bad listen address", ProductKIND, []api.PortMapping{{HostPort: 8080, ContainerPort: 80, ListenAddress: "localhost"}},
+			`portMappings: invalid listenAddress "localhost"`},
+		{"duplicate", ProductMinikube, []api.PortMapping{
+			{HostPort: 8080, ContainerPort: 80},
+			{HostPort: 8080, ContainerPort: 443, Protocol: "TCP"},
+		}, "portMappings: host port 8080/TCP is mapped more than once"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePortMappings(&api.Cluster{Product: string(tt.product), PortMappings: tt.mappings})
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckHostPortsAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	err = checkHostPortsAvailable([]api.PortMapping{{HostPort: port, ContainerPort: 80, ListenAddress: "127.0.0.1"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is already in use")
+	}
+
+	// The same port on UDP is free.
+	err = checkHostPortsAvailable([]api.PortMapping{{HostPort: port, ContainerPort: 80, ListenAddress: "127.0.0.1", Protocol: "UDP"}})
+	assert.NoError(t, err)
+}
+
+func TestDockerPortSpec(t *testing.T) {
+	assert.Equal(t, "8080:80/tcp", dockerPortSpec(api.PortMapping{HostPort: 8080, ContainerPort: 80}))
+	assert.Equal(t, "127.0.0.1:5353:53/udp",
+		dockerPortSpec(api.PortMapping{HostPort: 5353, ContainerPort: 53, Protocol: "UDP", ListenAddress: "127.0.0.1"}))
+}
+
+func TestKindPortMappings(t *testing.T) {
+	a := &kindAdmin{}
+	config := a.kindClusterConfig(&api.Cluster{
+		Name:         "kind-kind",
+		Nodes:        &api.ClusterNodes{Workers: 1},
+		PortMappings: []api.PortMapping{{HostPort: 8080, ContainerPort: 80}},
+	}, nil, nil)
+	assert.Equal(t, []v1alpha4.Node{
+		{
+			Role: v1alpha4.ControlPlaneRole,
+			ExtraPortMappings: []v1alpha4.PortMapping{
+				{HostPort: 8080, ContainerPort: 80, Protocol: v1alpha4.PortMappingProtocolTCP},
+			},
+		},
+		{Role: v1alpha4.WorkerRole},
+	}, config.Nodes)
+}
+
+func TestK3dPortMappings(t *testing.T) {
+	assert.Equal(t, []k3dv1alpha3.PortWithNodeFilters{
+		{Port: "8080:80/tcp", NodeFilters: []string{"loadbalancer"}},
+	}, k3dPortMappings([]api.PortMapping{{HostPort: 8080, ContainerPort: 80}}))
+}
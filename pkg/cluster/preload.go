@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+// How many images we transfer at once.
+const preloadParallelism = 4
+
+// The registry auth header for a registry that doesn't need auth.
+//
+// Some versions of the Docker daemon reject pushes without the header.
+var emptyRegistryAuth = base64.URLEncoding.EncodeToString([]byte("{}"))
+
+// Products where we can load images onto the nodes without a registry.
+//
+// On Docker Desktop, the cluster shares the host's container engine,
+// so pulling the image is enough.
+func supportsImageLoad(product Product) bool {
+	return product == ProductKIND || product == ProductMinikube || product == ProductK3D ||
+		product == ProductDockerDesktop
+}
+
+func validatePreloadImages(desired *api.Cluster) error {
+	if len(desired.PreloadImages) == 0 {
+		return nil
+	}
+
+	if desiredRegistryName(desired) == "" && !supportsImageLoad(Product(desired.Product)) {
+		return fmt.Errorf("product %s can't load images without a registry. "+
+			"Set a registry to preload images into it", desired.Product)
+	}
+
+	for _, image := range desired.PreloadImages {
+		named, err := reference.ParseNormalizedNamed(image)
+		if err != nil {
+			return fmt.Errorf("preloadImages: invalid image %q: %v", image, err)
+		}
+
+		// Pushing an image gives it a new manifest, so the digest
+		// wouldn't match in the registry or on the nodes.
+		_, isDigested := named.(reference.Digested)
+		if isDigested {
+			return fmt.Errorf("preloadImages: image %q must use a tag, not a digest", image)
+		}
+	}
+	return nil
+}
+
+// The image name in the local registry.
+//
+// e.g., nginx:1.21 -> localhost:5000/library/nginx:1.21
+func registryImageRef(host, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	tag := "latest"
+	tagged, ok := named.(reference.Tagged)
+	if ok {
+		tag = tagged.Tag()
+	}
+	return fmt.Sprintf("%s/%s:%s", host, reference.Path(named), tag), nil
+}
+
+// Reads a progress stream from the Docker API until it's done,
+// and returns any error reported in the stream.
+func readDockerStream(rc io.ReadCloser) error {
+	defer rc.Close()
+	decoder := json.NewDecoder(rc)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
+
+// Pulls the image into the host's container engine, unless it's already there.
+//
+// Images that are already present may be private images that the user pulled
+// with their own credentials, so we don't try to pull them again.
+func pullImage(ctx context.Context, dockerClient dockerClient, image string) error {
+	_, _, err := dockerClient.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	rc, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	return readDockerStream(rc)
+}
+
+func pushImage(ctx context.Context, dockerClient dockerClient, image, target string) error {
+	err := dockerClient.ImageTag(ctx, image, target)
+	if err != nil {
+		return err
+	}
+
+	rc, err := dockerClient.ImagePush(ctx, target, types.ImagePushOptions{RegistryAuth: emptyRegistryAuth})
+	if err != nil {
+		return err
+	}
+	return readDockerStream(rc)
+}
+
+// Pulls the images, then copies them into the cluster, with a few
+// transfers at a time.
+func (c *Controller) preloadImages(ctx context.Context, admin Admin, cluster *api.Cluster, reg *api.Registry) error {
+	dockerClient, err := c.getDockerClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var transfer func(ctx context.Context, image string) error
+	if reg != nil {
+		host := fmt.Sprintf("localhost:%d", reg.Status.HostPort)
+		transfer = func(ctx context.Context, image string) error {
+			target, err := registryImageRef(host, image)
+			if err != nil {
+				return err
+			}
+			return pushImage(ctx, dockerClient, image, target)
+		}
+	} else if loader, ok := admin.(imageLoader); ok {
+		transfer = func(ctx context.Context, image string) error {
+			return loader.LoadImage(ctx, cluster, image)
+		}
+	} else if Product(cluster.Product) == ProductDockerDesktop {
+		transfer = func(ctx context.Context, image string) error { return nil }
+	} else {
+		return fmt.Errorf("product %s can't load images without a registry", cluster.Product)
+	}
+
+	images := cluster.PreloadImages
+	_, _ = fmt.Fprintf(c.iostreams.ErrOut, " 📦 Preloading %d images into cluster %s\n", len(images), cluster.Name)
+
+	start := time.Now()
+	sem := make(chan struct{}, preloadParallelism)
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	done := 0
+	var failures []string
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := pullImage(ctx, dockerClient, image)
+			if err != nil {
+				err = fmt.Errorf("pulling: %v", err)
+			} else {
+				err = transfer(ctx, image)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", image, err))
+				_, _ = fmt.Fprintf(c.iostreams.ErrOut, "   [%d/%d] %s failed\n", done, len(images), image)
+				return
+			}
+			_, _ = fmt.Fprintf(c.iostreams.ErrOut, "   [%d/%d] %s\n", done, len(images), image)
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start).Round(100 * time.Millisecond)
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("preloading images: %d of %d failed:\n  %s",
+			len(failures), len(images), strings.Join(failures, "\n  "))
+	}
+	_, _ = fmt.Fprintf(c.iostreams.ErrOut, " 📦 Preloaded %d images into cluster %s in %s\n",
+		len(images), cluster.Name, elapsed)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func TestRegistryImageRef(t *testing.T) {
+	for _, tc := range []struct {
+		image    string
+		expected string
+	}{
+		{"nginx", "localhost:5000/library/nginx:latest"},
+		{"nginx:1.21", "localhost:5000/library/nginx:1.21"},
+		{"gcr.io/my-project/my-app:v1", "localhost:5000/my-project/my-app:v1"},
+		{"localhost:5000/my-app:dev", "localhost:5000/my-app:dev"},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := registryImageRef("localhost:5000", tc.image)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestValidatePreloadImagesDigest(t *testing.T) {
+	err := validatePreloadImages(&api.Cluster{
+		Product:       "kind",
+		PreloadImages: []string{"nginx:1.21"},
+	})
+	assert.NoError(t, err)
+
+	for _, image := range []string{
+		"nginx@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+		"nginx:1.21@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+	} {
+		err := validatePreloadImages(&api.Cluster{
+			Product:       "kind",
+			PreloadImages: []string{image},
+		})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "must use a tag, not a digest")
+		}
+	}
+}
+
+func TestReadDockerStream(t *testing.T) {
+	err := readDockerStream(ioutil.NopCloser(strings.NewReader(
+		`{"status":"Pulling from library/nginx"}
+{"status":"Downloading","progressDetail":{"current":1,"total":2}}
+`)))
+	assert.NoError(t, err)
+
+	err = readDockerStream(ioutil.NopCloser(strings.NewReader(
+		`{"status":"Pulling from library/nginx"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`)))
+	if assert.Error(t, err) {
+		assert.Equal(t, "manifest unknown", err.Error())
+	}
+}
@@ -0,0 +1,168 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	cexec "github.com/tilt-dev/ctlptl/internal/exec"
+)
+
+// The subset of `rdctl list-settings` that we care about.
+//
+// Older versions of Rancher Desktop kept the VM settings under `kubernetes`,
+// newer versions keep them under `virtualMachine`.
+type rancherDesktopSettings struct {
+	Kubernetes struct {
+		Enabled    bool   `json:"enabled"`
+		Version    string `json:"version"`
+		NumberCPUs int    `json:"numberCPUs"`
+		MemoryInGB int    `json:"memoryInGB"`
+	} `json:"kubernetes"`
+	VirtualMachine struct {
+		NumberCPUs int `json:"numberCPUs"`
+		MemoryInGB int `json:"memoryInGB"`
+	} `json:"virtualMachine"`
+}
+
+func (s rancherDesktopSettings) cpus() int {
+	if s.VirtualMachine.NumberCPUs != 0 {
+		return s.VirtualMachine.NumberCPUs
+	}
+	return s.Kubernetes.NumberCPUs
+}
+
+func (s rancherDesktopSettings) memoryInGB() int {
+	if s.VirtualMachine.MemoryInGB != 0 {
+		return s.VirtualMachine.MemoryInGB
+	}
+	return s.Kubernetes.MemoryInGB
+}
+
+// The settings we want Rancher Desktop to converge to.
+//
+// Zero values mean "leave the current setting alone".
+type rancherDesktopTarget struct {
+	k8sEnabled  *bool
+	k8sVersion  string
+	minCPUs     int
+	minMemoryGB int
+}
+
+type rdClient interface {
+	settings(ctx context.Context) (rancherDesktopSettings, error)
+	set(ctx context.Context, args []string) error
+	start(ctx context.Context) error
+	backendState(ctx context.Context) (string, error)
+}
+
+// The state of the Rancher Desktop backend, which runs the VM and Kubernetes.
+//
+// Rancher Desktop reports STARTED once Kubernetes is up again.
+const rancherDesktopStateStarted = "STARTED"
+
+// Uses the rdctl CLI to control Rancher Desktop.
+//
+// https://docs.rancherdesktop.io/references/rdctl-command-reference
+type rancherDesktopClient struct {
+	runner cexec.CmdRunner
+}
+
+func newRancherDesktopClient(runner cexec.CmdRunner) rancherDesktopClient {
+	return rancherDesktopClient{runner: runner}
+}
+
+func (c rancherDesktopClient) settings(ctx context.Context) (rancherDesktopSettings, error) {
+	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	err := c.runner.RunIO(ctx, genericclioptions.IOStreams{Out: out, ErrOut: errOut}, "rdctl", "list-settings")
+	if err != nil {
+		return rancherDesktopSettings{}, fmt.Errorf("reading Rancher Desktop settings: %v\n%s", err, errOut.String())
+	}
+	return parseRancherDesktopSettings(out.Bytes())
+}
+
+func parseRancherDesktopSettings(data []byte) (rancherDesktopSettings, error) {
+	settings := rancherDesktopSettings{}
+	err := json.Unmarshal(data, &settings)
+	if err != nil {
+		return rancherDesktopSettings{}, errors.Wrap(err, "reading Rancher Desktop settings")
+	}
+	return settings, nil
+}
+
+func (c rancherDesktopClient) set(ctx context.Context, args []string) error {
+	errOut := bytes.NewBuffer(nil)
+	err := c.runner.RunIO(ctx, genericclioptions.IOStreams{Out: errOut, ErrOut: errOut},
+		"rdctl", append([]string{"set"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("writing Rancher Desktop settings: %v\n%s", err, errOut.String())
+	}
+	return nil
+}
+
+// Reads the backend state, e.g., STARTING, STARTED, or STOPPING.
+//
+// The settings show new values as soon as they're written,
+// so this is how we tell that Rancher Desktop has finished restarting.
+func (c rancherDesktopClient) backendState(ctx context.Context) (string, error) {
+	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	err := c.runner.RunIO(ctx, genericclioptions.IOStreams{Out: out, ErrOut: errOut},
+		"rdctl", "api", "/v1/backend_state")
+	if err != nil {
+		return "", fmt.Errorf("reading Rancher Desktop state: %v\n%s", err, errOut.String())
+	}
+	state := struct {
+		VMState string `json:"vmState"`
+	}{}
+	err = json.Unmarshal(out.Bytes(), &state)
+	if err != nil {
+		return "", errors.Wrap(err, "reading Rancher Desktop state")
+	}
+	return state.VMState, nil
+}
+
+func (c rancherDesktopClient) start(ctx context.Context) error {
+	errOut := bytes.NewBuffer(nil)
+	err := c.runner.RunIO(ctx, genericclioptions.IOStreams{Out: errOut, ErrOut: errOut}, "rdctl", "start")
+	if err != nil {
+		return fmt.Errorf("starting Rancher Desktop: %v\n%s", err, errOut.String())
+	}
+	return nil
+}
+
+// Returns the `rdctl set` arguments needed to move the current settings
+// to the target. Returns an empty list if nothing needs to change.
+func rancherDesktopSetArgs(settings rancherDesktopSettings, target rancherDesktopTarget) ([]string, error) {
+	args := []string{}
+	if target.k8sEnabled != nil && *target.k8sEnabled != settings.Kubernetes.Enabled {
+		args = append(args, fmt.Sprintf("--kubernetes.enabled=%s", strconv.FormatBool(*target.k8sEnabled)))
+	}
+
+	if target.k8sVersion != "" {
+		dv, err := semver.ParseTolerant(target.k8sVersion)
+		if err != nil {
+			return nil, fmt.Errorf("parsing kubernetesVersion: %v", err)
+		}
+		ev, err := semver.ParseTolerant(settings.Kubernetes.Version)
+		if err != nil || dv.Major != ev.Major || dv.Minor != ev.Minor || dv.Patch != ev.Patch {
+			args = append(args, fmt.Sprintf("--kubernetes.version=%d.%d.%d", dv.Major, dv.Minor, dv.Patch))
+		}
+	}
+
+	if target.minCPUs > settings.cpus() {
+		args = append(args, fmt.Sprintf("--virtual-machine.number-cpus=%d", target.minCPUs))
+	}
+
+	if target.minMemoryGB > settings.memoryInGB() {
+		args = append(args, fmt.Sprintf("--virtual-machine.memory-in-gb=%d", target.minMemoryGB))
+	}
+	return args, nil
+}
@@ -0,0 +1,205 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+const rdSettingsJSON = `{"version":5,"kubernetes":{"version":"1.21.2","enabled":true,"port":6443},"virtualMachine":{"memoryInGB":4,"numberCPUs":2}}`
+const rdSettingsOldJSON = `{"version":4,"kubernetes":{"version":"1.20.4","memoryInGB":6,"numberCPUs":4,"enabled":false}}`
+
+func TestRancherDesktopParseSettings(t *testing.T) {
+	settings, err := parseRancherDesktopSettings([]byte(rdSettingsJSON))
+	require.NoError(t, err)
+	assert.Equal(t, 2, settings.cpus())
+	assert.Equal(t, 4, settings.memoryInGB())
+	assert.Equal(t, "1.21.2", settings.Kubernetes.Version)
+	assert.True(t, settings.Kubernetes.Enabled)
+
+	settings, err = parseRancherDesktopSettings([]byte(rdSettingsOldJSON))
+	require.NoError(t, err)
+	assert.Equal(t, 4, settings.cpus())
+	assert.Equal(t, 6, settings.memoryInGB())
+	assert.False(t, settings.Kubernetes.Enabled)
+}
+
+func TestRancherDesktopSetArgs(t *testing.T) {
+	settings, err := parseRancherDesktopSettings([]byte(rdSettingsOldJSON))
+	require.NoError(t, err)
+
+	enabled := true
+	args, err := rancherDesktopSetArgs(settings, rancherDesktopTarget{
+		k8sEnabled:  &enabled,
+		k8sVersion:  "v1.21.2",
+		minCPUs:     6,
+		minMemoryGB: 8,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--kubernetes.enabled=true",
+		"--kubernetes.version=1.21.2",
+		"--virtual-machine.number-cpus=6",
+		"--virtual-machine.memory-in-gb=8",
+	}, args)
+
+	args, err = rancherDesktopSetArgs(settings, rancherDesktopTarget{
+		k8sVersion:  "v1.20.4",
+		minCPUs:     2,
+		minMemoryGB: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, args)
+}
+
+func TestRancherDesktopRestart(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsOldJSON}
+	m := newRancherDesktopMachine(rd, os.Stderr)
+	m.sleep = func(d time.Duration) {}
+
+	err := m.Restart(context.Background(), &api.Cluster{
+		Product:           string(ProductRancherDesktop),
+		KubernetesVersion: "v1.20.4",
+		MinCPUs:           4,
+	}, &api.Cluster{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"--kubernetes.enabled=true"}}, rd.sets)
+	assert.Equal(t, []string{"STARTED"}, rd.states)
+}
+
+func TestRancherDesktopRestartWaitsForBackend(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsOldJSON}
+	m := newRancherDesktopMachine(rd, os.Stderr)
+	m.sleep = func(d time.Duration) {}
+
+	// The settings change right away, but Rancher Desktop hasn't
+	// started restarting yet when we first look.
+	rd.restartStates = []string{"STARTED", "STOPPING", "STARTED"}
+	err := m.Restart(context.Background(), &api.Cluster{
+		Product: string(ProductRancherDesktop),
+	}, &api.Cluster{})
+	require.NoError(t, err)
+
+	// Restart doesn't return until the backend went down and came back.
+	assert.Equal(t, 3, rd.stateReads)
+}
+
+func TestRancherDesktopRestartNoOp(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsJSON}
+	m := newRancherDesktopMachine(rd, os.Stderr)
+
+	err := m.Restart(context.Background(), &api.Cluster{
+		Product:           string(ProductRancherDesktop),
+		KubernetesVersion: "v1.21.2",
+		MinCPUs:           1,
+	}, &api.Cluster{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(rd.sets))
+}
+
+func TestRancherDesktopRestartMemory(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsJSON}
+	m := newRancherDesktopMachine(rd, os.Stderr)
+	m.sleep = func(d time.Duration) {}
+
+	err := m.Restart(context.Background(), &api.Cluster{
+		Product:   string(ProductRancherDesktop),
+		MinMemory: "5500Mi",
+	}, &api.Cluster{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"--virtual-machine.memory-in-gb=6"}}, rd.sets)
+}
+
+func TestRancherDesktopRestartDisk(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsJSON}
+	m := newRancherDesktopMachine(rd, os.Stderr)
+
+	err := m.Restart(context.Background(), &api.Cluster{
+		Product: string(ProductRancherDesktop),
+		MinDisk: "100Gi",
+	}, &api.Cluster{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Cannot automatically set minimum disk to 100Gi on Rancher Desktop")
+	}
+}
+
+func TestRancherDesktopDelete(t *testing.T) {
+	rd := &fakeRDClient{settingsJSON: rdSettingsJSON}
+	a := newRancherDesktopAdmin(rd)
+
+	err := a.Delete(context.Background(), &api.Cluster{Name: "rancher-desktop"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"--kubernetes.enabled=false"}}, rd.sets)
+}
+
+// A fake rdctl that applies `set` to its in-memory settings.
+type fakeRDClient struct {
+	settingsJSON string
+	sets         [][]string
+	enabled      *bool
+	memoryInGB   int
+
+	// The backend states to report after a set, one per call.
+	// Stays on the last state.
+	restartStates []string
+	states        []string
+	stateReads    int
+}
+
+func (c *fakeRDClient) settings(ctx context.Context) (rancherDesktopSettings, error) {
+	settings, err := parseRancherDesktopSettings([]byte(c.settingsJSON))
+	if err != nil {
+		return rancherDesktopSettings{}, err
+	}
+	if c.enabled != nil {
+		settings.Kubernetes.Enabled = *c.enabled
+	}
+	if c.memoryInGB != 0 {
+		settings.VirtualMachine.MemoryInGB = c.memoryInGB
+	}
+	return settings, nil
+}
+
+func (c *fakeRDClient) set(ctx context.Context, args []string) error {
+	c.sets = append(c.sets, args)
+	c.states = c.restartStates
+	if c.states == nil {
+		c.states = []string{"STOPPING", "STARTING", "STARTED"}
+	}
+	for _, arg := range args {
+		switch arg {
+		case "--kubernetes.enabled=true":
+			enabled := true
+			c.enabled = &enabled
+		case "--kubernetes.enabled=false":
+			enabled := false
+			c.enabled = &enabled
+		default:
+			_, _ = fmt.Sscanf(arg, "--virtual-machine.memory-in-gb=%d", &c.memoryInGB)
+		}
+	}
+	return nil
+}
+
+func (c *fakeRDClient) start(ctx context.Context) error {
+	return nil
+}
+
+func (c *fakeRDClient) backendState(ctx context.Context) (string, error) {
+	c.stateReads++
+	if len(c.states) == 0 {
+		return "STARTED", nil
+	}
+	state := c.states[0]
+	if len(c.states) > 1 {
+		c.states = c.states[1:]
+	}
+	return state, nil
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// One way that an existing cluster differs from the desired cluster
+// that ctlptl can only fix by deleting the cluster and creating a new one.
+type Difference struct {
+	// The cluster config field that differs, e.g., kindV1Alpha4Cluster
+	Field string
+
+	// What differs, e.g., desired Kind config does not match current
+	Message string
+
+	// The config diff, in cmp.Diff format, if any.
+	Diff string
+}
+
+// Returned by Apply when the existing cluster can't be reconciled with the
+// desired cluster, and the user didn't confirm that we should recreate it.
+type IrreconcilableError struct {
+	// The cluster name.
+	Name string
+
+	Differences []Difference
+}
+
+func (e *IrreconcilableError) Error() string {
+	return e.Describe() + "Deleting the cluster loses everything in it. Re-run with --force to delete and recreate it"
+}
+
+// Lists the differences, one per line.
+func (e *IrreconcilableError) Describe() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "cluster %s must be deleted and recreated to apply this config:\n", e.Name)
+	for _, diff := range e.Differences {
+		_, _ = fmt.Fprintf(&sb, "  - %s: %s\n", diff.Field, diff.Message)
+		if diff.Diff == "" {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(diff.Diff, "\n"), "\n") {
+			_, _ = fmt.Fprintf(&sb, "    %s\n", line)
+		}
+	}
+	return sb.String()
+}
+
+// Asks whether Apply may delete an irreconcilable cluster and create a new one.
+//
+// Returns true to recreate the cluster, and false to fail with the IrreconcilableError.
+type ConfirmRecreateFunc func(ctx context.Context, irreconcilable *IrreconcilableError) (bool, error)
+
+// Always recreates irreconcilable clusters, e.g., for --force.
+func AlwaysRecreate(ctx context.Context, irreconcilable *IrreconcilableError) (bool, error) {
+	return true, nil
+}
+
+// Sets how Apply decides whether to recreate a cluster that it can't reconcile.
+//
+// By default, Apply never recreates a cluster, and returns an IrreconcilableError instead.
+func (c *Controller) SetConfirmRecreate(confirm ConfirmRecreateFunc) {
+	c.confirmRecreate = confirm
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func validateTTL(desired *api.Cluster) error {
+	if desired.TTL == "" {
+		return nil
+	}
+
+	ttl, err := time.ParseDuration(desired.TTL)
+	if err != nil {
+		return fmt.Errorf("ttl: %v", err)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl: must be positive, got %s", desired.TTL)
+	}
+	return nil
+}
+
+// When the cluster expires. Returns false if the cluster doesn't have a TTL,
+// or we don't know when it was created.
+func ExpirationTime(cluster *api.Cluster) (time.Time, bool) {
+	if cluster.TTL == "" || cluster.Status.CreationTimestamp.IsZero() {
+		return time.Time{}, false
+	}
+
+	ttl, err := time.ParseDuration(cluster.TTL)
+	if err != nil || ttl <= 0 {
+		return time.Time{}, false
+	}
+	return cluster.Status.CreationTimestamp.Add(ttl), true
+}
+
+// Whether the cluster has outlived its TTL.
+func IsExpired(cluster *api.Cluster, now time.Time) bool {
+	expiration, ok := ExpirationTime(cluster)
+	return ok && !now.Before(expiration)
+}
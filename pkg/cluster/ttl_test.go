@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+)
+
+func TestValidateTTL(t *testing.T) {
+	assert.NoError(t, validateTTL(&api.Cluster{}))
+	assert.NoError(t, validateTTL(&api.Cluster{TTL: "8h"}))
+
+	err := validateTTL(&api.Cluster{TTL: "a while"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `ttl: time: invalid duration`)
+	}
+
+	err = validateTTL(&api.Cluster{TTL: "-1h"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ttl: must be positive")
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	cluster := &api.Cluster{
+		TTL: "8h",
+		Status: api.ClusterStatus{
+			CreationTimestamp: metav1.Time{Time: created},
+		},
+	}
+
+	assert.False(t, IsExpired(cluster, created.Add(time.Hour)))
+	assert.True(t, IsExpired(cluster, created.Add(8*time.Hour)))
+
+	expiration, ok := ExpirationTime(cluster)
+	assert.True(t, ok)
+	assert.Equal(t, created.Add(8*time.Hour), expiration)
+
+	// Clusters without a TTL never expire.
+	cluster.TTL = ""
+	assert.False(t, IsExpired(cluster, created.Add(1000*time.Hour)))
+
+	// Neither do clusters that we can't date.
+	cluster.TTL = "8h"
+	cluster.Status.CreationTimestamp = metav1.Time{}
+	assert.False(t, IsExpired(cluster, created.Add(1000*time.Hour)))
+}
@@ -9,8 +9,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tilt-dev/ctlptl/pkg/api"
 	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 	"github.com/tilt-dev/ctlptl/pkg/registry"
-	"github.com/tilt-dev/ctlptl/pkg/visitor"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
@@ -20,6 +21,8 @@ type ApplyOptions struct {
 	genericclioptions.IOStreams
 
 	Filenames []string
+	DryRun    bool
+	Force     bool
 }
 
 func NewApplyOptions() *ApplyOptions {
@@ -36,7 +39,9 @@ func (o *ApplyOptions) Command() *cobra.Command {
 		Use:   "apply -f FILENAME",
 		Short: "Apply a cluster config to the currently running clusters",
 		Example: "  ctlptl apply -f cluster.yaml\n" +
-			"  cat cluster.yaml | ctlptl apply -f -",
+			"  cat cluster.yaml | ctlptl apply -f -\n" +
+			"  ctlptl apply -f cluster.yaml --dry-run\n" +
+			"  ctlptl apply -f cluster.yaml --force",
 		Run: o.Run,
 	}
 
@@ -44,6 +49,10 @@ func (o *ApplyOptions) Command() *cobra.Command {
 	cmd.SetErr(o.ErrOut)
 	o.FileNameFlags.AddFlags(cmd.Flags())
 	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun,
+		"If true, only print the changes that apply would make, without making them. Exits with code 2 if there are changes.")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force,
+		"If true, delete and recreate clusters that can't be updated in place, without asking.")
 
 	return cmd
 }
@@ -54,6 +63,18 @@ func (o *ApplyOptions) Run(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if o.DryRun {
+		hasChanges, err := o.dryRun()
+		if err != nil {
+			_, _ = fmt.Fprintf(o.ErrOut, "%v\n", err)
+			os.Exit(1)
+		}
+		if hasChanges {
+			os.Exit(exitCodeChangesPending)
+		}
+		return
+	}
+
 	err := o.run()
 	if err != nil {
 		_, _ = fmt.Fprintf(o.ErrOut, "%v\n", err)
@@ -61,6 +82,26 @@ func (o *ApplyOptions) Run(cmd *cobra.Command, args []string) {
 	}
 }
 
+// Prints the changes that apply would make, without making them.
+//
+// Returns true if there are changes to make.
+func (o *ApplyOptions) dryRun() (bool, error) {
+	a, err := newAnalytics()
+	if err != nil {
+		return false, err
+	}
+	a.Incr("cmd.apply", map[string]string{"dryRun": "true"})
+	defer a.Flush(time.Second)
+
+	objects, err := decodeObjects(o.Filenames, o.In)
+	if err != nil {
+		return false, err
+	}
+
+	diff := &DiffOptions{IOStreams: o.IOStreams}
+	return diff.printPlans(context.TODO(), objects)
+}
+
 func (o *ApplyOptions) run() error {
 	a, err := newAnalytics()
 	if err != nil {
@@ -76,26 +117,19 @@ func (o *ApplyOptions) run() error {
 		return err
 	}
 
-	visitors, err := visitor.FromStrings(o.Filenames, o.In)
-	if err != nil {
-		return err
-	}
-
-	objects, err := visitor.DecodeAll(visitors)
+	objects, err := decodeObjects(o.Filenames, o.In)
 	if err != nil {
 		return err
 	}
 
 	var cc *cluster.Controller
-	var rc *registry.Controller
+	rcs := newRegistryControllers(o.IOStreams)
 	for _, obj := range objects {
 		switch obj := obj.(type) {
 		case *api.Registry:
-			if rc == nil {
-				rc, err = registry.DefaultController(ctx, o.IOStreams)
-				if err != nil {
-					return err
-				}
+			rc, err := rcs.forEngine(ctx, obj.Engine)
+			if err != nil {
+				return err
 			}
 
 			newObj, err := rc.Apply(ctx, obj)
@@ -118,6 +152,11 @@ func (o *ApplyOptions) run() error {
 				if err != nil {
 					return err
 				}
+				if o.Force {
+					cc.SetConfirmRecreate(cluster.AlwaysRecreate)
+				} else {
+					cc.SetConfirmRecreate(confirmRecreate(o.IOStreams))
+				}
 			}
 
 			newObj, err := cc.Apply(ctx, obj)
@@ -140,3 +179,127 @@ func (o *ApplyOptions) run() error {
 	}
 	return nil
 }
+
+// Registries may live on different container engines,
+// so keep one controller per engine.
+type registryControllers struct {
+	iostreams genericclioptions.IOStreams
+	byEngine  map[string]*registry.Controller
+}
+
+func newRegistryControllers(iostreams genericclioptions.IOStreams) *registryControllers {
+	return &registryControllers{
+		iostreams: iostreams,
+		byEngine:  make(map[string]*registry.Controller),
+	}
+}
+
+func (r *registryControllers) forEngine(ctx context.Context, engine string) (*registry.Controller, error) {
+	rc, ok := r.byEngine[engine]
+	if ok {
+		return rc, nil
+	}
+	rc, err := registry.ControllerForEngine(ctx, r.iostreams, docker.Engine(engine))
+	if err != nil {
+		return nil, err
+	}
+	r.byEngine[engine] = rc
+	return rc, nil
+}
+
+type registryListDeleter interface {
+	registryLister
+	deleter
+	Get(ctx context.Context, name string) (*api.Registry, error)
+}
+
+// Looks up registries by name on every installed container engine,
+// for commands that don't know which engine a registry lives on.
+func (r *registryControllers) allEngines() *allEngineRegistries {
+	return &allEngineRegistries{
+		engines: docker.AvailableEngines(),
+		forEngine: func(ctx context.Context, engine docker.Engine) (registryListDeleter, error) {
+			rc, err := r.forEngine(ctx, string(engine))
+			if err != nil {
+				return nil, err
+			}
+			return rc, nil
+		},
+	}
+}
+
+type allEngineRegistries struct {
+	engines   []docker.Engine
+	forEngine func(ctx context.Context, engine docker.Engine) (registryListDeleter, error)
+}
+
+// Lists the registries on all engines.
+//
+// Skips engines that we can't connect to (e.g., podman is installed
+// but its service isn't running), unless we can't connect to any of them.
+func (r *allEngineRegistries) List(ctx context.Context, options registry.ListOptions) (*api.RegistryList, error) {
+	var result *api.RegistryList
+	var firstErr error
+	for _, engine := range r.engines {
+		rc, err := r.forEngine(ctx, engine)
+		if err == nil {
+			var list *api.RegistryList
+			list, err = rc.List(ctx, options)
+			if err == nil {
+				if result == nil {
+					result = list
+				} else {
+					result.Items = append(result.Items, list.Items...)
+				}
+				continue
+			}
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", engine, err)
+		}
+	}
+	if result == nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// Gets the registry from the first engine that has it.
+func (r *allEngineRegistries) Get(ctx context.Context, name string) (*api.Registry, error) {
+	_, registry, err := r.find(ctx, name)
+	return registry, err
+}
+
+// Deletes the registry from the first engine that has it.
+func (r *allEngineRegistries) Delete(ctx context.Context, name string) error {
+	rc, _, err := r.find(ctx, name)
+	if err != nil {
+		return err
+	}
+	return rc.Delete(ctx, name)
+}
+
+func (r *allEngineRegistries) find(ctx context.Context, name string) (registryListDeleter, *api.Registry, error) {
+	var notFoundErr, otherErr error
+	for _, engine := range r.engines {
+		rc, err := r.forEngine(ctx, engine)
+		if err == nil {
+			var registry *api.Registry
+			registry, err = rc.Get(ctx, name)
+			if err == nil {
+				return rc, registry, nil
+			}
+		}
+		if errors.IsNotFound(err) {
+			notFoundErr = err
+		} else if otherErr == nil {
+			otherErr = fmt.Errorf("%s: %v", engine, err)
+		}
+	}
+
+	// If we couldn't check an engine, the registry might be there.
+	if otherErr != nil {
+		return nil, nil, otherErr
+	}
+	return nil, nil, notFoundErr
+}
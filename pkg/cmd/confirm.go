@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// Asks the user before recreating a cluster that can't be updated in place.
+//
+// If stdin isn't a terminal, there's nobody to ask, so we refuse.
+func confirmRecreate(streams genericclioptions.IOStreams) cluster.ConfirmRecreateFunc {
+	return func(ctx context.Context, irreconcilable *cluster.IrreconcilableError) (bool, error) {
+		if !isTerminal(streams.In) {
+			return false, nil
+		}
+		return promptRecreate(streams.In, streams.ErrOut, irreconcilable)
+	}
+}
+
+func promptRecreate(in io.Reader, out io.Writer, irreconcilable *cluster.IrreconcilableError) (bool, error) {
+	_, _ = fmt.Fprint(out, irreconcilable.Describe())
+	_, _ = fmt.Fprintf(out, "Delete cluster %s and everything in it, and create a new one? [y/N]: ", irreconcilable.Name)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
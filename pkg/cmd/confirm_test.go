@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+)
+
+var kindConfigChange = &cluster.IrreconcilableError{
+	Name: "kind-kind",
+	Differences: []cluster.Difference{
+		{Field: "kindV1Alpha4Cluster", Message: "desired Kind config does not match current"},
+	},
+}
+
+func TestPromptRecreate(t *testing.T) {
+	for _, tc := range []struct {
+		answer   string
+		expected bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	} {
+		t.Run(tc.answer, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			ok, err := promptRecreate(strings.NewReader(tc.answer), out, kindConfigChange)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ok)
+			assert.Contains(t, out.String(), "kindV1Alpha4Cluster: desired Kind config does not match current")
+			assert.Contains(t, out.String(), "Delete cluster kind-kind and everything in it, and create a new one? [y/N]")
+		})
+	}
+}
+
+func TestConfirmRecreateNotTerminal(t *testing.T) {
+	streams, in, _, errOut := genericclioptions.NewTestIOStreams()
+	_, _ = in.Write([]byte("y\n"))
+
+	// We never ask when stdin isn't a terminal.
+	ok, err := confirmRecreate(streams)(context.Background(), kindConfigChange)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "", errOut.String())
+}
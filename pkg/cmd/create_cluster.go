@@ -50,8 +50,16 @@ func (o *CreateClusterOptions) Command() *cobra.Command {
 		o.Cluster.Name, "Names the context. If not specified, uses the default cluster name for this Kubernetes product")
 	cmd.Flags().IntVar(&o.Cluster.MinCPUs, "min-cpus",
 		o.Cluster.MinCPUs, "Sets the minimum CPUs for the cluster")
+	cmd.Flags().StringVar(&o.Cluster.MinMemory, "min-memory",
+		o.Cluster.MinMemory, "Sets the minimum memory for the cluster, as a Kubernetes quantity (e.g., 8Gi)")
+	cmd.Flags().StringVar(&o.Cluster.MinDisk, "min-disk",
+		o.Cluster.MinDisk, "Sets the minimum disk for the cluster, as a Kubernetes quantity (e.g., 64Gi)")
 	cmd.Flags().StringVar(&o.Cluster.KubernetesVersion, "kubernetes-version",
 		o.Cluster.KubernetesVersion, "Sets the kubernetes version for the cluster, if possible")
+	cmd.Flags().StringVar(&o.Cluster.NodeImage, "node-image",
+		o.Cluster.NodeImage, "Sets the node image for the cluster. Only supported on kind")
+	cmd.Flags().StringVar(&o.Cluster.Engine, "engine",
+		o.Cluster.Engine, "Sets the container engine (docker or podman). If not specified, uses docker, or podman if docker isn't installed")
 
 	return cmd
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
 	"github.com/tilt-dev/ctlptl/pkg/registry"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -36,7 +37,8 @@ func (o *CreateRegistryOptions) Command() *cobra.Command {
 		Use:   "registry [name]",
 		Short: "Create a registry with the given name",
 		Example: "  ctlptl create registry ctlptl-registry\n" +
-			"  ctlptl create registry ctlptl-registry --port=5000",
+			"  ctlptl create registry ctlptl-registry --port=5000\n" +
+			"  ctlptl create registry ctlptl-registry --engine=podman",
 		Run:  o.Run,
 		Args: cobra.ExactArgs(1),
 	}
@@ -45,12 +47,14 @@ func (o *CreateRegistryOptions) Command() *cobra.Command {
 	cmd.SetErr(o.ErrOut)
 	o.PrintFlags.AddFlags(cmd)
 	cmd.Flags().IntVar(&o.Registry.Port, "port", o.Registry.Port, "The port to expose the registry on localhost. If not specified, chooses a random port")
+	cmd.Flags().StringVar(&o.Registry.Engine, "engine", o.Registry.Engine, "The container engine to run the registry on (docker or podman). If not specified, uses docker, or podman if docker isn't installed")
+	cmd.Flags().StringVar(&o.Registry.ProxyRemoteURL, "proxy-remote-url", o.Registry.ProxyRemoteURL, "Run the registry as a pull-through cache of the remote registry at this URL (e.g., https://registry-1.docker.io)")
 
 	return cmd
 }
 
 func (o *CreateRegistryOptions) Run(cmd *cobra.Command, args []string) {
-	controller, err := registry.DefaultController(context.Background(), o.IOStreams)
+	controller, err := registry.ControllerForEngine(context.Background(), o.IOStreams, docker.Engine(o.Registry.Engine))
 	if err != nil {
 		_, _ = fmt.Fprintf(o.ErrOut, "%v\n", err)
 		os.Exit(1)
@@ -23,9 +23,12 @@ type DeleteOptions struct {
 
 	IgnoreNotFound bool
 	Filenames      []string
+	LabelSelector  string
 
 	clusterDeleter  deleter
 	registryDeleter deleter
+	clusterLister   clusterLister
+	registryLister  registryLister
 }
 
 func NewDeleteOptions() *DeleteOptions {
@@ -42,7 +45,8 @@ func (o *DeleteOptions) Command() *cobra.Command {
 		Use:   "delete -f FILENAME",
 		Short: "Delete a currently running cluster",
 		Example: "  ctlptl delete -f cluster.yaml\n" +
-			"  ctlptl delete cluster minikube",
+			"  ctlptl delete cluster minikube\n" +
+			"  ctlptl delete clusters -l team=payments",
 		Run: o.Run,
 	}
 
@@ -51,6 +55,7 @@ func (o *DeleteOptions) Command() *cobra.Command {
 	o.FileNameFlags.AddFlags(cmd.Flags())
 
 	cmd.Flags().BoolVar(&o.IgnoreNotFound, "ignore-not-found", o.IgnoreNotFound, "If the requested object does not exist the command will return exit code 0.")
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", o.LabelSelector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 
 	return cmd
 }
@@ -67,6 +72,14 @@ type deleter interface {
 	Delete(ctx context.Context, name string) error
 }
 
+type clusterLister interface {
+	List(ctx context.Context, options cluster.ListOptions) (*api.ClusterList, error)
+}
+
+type registryLister interface {
+	List(ctx context.Context, options registry.ListOptions) (*api.RegistryList, error)
+}
+
 func (o *DeleteOptions) run(args []string) error {
 	a, err := newAnalytics()
 	if err != nil {
@@ -77,15 +90,26 @@ func (o *DeleteOptions) run(args []string) error {
 
 	hasFiles := len(o.Filenames) > 0
 	hasNames := len(args) >= 2
-	if !(hasFiles || hasNames) {
-		return fmt.Errorf("Expected resources, specified as files ('ctlptl delete -f') or names ('ctlptl delete cluster foo`)")
+	hasSelector := o.LabelSelector != ""
+	if !(hasFiles || hasNames || hasSelector) {
+		return fmt.Errorf("Expected resources, specified as files ('ctlptl delete -f'), names ('ctlptl delete cluster foo`), or labels ('ctlptl delete cluster -l team=foo')")
 	}
-	if hasFiles && hasNames {
-		return fmt.Errorf("Can only specify one of {files, resource names}")
+	if (hasFiles && hasNames) || (hasFiles && hasSelector) || (hasNames && hasSelector) {
+		return fmt.Errorf("Can only specify one of {files, resource names, label selector}")
 	}
+	if hasSelector && len(args) != 1 {
+		return fmt.Errorf("Expected a resource type with a label selector ('ctlptl delete cluster -l team=foo')")
+	}
+
+	ctx := context.TODO()
 
 	var resources []runtime.Object
-	if hasFiles {
+	if hasSelector {
+		resources, err = o.selectResources(ctx, args[0])
+		if err != nil {
+			return err
+		}
+	} else if hasFiles {
 		visitors, err := visitor.FromStrings(o.Filenames, o.In)
 		if err != nil {
 			return err
@@ -118,8 +142,6 @@ func (o *DeleteOptions) run(args []string) error {
 		}
 	}
 
-	ctx := context.TODO()
-
 	printer, err := toPrinter(o.PrintFlags)
 	if err != nil {
 		return err
@@ -149,10 +171,7 @@ func (o *DeleteOptions) run(args []string) error {
 			}
 		case *api.Registry:
 			if o.registryDeleter == nil {
-				o.registryDeleter, err = registry.DefaultController(ctx, o.IOStreams)
-				if err != nil {
-					return err
-				}
+				o.registryDeleter = newRegistryControllers(o.IOStreams).allEngines()
 			}
 
 			registry.FillDefaults(resource)
@@ -173,3 +192,47 @@ func (o *DeleteOptions) run(args []string) error {
 	}
 	return nil
 }
+
+// Lists the resources of the given type that match the label selector.
+func (o *DeleteOptions) selectResources(ctx context.Context, t string) ([]runtime.Object, error) {
+	var resources []runtime.Object
+	switch t {
+	case "cluster", "clusters":
+		if o.clusterLister == nil {
+			c, err := cluster.DefaultController(o.IOStreams)
+			if err != nil {
+				return nil, err
+			}
+			o.clusterLister = c
+		}
+
+		list, err := o.clusterLister.List(ctx, cluster.ListOptions{LabelSelector: o.LabelSelector})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			resources = append(resources, &api.Cluster{
+				TypeMeta: cluster.TypeMeta(),
+				Name:     item.Name,
+			})
+		}
+	case "registry", "registries":
+		if o.registryLister == nil {
+			o.registryLister = newRegistryControllers(o.IOStreams).allEngines()
+		}
+
+		list, err := o.registryLister.List(ctx, registry.ListOptions{LabelSelector: o.LabelSelector})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			resources = append(resources, &api.Registry{
+				TypeMeta: registry.TypeMeta(),
+				Name:     item.Name,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("Unrecognized type: %s", t)
+	}
+	return resources, nil
+}
@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -109,6 +114,117 @@ port: 5002
 	assert.Equal(t, "ctlptl-registry", rd.lastName)
 }
 
+func TestDeleteBySelector(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDeleteOptions()
+	o.IOStreams = streams
+
+	cd := &fakeDeleter{}
+	cl := &fakeClusterLister{items: []api.Cluster{{Name: "kind-payments"}}}
+	o.clusterDeleter = cd
+	o.clusterLister = cl
+	o.LabelSelector = "team=payments"
+	err := o.run([]string{"clusters"})
+	require.NoError(t, err)
+	assert.Equal(t, "cluster.ctlptl.dev/kind-payments deleted\n", out.String())
+	assert.Equal(t, "kind-payments", cd.lastName)
+	assert.Equal(t, "team=payments", cl.lastOptions.LabelSelector)
+}
+
+func TestDeleteSelectorWithNames(t *testing.T) {
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := NewDeleteOptions()
+	o.IOStreams = streams
+
+	o.clusterDeleter = &fakeDeleter{}
+	o.LabelSelector = "team=payments"
+	err := o.run([]string{"cluster", "kind-kind"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Can only specify one of")
+	}
+}
+
+func TestDeleteRegistryOnItsEngine(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDeleteOptions()
+	o.IOStreams = streams
+
+	onDocker := &fakeEngineRegistries{items: []api.Registry{{Name: "ctlptl-registry", Engine: "docker"}}}
+	onPodman := &fakeEngineRegistries{items: []api.Registry{{Name: "podman-registry", Engine: "podman"}}}
+	o.registryDeleter = fakeAllEngines(map[docker.Engine]*fakeEngineRegistries{
+		docker.EngineDocker: onDocker,
+		docker.EnginePodman: onPodman,
+	})
+	err := o.run([]string{"registry", "podman-registry"})
+	require.NoError(t, err)
+	assert.Equal(t, "registry.ctlptl.dev/podman-registry deleted\n", out.String())
+	assert.Equal(t, "", onDocker.lastName)
+	assert.Equal(t, "podman-registry", onPodman.lastName)
+}
+
+func TestListRegistriesSkipsUnreachableEngine(t *testing.T) {
+	r := fakeAllEngines(map[docker.Engine]*fakeEngineRegistries{
+		docker.EngineDocker: {listErr: fmt.Errorf("Cannot connect to the Docker daemon")},
+		docker.EnginePodman: {items: []api.Registry{{Name: "podman-registry", Engine: "podman"}}},
+	})
+	list, err := r.List(context.Background(), registry.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list.Items))
+	assert.Equal(t, "podman-registry", list.Items[0].Name)
+
+	// If the registry might be on the unreachable engine, say so.
+	_, err = r.Get(context.Background(), "ctlptl-registry")
+	if assert.Error(t, err) {
+		assert.False(t, errors.IsNotFound(err))
+		assert.Contains(t, err.Error(), "docker: Cannot connect")
+	}
+}
+
+func fakeAllEngines(byEngine map[docker.Engine]*fakeEngineRegistries) *allEngineRegistries {
+	return &allEngineRegistries{
+		engines: []docker.Engine{docker.EngineDocker, docker.EnginePodman},
+		forEngine: func(ctx context.Context, engine docker.Engine) (registryListDeleter, error) {
+			return byEngine[engine], nil
+		},
+	}
+}
+
+type fakeEngineRegistries struct {
+	fakeDeleter
+	items   []api.Registry
+	listErr error
+}
+
+func (r *fakeEngineRegistries) List(ctx context.Context, options registry.ListOptions) (*api.RegistryList, error) {
+	if r.listErr != nil {
+		return nil, r.listErr
+	}
+	return &api.RegistryList{Items: r.items}, nil
+}
+
+func (r *fakeEngineRegistries) Get(ctx context.Context, name string) (*api.Registry, error) {
+	if r.listErr != nil {
+		return nil, r.listErr
+	}
+	for _, item := range r.items {
+		if item.Name == name {
+			item := item
+			return &item, nil
+		}
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{Group: "ctlptl.dev", Resource: "registries"}, name)
+}
+
+type fakeClusterLister struct {
+	items       []api.Cluster
+	lastOptions cluster.ListOptions
+}
+
+func (cl *fakeClusterLister) List(ctx context.Context, options cluster.ListOptions) (*api.ClusterList, error) {
+	cl.lastOptions = options
+	return &api.ClusterList{Items: cl.items}, nil
+}
+
 type fakeDeleter struct {
 	lastName  string
 	nextError error
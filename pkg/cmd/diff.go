@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
+	"github.com/tilt-dev/ctlptl/pkg/visitor"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// The exit code when `ctlptl diff` or `ctlptl apply --dry-run` finds
+// changes to make, so that scripts can tell them apart from errors.
+const exitCodeChangesPending = 2
+
+type DiffOptions struct {
+	*genericclioptions.FileNameFlags
+	genericclioptions.IOStreams
+
+	Filenames []string
+
+	clusterPlanner  clusterPlanner
+	registryPlanner registryPlanner
+}
+
+func NewDiffOptions() *DiffOptions {
+	o := &DiffOptions{
+		IOStreams: genericclioptions.IOStreams{Out: os.Stdout, ErrOut: os.Stderr, In: os.Stdin},
+	}
+	o.FileNameFlags = &genericclioptions.FileNameFlags{Filenames: &o.Filenames}
+	return o
+}
+
+func (o *DiffOptions) Command() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "diff -f FILENAME",
+		Short: "Show the changes that apply would make to the currently running clusters",
+		Long: "Show the changes that apply would make to the currently running clusters and registries, " +
+			"without making them.\n\n" +
+			"Exits with code 0 if there are no changes, 2 if there are changes, and 1 on error.",
+		Example: "  ctlptl diff -f cluster.yaml\n" +
+			"  cat cluster.yaml | ctlptl diff -f -",
+		Run: o.Run,
+	}
+
+	cmd.SetOut(o.Out)
+	cmd.SetErr(o.ErrOut)
+	o.FileNameFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *DiffOptions) Run(cmd *cobra.Command, args []string) {
+	if len(o.Filenames) == 0 {
+		fmt.Fprintf(o.ErrOut, "Expected source files with -f")
+		os.Exit(1)
+	}
+
+	hasChanges, err := o.run()
+	if err != nil {
+		_, _ = fmt.Fprintf(o.ErrOut, "%v\n", err)
+		os.Exit(1)
+	}
+	if hasChanges {
+		os.Exit(exitCodeChangesPending)
+	}
+}
+
+type clusterPlanner interface {
+	Plan(ctx context.Context, desired *api.Cluster) ([]plan.Action, error)
+}
+
+type registryPlanner interface {
+	Plan(ctx context.Context, desired *api.Registry) ([]plan.Action, error)
+}
+
+func (o *DiffOptions) run() (bool, error) {
+	a, err := newAnalytics()
+	if err != nil {
+		return false, err
+	}
+	a.Incr("cmd.diff", nil)
+	defer a.Flush(time.Second)
+
+	objects, err := decodeObjects(o.Filenames, o.In)
+	if err != nil {
+		return false, err
+	}
+
+	return o.printPlans(context.TODO(), objects)
+}
+
+func decodeObjects(filenames []string, in io.Reader) ([]runtime.Object, error) {
+	visitors, err := visitor.FromStrings(filenames, in)
+	if err != nil {
+		return nil, err
+	}
+	return visitor.DecodeAll(visitors)
+}
+
+// Prints what apply would do to each object.
+//
+// Returns true if there are changes to make.
+func (o *DiffOptions) printPlans(ctx context.Context, objects []runtime.Object) (bool, error) {
+	plans, err := o.plan(ctx, objects)
+	if err != nil {
+		return false, err
+	}
+
+	hasChanges := false
+	for _, p := range plans {
+		err := p.Print(o.Out)
+		if err != nil {
+			return false, err
+		}
+		if p.HasChanges() {
+			hasChanges = true
+		}
+	}
+	return hasChanges, nil
+}
+
+// Plans the objects in the same order that apply applies them:
+// registries first, then clusters.
+func (o *DiffOptions) plan(ctx context.Context, objects []runtime.Object) ([]plan.Plan, error) {
+	var plans []plan.Plan
+
+	rcs := newRegistryControllers(o.IOStreams)
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *api.Registry:
+			rp := o.registryPlanner
+			if rp == nil {
+				rc, err := rcs.forEngine(ctx, obj.Engine)
+				if err != nil {
+					return nil, err
+				}
+				rp = rc
+			}
+
+			actions, err := rp.Plan(ctx, obj)
+			if err != nil {
+				return nil, err
+			}
+			plans = append(plans, plan.Plan{Object: objectName(obj.TypeMeta, obj.Name), Actions: actions})
+		}
+	}
+
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *api.Cluster:
+			if o.clusterPlanner == nil {
+				cc, err := cluster.DefaultController(o.IOStreams)
+				if err != nil {
+					return nil, err
+				}
+				o.clusterPlanner = cc
+			}
+
+			actions, err := o.clusterPlanner.Plan(ctx, obj)
+			if err != nil {
+				return nil, err
+			}
+			plans = append(plans, plan.Plan{Object: objectName(obj.TypeMeta, obj.Name), Actions: actions})
+
+		case *api.Registry:
+			// Handled above
+			continue
+
+		default:
+			return nil, fmt.Errorf("unrecognized type: %T", obj)
+		}
+	}
+	return plans, nil
+}
+
+// The object name, in the same format as `-o name`.
+func objectName(tm api.TypeMeta, name string) string {
+	group := strings.Split(tm.APIVersion, "/")[0]
+	return fmt.Sprintf("%s.%s/%s", strings.ToLower(tm.Kind), group, name)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
+)
+
+func TestDiff(t *testing.T) {
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDiffOptions()
+	o.IOStreams = streams
+
+	_, _ = in.Write([]byte(`apiVersion: ctlptl.dev/v1alpha1
+kind: Cluster
+product: kind
+registry: ctlptl-registry
+---
+apiVersion: ctlptl.dev/v1alpha1
+kind: Registry
+name: ctlptl-registry
+`))
+
+	o.clusterPlanner = &fakeClusterPlanner{actions: []plan.Action{{Description: "create cluster"}}}
+	o.registryPlanner = &fakeRegistryPlanner{}
+	o.Filenames = []string{"-"}
+	hasChanges, err := o.run()
+	require.NoError(t, err)
+	assert.True(t, hasChanges)
+	assert.Equal(t, `registry.ctlptl.dev/ctlptl-registry: no changes
+cluster.ctlptl.dev/kind-kind:
+  - create cluster
+`, out.String())
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDiffOptions()
+	o.IOStreams = streams
+
+	_, _ = in.Write([]byte(`apiVersion: ctlptl.dev/v1alpha1
+kind: Cluster
+product: kind
+`))
+
+	o.clusterPlanner = &fakeClusterPlanner{}
+	o.Filenames = []string{"-"}
+	hasChanges, err := o.run()
+	require.NoError(t, err)
+	assert.False(t, hasChanges)
+	assert.Equal(t, "cluster.ctlptl.dev/kind-kind: no changes\n", out.String())
+}
+
+type fakeClusterPlanner struct {
+	actions []plan.Action
+}
+
+func (p *fakeClusterPlanner) Plan(ctx context.Context, desired *api.Cluster) ([]plan.Action, error) {
+	cluster.FillDefaults(desired)
+	return p.actions, nil
+}
+
+type fakeRegistryPlanner struct {
+	actions []plan.Action
+}
+
+func (p *fakeRegistryPlanner) Plan(ctx context.Context, desired *api.Registry) ([]plan.Action, error) {
+	return p.actions, nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+type GCOptions struct {
+	*genericclioptions.PrintFlags
+	genericclioptions.IOStreams
+
+	DryRun bool
+
+	clusterController  gcClusterController
+	registryController gcRegistryController
+	now                func() time.Time
+}
+
+func NewGCOptions() *GCOptions {
+	o := &GCOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("deleted"),
+		IOStreams:  genericclioptions.IOStreams{Out: os.Stdout, ErrOut: os.Stderr, In: os.Stdin},
+		now:        time.Now,
+	}
+	return o
+}
+
+func (o *GCOptions) Command() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Delete clusters that have outlived their TTL",
+		Long: "Delete clusters that have outlived their TTL.\n\n" +
+			"A cluster expires when it's older than the ttl in its cluster config. " +
+			"Also deletes the expired clusters' registries, unless another cluster still uses them.",
+		Example: "  ctlptl gc\n" +
+			"  ctlptl gc --dry-run -o yaml",
+		Args: cobra.ExactArgs(0),
+		Run:  o.Run,
+	}
+
+	cmd.SetOut(o.Out)
+	cmd.SetErr(o.ErrOut)
+	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the clusters and registries that would be deleted.")
+
+	return cmd
+}
+
+func (o *GCOptions) Run(cmd *cobra.Command, args []string) {
+	err := o.run()
+	if err != nil {
+		_, _ = fmt.Fprintf(o.ErrOut, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+type gcClusterController interface {
+	clusterLister
+	deleter
+}
+
+type gcRegistryController interface {
+	registryLister
+	deleter
+}
+
+func (o *GCOptions) run() error {
+	a, err := newAnalytics()
+	if err != nil {
+		return err
+	}
+	a.Incr("cmd.gc", nil)
+	defer a.Flush(time.Second)
+
+	ctx := context.TODO()
+
+	if o.DryRun {
+		err := o.PrintFlags.Complete("%s (dry run)")
+		if err != nil {
+			return err
+		}
+	}
+	printer, err := toPrinter(o.PrintFlags)
+	if err != nil {
+		return err
+	}
+
+	if o.clusterController == nil {
+		o.clusterController, err = cluster.DefaultController(o.IOStreams)
+		if err != nil {
+			return err
+		}
+	}
+
+	clusters, err := o.clusterController.List(ctx, cluster.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	now := o.now()
+	var expired []api.Cluster
+	inUse := make(map[string]bool)
+	expiredRegistries := make(map[string]bool)
+	var unknownRegistries []string
+	for _, c := range clusters.Items {
+		names := clusterRegistries(c)
+		if cluster.IsExpired(&c, now) {
+			expired = append(expired, c)
+			for _, name := range names {
+				expiredRegistries[name] = true
+			}
+			continue
+		}
+		if !knowsRegistries(c) {
+			unknownRegistries = append(unknownRegistries, c.Name)
+		}
+		for _, name := range names {
+			inUse[name] = true
+		}
+	}
+
+	if len(expired) == 0 {
+		_, _ = fmt.Fprintln(o.ErrOut, "No expired clusters")
+		return nil
+	}
+
+	for i := range expired {
+		c := &expired[i]
+		if !o.DryRun {
+			err := o.clusterController.Delete(ctx, c.Name)
+			if err != nil {
+				return err
+			}
+		}
+		err = printer.PrintObj(c, o.Out)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only delete the registries that belonged to the expired clusters.
+	// Registries that are still in use stay around.
+	for name := range inUse {
+		delete(expiredRegistries, name)
+	}
+	if len(expiredRegistries) == 0 {
+		return nil
+	}
+
+	// If we couldn't read a live cluster's spec, it might still use one
+	// of the expired clusters' registries. Better to leave them around.
+	if len(unknownRegistries) > 0 {
+		_, _ = fmt.Fprintf(o.ErrOut,
+			"Not deleting registries: can't tell which registries these clusters use: %s\n",
+			strings.Join(unknownRegistries, ", "))
+		return nil
+	}
+
+	if o.registryController == nil {
+		o.registryController = newRegistryControllers(o.IOStreams).allEngines()
+	}
+
+	registries, err := o.registryController.List(ctx, registry.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range registries.Items {
+		r := &registries.Items[i]
+		if !expiredRegistries[r.Name] {
+			continue
+		}
+		if !o.DryRun {
+			err := o.registryController.Delete(ctx, r.Name)
+			if err != nil {
+				return err
+			}
+		}
+		err = printer.PrintObj(r, o.Out)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Whether we could read the registries that a cluster uses. That takes
+// a reachable API server and a spec that ctlptl recorded.
+func knowsRegistries(c api.Cluster) bool {
+	return conditionIsTrue(c.Status.Conditions, api.ClusterConditionAPIServerReachable) &&
+		conditionIsTrue(c.Status.Conditions, api.ClusterConditionSpecRecorded)
+}
+
+func conditionIsTrue(conditions []api.Condition, conditionType string) bool {
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			return c.Status == metav1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// The registries that a cluster uses, including its mirrors.
+func clusterRegistries(c api.Cluster) []string {
+	var names []string
+	if c.Registry != "" {
+		names = append(names, c.Registry)
+	}
+	for _, name := range c.RegistryMirrors {
+		names = append(names, name)
+	}
+	return names
+}
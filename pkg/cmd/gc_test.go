@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/cluster"
+	"github.com/tilt-dev/ctlptl/pkg/registry"
+)
+
+var gcNow = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func gcCluster(name, ttl string, age time.Duration, registry string) api.Cluster {
+	return api.Cluster{
+		TypeMeta: cluster.TypeMeta(),
+		Name:     name,
+		Product:  "kind",
+		TTL:      ttl,
+		Registry: registry,
+		Status: api.ClusterStatus{
+			CreationTimestamp: metav1.Time{Time: gcNow.Add(-age)},
+			Conditions: []api.Condition{
+				{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue},
+				{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionTrue},
+			},
+		},
+	}
+}
+
+type gcFixture struct {
+	o      *GCOptions
+	cc     *fakeGCClusterController
+	rc     *fakeGCRegistryController
+	out    *bytes.Buffer
+	errOut *bytes.Buffer
+}
+
+func newGCFixture(clusters []api.Cluster, registries []string) *gcFixture {
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	o := NewGCOptions()
+	o.IOStreams = streams
+	o.now = func() time.Time { return gcNow }
+
+	cc := &fakeGCClusterController{items: clusters}
+	rc := &fakeGCRegistryController{}
+	for _, name := range registries {
+		rc.items = append(rc.items, api.Registry{TypeMeta: registry.TypeMeta(), Name: name})
+	}
+	o.clusterController = cc
+	o.registryController = rc
+	return &gcFixture{o: o, cc: cc, rc: rc, out: out, errOut: errOut}
+}
+
+func TestGCDeletesExpiredClusters(t *testing.T) {
+	f := newGCFixture([]api.Cluster{
+		gcCluster("kind-old", "8h", 9*time.Hour, "old-registry"),
+		gcCluster("kind-new", "8h", time.Hour, "shared-registry"),
+		gcCluster("kind-forever", "", 1000*time.Hour, ""),
+		gcCluster("kind-old-shared", "1h", 2*time.Hour, "shared-registry"),
+	}, []string{"old-registry", "shared-registry"})
+
+	err := f.o.run()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kind-old", "kind-old-shared"}, f.cc.deleted)
+	assert.Equal(t, []string{"old-registry"}, f.rc.deleted)
+	assert.Equal(t,
+		"cluster.ctlptl.dev/kind-old deleted\n"+
+			"cluster.ctlptl.dev/kind-old-shared deleted\n"+
+			"registry.ctlptl.dev/old-registry deleted\n",
+		f.out.String())
+}
+
+func TestGCDryRun(t *testing.T) {
+	f := newGCFixture([]api.Cluster{
+		gcCluster("kind-old", "8h", 9*time.Hour, "old-registry"),
+	}, []string{"old-registry"})
+	f.o.DryRun = true
+
+	err := f.o.run()
+	require.NoError(t, err)
+	assert.Nil(t, f.cc.deleted)
+	assert.Nil(t, f.rc.deleted)
+	assert.Equal(t,
+		"cluster.ctlptl.dev/kind-old deleted (dry run)\n"+
+			"registry.ctlptl.dev/old-registry deleted (dry run)\n",
+		f.out.String())
+}
+
+func TestGCKeepsRegistriesWhenUsageUnknown(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		conditions []api.Condition
+	}{
+		{"unreachable", []api.Condition{
+			{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionFalse},
+			{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionUnknown},
+		}},
+		{"not recorded", []api.Condition{
+			{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue},
+			{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionUnknown},
+		}},
+		{"no conditions", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			live := gcCluster("kind-live", "", time.Hour, "")
+			live.Status.Conditions = tc.conditions
+			f := newGCFixture([]api.Cluster{
+				gcCluster("kind-old", "8h", 9*time.Hour, "old-registry"),
+				live,
+			}, []string{"old-registry"})
+
+			err := f.o.run()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"kind-old"}, f.cc.deleted)
+			assert.Nil(t, f.rc.deleted)
+			assert.Contains(t, f.errOut.String(), "Not deleting registries: can't tell which registries these clusters use: kind-live")
+		})
+	}
+}
+
+func TestGCNothingExpired(t *testing.T) {
+	f := newGCFixture([]api.Cluster{
+		gcCluster("kind-new", "8h", time.Hour, ""),
+	}, nil)
+
+	err := f.o.run()
+	require.NoError(t, err)
+	assert.Nil(t, f.cc.deleted)
+	assert.Equal(t, "No expired clusters\n", f.errOut.String())
+}
+
+type fakeGCClusterController struct {
+	items   []api.Cluster
+	deleted []string
+}
+
+func (c *fakeGCClusterController) List(ctx context.Context, options cluster.ListOptions) (*api.ClusterList, error) {
+	return &api.ClusterList{Items: c.items}, nil
+}
+
+func (c *fakeGCClusterController) Delete(ctx context.Context, name string) error {
+	c.deleted = append(c.deleted, name)
+	return nil
+}
+
+type fakeGCRegistryController struct {
+	items   []api.Registry
+	deleted []string
+}
+
+func (c *fakeGCRegistryController) List(ctx context.Context, options registry.ListOptions) (*api.RegistryList, error) {
+	return &api.RegistryList{Items: c.items}, nil
+}
+
+func (c *fakeGCRegistryController) Delete(ctx context.Context, name string) error {
+	c.deleted = append(c.deleted, name)
+	return nil
+}
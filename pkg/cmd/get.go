@@ -24,6 +24,7 @@ type GetOptions struct {
 	StartTime      time.Time
 	IgnoreNotFound bool
 	FieldSelector  string
+	LabelSelector  string
 }
 
 func NewGetOptions() *GetOptions {
@@ -47,7 +48,9 @@ https://kubernetes.io/docs/reference/kubectl/cheatsheet/#formatting-output
 `,
 		Example: "  ctlptl get\n" +
 			"  ctlptl get cluster microk8s -o yaml\n" +
-			"  ctlptl get cluster kind-kind -o template --template '{{.status.localRegistryHosting.host}}'\n",
+			"  ctlptl get cluster kind-kind -o template --template '{{.status.localRegistryHosting.host}}'\n" +
+			"  ctlptl get clusters -l team=payments\n" +
+			"  ctlptl get kind-images v0.11.1\n",
 		Run:  o.Run,
 		Args: cobra.MaximumNArgs(2),
 	}
@@ -58,6 +61,7 @@ https://kubernetes.io/docs/reference/kubectl/cheatsheet/#formatting-output
 
 	cmd.Flags().BoolVar(&o.IgnoreNotFound, "ignore-not-found", o.IgnoreNotFound, "If the requested object does not exist the command will return exit code 0.")
 	cmd.Flags().StringVar(&o.FieldSelector, "field-selector", o.FieldSelector, "Selector (field query) to filter on, supports '=', '==', and '!='.(e.g. --field-selector key1=value1,key2=value2). The server only supports a limited number of field queries per type.")
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", o.LabelSelector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 
 	return cmd
 }
@@ -79,12 +83,7 @@ func (o *GetOptions) Run(cmd *cobra.Command, args []string) {
 	var resource runtime.Object
 	switch t {
 	case "registry", "registries":
-		c, err := registry.DefaultController(ctx, o.IOStreams)
-		if err != nil {
-			_, _ = fmt.Fprintf(o.ErrOut, "Loading controller: %v\n", err)
-			os.Exit(1)
-		}
-
+		c := newRegistryControllers(o.IOStreams).allEngines()
 		if len(args) >= 2 {
 			resource, err = c.Get(ctx, args[1])
 			if err != nil {
@@ -95,7 +94,7 @@ func (o *GetOptions) Run(cmd *cobra.Command, args []string) {
 				os.Exit(1)
 			}
 		} else {
-			resource, err = c.List(ctx, registry.ListOptions{FieldSelector: o.FieldSelector})
+			resource, err = c.List(ctx, registry.ListOptions{FieldSelector: o.FieldSelector, LabelSelector: o.LabelSelector})
 			if err != nil {
 				_, _ = fmt.Fprintf(o.ErrOut, "List registries: %v\n", err)
 				os.Exit(1)
@@ -119,15 +118,35 @@ func (o *GetOptions) Run(cmd *cobra.Command, args []string) {
 				os.Exit(1)
 			}
 		} else {
-			resource, err = c.List(ctx, cluster.ListOptions{FieldSelector: o.FieldSelector})
+			resource, err = c.List(ctx, cluster.ListOptions{FieldSelector: o.FieldSelector, LabelSelector: o.LabelSelector})
 			if err != nil {
 				_, _ = fmt.Fprintf(o.ErrOut, "List clusters: %v\n", err)
 				os.Exit(1)
 			}
 		}
 
+	case "kind-images", "kind-image":
+		table, err := cluster.LoadKindNodeImageTable(ctx)
+		if err != nil {
+			_, _ = fmt.Fprintf(o.ErrOut, "Loading kind images: %v\n", err)
+			os.Exit(1)
+		}
+
+		kindVersion := ""
+		if len(args) >= 2 {
+			kindVersion = args[1]
+			if _, ok := table[kindVersion]; !ok {
+				if o.IgnoreNotFound {
+					os.Exit(0)
+				}
+				_, _ = fmt.Fprintf(o.ErrOut, "No images for kind version %s\n", kindVersion)
+				os.Exit(1)
+			}
+		}
+		resource = o.kindImagesAsTable(table, kindVersion)
+
 	default:
-		_, _ = fmt.Fprintf(o.ErrOut, "Unrecognized type: %s. Possible values: cluster, registry.\n", t)
+		_, _ = fmt.Fprintf(o.ErrOut, "Unrecognized type: %s. Possible values: cluster, registry, kind-images.\n", t)
 		os.Exit(1)
 	}
 
@@ -202,6 +221,10 @@ func (o *GetOptions) clustersAsTable(clusters []api.Cluster) runtime.Object {
 				Name: "Product",
 				Type: "string",
 			},
+			metav1.TableColumnDefinition{
+				Name: "Status",
+				Type: "string",
+			},
 			metav1.TableColumnDefinition{
 				Name: "Age",
 				Type: "string",
@@ -238,6 +261,7 @@ func (o *GetOptions) clustersAsTable(clusters []api.Cluster) runtime.Object {
 				current,
 				cluster.Name,
 				cluster.Product,
+				statusSummary(cluster.Status.Conditions),
 				age,
 				rHost,
 			},
@@ -247,6 +271,59 @@ func (o *GetOptions) clustersAsTable(clusters []api.Cluster) runtime.Object {
 	return &table
 }
 
+// Summarizes the conditions in one word: the reason for the first
+// condition that's false, or Ready if none of them are.
+func statusSummary(conditions []api.Condition) string {
+	if len(conditions) == 0 {
+		return "Unknown"
+	}
+	for _, c := range conditions {
+		if c.Status == metav1.ConditionFalse {
+			return c.Reason
+		}
+	}
+	return "Ready"
+}
+
+// Prints the kind node image table. If kindVersion is non-empty,
+// only prints the images for that kind version.
+func (o *GetOptions) kindImagesAsTable(images cluster.KindNodeImageTable, kindVersion string) runtime.Object {
+	table := metav1.Table{
+		TypeMeta: metav1.TypeMeta{Kind: "Table", APIVersion: "metav1.k8s.io"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			metav1.TableColumnDefinition{
+				Name: "Kind Version",
+				Type: "string",
+			},
+			metav1.TableColumnDefinition{
+				Name: "Kubernetes Version",
+				Type: "string",
+			},
+			metav1.TableColumnDefinition{
+				Name: "Image",
+				Type: "string",
+			},
+		},
+	}
+
+	for _, kv := range images.KindVersions() {
+		if kindVersion != "" && kv != kindVersion {
+			continue
+		}
+		for _, k8sVersion := range images.K8sVersions(kv) {
+			table.Rows = append(table.Rows, metav1.TableRow{
+				Cells: []interface{}{
+					kv,
+					k8sVersion,
+					images[kv][k8sVersion],
+				},
+			})
+		}
+	}
+
+	return &table
+}
+
 func (o *GetOptions) registriesAsTable(registries []api.Registry) runtime.Object {
 	table := metav1.Table{
 		TypeMeta: metav1.TypeMeta{Kind: "Table", APIVersion: "metav1.k8s.io"},
@@ -255,6 +332,10 @@ func (o *GetOptions) registriesAsTable(registries []api.Registry) runtime.Object
 				Name: "Name",
 				Type: "string",
 			},
+			metav1.TableColumnDefinition{
+				Name: "Status",
+				Type: "string",
+			},
 			metav1.TableColumnDefinition{
 				Name: "Host Address",
 				Type: "int",
@@ -290,6 +371,7 @@ func (o *GetOptions) registriesAsTable(registries []api.Registry) runtime.Object
 		table.Rows = append(table.Rows, metav1.TableRow{
 			Cells: []interface{}{
 				registry.Name,
+				statusSummary(registry.Status.Conditions),
 				hostAddress,
 				containerAddress,
 				age,
@@ -26,6 +26,9 @@ var clusterList = &api.ClusterList{
 			Status: api.ClusterStatus{
 				CreationTimestamp: metav1.Time{Time: createTime},
 				Current:           true,
+				Conditions: []api.Condition{
+					{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue, Reason: "Reachable"},
+				},
 			},
 		},
 		api.Cluster{
@@ -37,6 +40,15 @@ var clusterList = &api.ClusterList{
 				LocalRegistryHosting: &localregistry.LocalRegistryHostingV1{
 					Host: "localhost:5000",
 				},
+				Conditions: []api.Condition{
+					{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue, Reason: "Reachable"},
+					{
+						Type:    api.ClusterConditionRegistryConnected,
+						Status:  metav1.ConditionFalse,
+						Reason:  "RegistryNotFound",
+						Message: "no registry is listening on localhost:5000",
+					},
+				},
 			},
 		},
 	},
@@ -50,9 +62,9 @@ func TestDefaultPrint(t *testing.T) {
 
 	err := o.Print(o.transformForOutput(clusterList))
 	require.NoError(t, err)
-	assert.Equal(t, out.String(), `CURRENT   NAME        PRODUCT    AGE   REGISTRY
-*         microk8s    microk8s   3y    none
-          kind-kind   KIND       3y    localhost:5000
+	assert.Equal(t, out.String(), `CURRENT   NAME        PRODUCT    STATUS             AGE   REGISTRY
+*         microk8s    microk8s   Ready              3y    none
+          kind-kind   KIND       RegistryNotFound   3y    localhost:5000
 `)
 }
 
@@ -74,6 +86,10 @@ items:
   name: microk8s
   product: microk8s
   status:
+    conditions:
+    - reason: Reachable
+      status: "True"
+      type: APIServerReachable
     creationTimestamp: "2017-07-14T02:40:00Z"
     current: true
 - apiVersion: ctlptl.dev/v1alpha1
@@ -81,9 +97,53 @@ items:
   name: kind-kind
   product: KIND
   status:
+    conditions:
+    - reason: Reachable
+      status: "True"
+      type: APIServerReachable
+    - message: no registry is listening on localhost:5000
+      reason: RegistryNotFound
+      status: "False"
+      type: RegistryConnected
     creationTimestamp: "2017-07-14T02:40:00Z"
     localRegistryHosting:
       host: localhost:5000
 kind: ClusterList
 `, out.String())
 }
+
+func TestStatusSummary(t *testing.T) {
+	assert.Equal(t, "Unknown", statusSummary(nil))
+	assert.Equal(t, "Ready", statusSummary([]api.Condition{
+		{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionTrue},
+		{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionUnknown, Reason: "NotRecorded"},
+	}))
+	assert.Equal(t, "APIServerUnreachable", statusSummary([]api.Condition{
+		{Type: api.ClusterConditionMachineReachable, Status: metav1.ConditionTrue},
+		{Type: api.ClusterConditionAPIServerReachable, Status: metav1.ConditionFalse, Reason: "APIServerUnreachable"},
+		{Type: api.ClusterConditionSpecRecorded, Status: metav1.ConditionUnknown, Reason: "APIServerUnreachable"},
+	}))
+}
+
+func TestKindImagesPrint(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewGetOptions()
+	o.IOStreams = streams
+
+	images := cluster.KindNodeImageTable{
+		"v0.9.0": {
+			"1.18": "kindest/node:v1.18.8",
+			"1.19": "kindest/node:v1.19.1",
+		},
+		"v0.10.0": {
+			"1.20": "kindest/node:v1.20.2",
+		},
+	}
+	err := o.Print(o.transformForOutput(o.kindImagesAsTable(images, "")))
+	require.NoError(t, err)
+	assert.Equal(t, `KIND VERSION   KUBERNETES VERSION   IMAGE
+v0.10.0        1.20                 kindest/node:v1.20.2
+v0.9.0         1.19                 kindest/node:v1.19.1
+v0.9.0         1.18                 kindest/node:v1.18.8
+`, out.String())
+}
@@ -17,6 +17,8 @@ func NewRootCommand() *cobra.Command {
 	rootCmd.AddCommand(NewGetOptions().Command())
 	rootCmd.AddCommand(NewApplyOptions().Command())
 	rootCmd.AddCommand(NewDeleteOptions().Command())
+	rootCmd.AddCommand(NewDiffOptions().Command())
+	rootCmd.AddCommand(NewGCOptions().Command())
 	rootCmd.AddCommand(NewDockerDesktopCommand())
 	rootCmd.AddCommand(newDocsCommand(rootCmd))
 	rootCmd.AddCommand(analytics.NewCommand())
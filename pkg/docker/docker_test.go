@@ -2,9 +2,11 @@ package docker
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type dockerHostTestCase struct {
@@ -29,3 +31,42 @@ func TestIsLocalDockerHost(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveEngine(t *testing.T) {
+	e, err := ResolveEngine("podman")
+	assert.NoError(t, err)
+	assert.Equal(t, EnginePodman, e)
+	assert.Equal(t, "podman", e.CLI())
+
+	e, err = ResolveEngine("docker")
+	assert.NoError(t, err)
+	assert.Equal(t, "docker", e.CLI())
+
+	_, err = ResolveEngine("rkt")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown container engine")
+	}
+}
+
+func TestPodmanIgnoresDockerHost(t *testing.T) {
+	setenv(t, "DOCKER_HOST", "tcp://cluster:2375")
+	setenv(t, "CONTAINER_HOST", "")
+	assert.Equal(t, "tcp://cluster:2375", EngineDocker.HostEnv())
+	assert.Equal(t, "", EnginePodman.HostEnv())
+	assert.True(t, EnginePodman.IsLocalHost())
+
+	setenv(t, "CONTAINER_HOST", "unix:///tmp/podman.sock")
+	assert.Equal(t, "unix:///tmp/podman.sock", EnginePodman.HostEnv())
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, old)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+	require.NoError(t, os.Setenv(key, value))
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+)
+
+// Enum of container engines that ctlptl can drive.
+//
+// All engines must serve a Docker-compatible API and
+// ship a Docker-compatible CLI.
+type Engine string
+
+const (
+	EngineDocker Engine = "docker"
+	EnginePodman Engine = "podman"
+)
+
+func (e Engine) String() string { return string(e) }
+
+// The CLI binary for this engine.
+func (e Engine) CLI() string {
+	if e == EnginePodman {
+		return "podman"
+	}
+	return "docker"
+}
+
+// The host of the engine API, as set in the environment.
+//
+// Podman only reads CONTAINER_HOST. DOCKER_HOST points at the Docker daemon.
+func (e Engine) HostEnv() string {
+	if e == EnginePodman {
+		return os.Getenv("CONTAINER_HOST")
+	}
+	return GetHostEnv()
+}
+
+func (e Engine) IsLocalHost() bool {
+	return IsLocalHost(e.HostEnv())
+}
+
+// Podman serves a Docker-compatible API on a unix socket. The socket
+// is per-user when podman runs rootless.
+//
+// https://docs.podman.io/en/latest/markdown/podman-system-service.1.html
+func podmanSocketHost() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir != "" {
+		sock := filepath.Join(runtimeDir, "podman", "podman.sock")
+		_, err := os.Stat(sock)
+		if err == nil {
+			return "unix://" + sock
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// Creates an API client for the engine.
+func NewClient(ctx context.Context, e Engine) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if e == EnginePodman {
+		host := e.HostEnv()
+		if host == "" {
+			host = podmanSocketHost()
+		}
+		// Skip FromEnv, which reads DOCKER_HOST and Docker's TLS settings.
+		opts = []client.Opt{client.WithHost(host)}
+	}
+
+	c, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.NegotiateAPIVersion(ctx)
+	return c, nil
+}
+
+// Picks the engine to use.
+//
+// If the user asked for an engine by name, use that. Otherwise, prefer Docker
+// and fall back to Podman if it's the only engine installed.
+func ResolveEngine(name string) (Engine, error) {
+	switch Engine(name) {
+	case EngineDocker, EnginePodman:
+		return Engine(name), nil
+	case "":
+		return detectEngine(), nil
+	}
+	return "", fmt.Errorf("unknown container engine %q. Supported engines: %s, %s", name, EngineDocker, EnginePodman)
+}
+
+// Lists the engines whose CLI is installed, in order of preference.
+//
+// If no engine is installed, returns Docker, so that callers get
+// Docker's error messages.
+func AvailableEngines() []Engine {
+	var result []Engine
+	for _, e := range []Engine{EngineDocker, EnginePodman} {
+		_, err := exec.LookPath(e.CLI())
+		if err == nil {
+			result = append(result, e)
+		}
+	}
+	if len(result) == 0 {
+		return []Engine{EngineDocker}
+	}
+	return result
+}
+
+func detectEngine() Engine {
+	_, err := exec.LookPath("docker")
+	if err == nil {
+		return EngineDocker
+	}
+	_, err = exec.LookPath("podman")
+	if err == nil {
+		return EnginePodman
+	}
+	return EngineDocker
+}
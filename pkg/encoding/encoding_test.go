@@ -58,3 +58,19 @@ product: microk8s
 		assert.Contains(t, err.Error(), "decoding {Cluster ctlptl.dev/v1alpha1}: yaml: unmarshal errors:\n  line 9: field nameTypo not found in type api.Cluster")
 	}
 }
+
+func TestParseK3dTimeout(t *testing.T) {
+	yaml := `
+apiVersion: ctlptl.dev/v1alpha1
+kind: Cluster
+product: k3d
+k3dV1Alpha3Simple:
+  options:
+    k3d:
+      timeout: 60s
+`
+	data, err := ParseStream(strings.NewReader(yaml))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(data))
+	assert.Equal(t, "60s", data[0].(*api.Cluster).K3DV1Alpha3Simple.Options.K3dOptions.Timeout)
+}
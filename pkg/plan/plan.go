@@ -0,0 +1,65 @@
+// Package plan describes the changes that ctlptl would make to clusters
+// and registries, so that we can show them without making them.
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A change that ctlptl would make to a cluster or registry.
+type Action struct {
+	// What ctlptl would do.
+	//
+	// Example:
+	// recreate cluster because desired Kind config does not match current
+	Description string
+
+	// The config diff behind the change, in cmp.Diff format, if any.
+	Diff string
+}
+
+// The changes that ctlptl would make to one object.
+type Plan struct {
+	// The object, in the same format as `-o name`.
+	//
+	// Example:
+	// cluster.ctlptl.dev/kind-kind
+	Object string
+
+	Actions []Action
+}
+
+func (p Plan) HasChanges() bool {
+	return len(p.Actions) > 0
+}
+
+// Writes the plan in a human-readable format.
+func (p Plan) Print(w io.Writer) error {
+	if !p.HasChanges() {
+		_, err := fmt.Fprintf(w, "%s: no changes\n", p.Object)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%s:\n", p.Object)
+	if err != nil {
+		return err
+	}
+	for _, action := range p.Actions {
+		_, err := fmt.Fprintf(w, "  - %s\n", action.Description)
+		if err != nil {
+			return err
+		}
+		if action.Diff == "" {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(action.Diff, "\n"), "\n") {
+			_, err := fmt.Fprintf(w, "    %s\n", line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
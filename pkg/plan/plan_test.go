@@ -0,0 +1,40 @@
+package plan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintNoChanges(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	err := Plan{Object: "cluster.ctlptl.dev/kind-kind"}.Print(out)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster.ctlptl.dev/kind-kind: no changes\n", out.String())
+}
+
+func TestPrintActions(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	p := Plan{
+		Object: "cluster.ctlptl.dev/kind-kind",
+		Actions: []Action{
+			{
+				Description: "recreate cluster because desired port mappings do not match current",
+				Diff:        "  []api.PortMapping{\n+ \t{ContainerPort: 80, HostPort: 8080},\n  }\n",
+			},
+			{Description: "create cluster"},
+		},
+	}
+	err := p.Print(out)
+	require.NoError(t, err)
+	assert.True(t, p.HasChanges())
+	assert.Equal(t, `cluster.ctlptl.dev/kind-kind:
+  - recreate cluster because desired port mappings do not match current
+      []api.PortMapping{
+    + 	{ContainerPort: 80, HostPort: 8080},
+      }
+  - create cluster
+`, out.String())
+}
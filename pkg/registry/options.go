@@ -9,6 +9,7 @@ import (
 
 type ListOptions struct {
 	FieldSelector string
+	LabelSelector string
 }
 
 type registryFields api.Registry
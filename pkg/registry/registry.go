@@ -9,15 +9,17 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/client"
+	"github.com/google/go-cmp/cmp"
 	"github.com/phayes/freeport"
 	"github.com/tilt-dev/ctlptl/internal/exec"
 	"github.com/tilt-dev/ctlptl/internal/socat"
 	"github.com/tilt-dev/ctlptl/pkg/api"
 	"github.com/tilt-dev/ctlptl/pkg/docker"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
@@ -29,6 +31,21 @@ var groupResource = schema.GroupResource{Group: "ctlptl.dev", Resource: "registr
 // https://github.com/moby/moby/blob/v20.10.3/api/types/types.go#L313
 const containerStateRunning = "running"
 
+// Container labels with this prefix are reserved for ctlptl.
+const ctlptlLabelPrefix = "dev.tilt.ctlptl."
+
+// The container label where we record the remote URL of a pull-through cache,
+// because the container list doesn't include the container's env.
+const proxyRemoteURLLabel = ctlptlLabelPrefix + "proxy-remote-url"
+
+// Docker containers don't have annotations, so we store them
+// as labels with this prefix.
+const annotationLabelPrefix = ctlptlLabelPrefix + "annotation."
+
+// The container label where we record which labels are the registry's labels,
+// so that we don't mistake labels from other tools (like compose) for them.
+const labelKeysLabel = ctlptlLabelPrefix + "label-keys"
+
 func TypeMeta() api.TypeMeta {
 	return typeMeta
 }
@@ -58,28 +75,44 @@ type socatController interface {
 type Controller struct {
 	iostreams    genericclioptions.IOStreams
 	dockerClient ContainerClient
+	engine       docker.Engine
 	runner       exec.CmdRunner
 	socat        socatController
 }
 
 func NewController(iostreams genericclioptions.IOStreams, dockerClient ContainerClient) (*Controller, error) {
+	return newController(iostreams, docker.EngineDocker, dockerClient), nil
+}
+
+func newController(iostreams genericclioptions.IOStreams, engine docker.Engine, dockerClient ContainerClient) *Controller {
 	return &Controller{
 		iostreams:    iostreams,
 		dockerClient: dockerClient,
+		engine:       engine,
 		runner:       exec.RealCmdRunner{},
-		socat:        socat.NewController(dockerClient),
-	}, nil
+		socat:        socat.NewController(dockerClient, engine),
+	}
 }
 
 func DefaultController(ctx context.Context, iostreams genericclioptions.IOStreams) (*Controller, error) {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
+	return ControllerForEngine(ctx, iostreams, "")
+}
+
+// Creates a controller that runs registries on the given container engine.
+//
+// If the engine is empty, auto-detects the engine.
+func ControllerForEngine(ctx context.Context, iostreams genericclioptions.IOStreams, engine docker.Engine) (*Controller, error) {
+	engine, err := docker.ResolveEngine(string(engine))
 	if err != nil {
 		return nil, err
 	}
 
-	dockerClient.NegotiateAPIVersion(ctx)
+	dockerClient, err := docker.NewClient(ctx, engine)
+	if err != nil {
+		return nil, err
+	}
 
-	return NewController(iostreams, dockerClient)
+	return newController(iostreams, engine, dockerClient), nil
 }
 
 func (c *Controller) Get(ctx context.Context, name string) (*api.Registry, error) {
@@ -101,6 +134,10 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Regist
 	if err != nil {
 		return nil, err
 	}
+	labelSelector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
 
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("ancestor", "registry:2") // The registry everyone uses.
@@ -136,11 +173,16 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Regist
 		sort.Strings(networks)
 
 		hostPort, containerPort := c.portsFrom(container.Ports)
+		userLabels, annotations := metadataFrom(container.Labels)
 
 		registry := &api.Registry{
-			TypeMeta: typeMeta,
-			Name:     name,
-			Port:     hostPort,
+			TypeMeta:       typeMeta,
+			Name:           name,
+			Port:           hostPort,
+			Engine:         string(c.engine),
+			Labels:         userLabels,
+			Annotations:    annotations,
+			ProxyRemoteURL: container.Labels[proxyRemoteURLLabel],
 			Status: api.RegistryStatus{
 				CreationTimestamp: metav1.Time{Time: created},
 				ContainerID:       container.ID,
@@ -149,12 +191,16 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Regist
 				ContainerPort:     containerPort,
 				Networks:          networks,
 				State:             container.State,
+				Conditions:        []api.Condition{runningCondition(container)},
 			},
 		}
 
 		if !selector.Matches((*registryFields)(registry)) {
 			continue
 		}
+		if !labelSelector.Matches(labels.Set(registry.Labels)) {
+			continue
+		}
 		result = append(result, *registry)
 	}
 	return &api.RegistryList{
@@ -163,9 +209,77 @@ func (c *Controller) List(ctx context.Context, options ListOptions) (*api.Regist
 	}, nil
 }
 
+// Whether the registry container is running, and if not, what docker says about it.
+func runningCondition(container types.Container) api.Condition {
+	if container.State == containerStateRunning {
+		return api.Condition{Type: api.RegistryConditionRunning, Status: metav1.ConditionTrue, Reason: "Running"}
+	}
+	return api.Condition{
+		Type:    api.RegistryConditionRunning,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NotRunning",
+		Message: fmt.Sprintf("container is %s: %s", container.State, container.Status),
+	}
+}
+
+// Splits the container labels into the registry's labels and annotations,
+// skipping the labels that ctlptl didn't write.
+func metadataFrom(containerLabels map[string]string) (map[string]string, map[string]string) {
+	var userLabels, annotations map[string]string
+	for key, value := range containerLabels {
+		if strings.HasPrefix(key, annotationLabelPrefix) {
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[strings.TrimPrefix(key, annotationLabelPrefix)] = value
+		}
+	}
+
+	labelKeys := containerLabels[labelKeysLabel]
+	if labelKeys == "" {
+		return userLabels, annotations
+	}
+	for _, key := range strings.Split(labelKeys, ",") {
+		value, ok := containerLabels[key]
+		if !ok {
+			continue
+		}
+		if userLabels == nil {
+			userLabels = make(map[string]string)
+		}
+		userLabels[key] = value
+	}
+	return userLabels, annotations
+}
+
+// The docker run flags that add the registry's labels and annotations to the container.
+func labelFlags(registry *api.Registry) []string {
+	var flags []string
+	var keys []string
+	for key, value := range registry.Labels {
+		flags = append(flags, fmt.Sprintf("%s=%s", key, value))
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		flags = append(flags, fmt.Sprintf("%s=%s", labelKeysLabel, strings.Join(keys, ",")))
+	}
+	for key, value := range registry.Annotations {
+		flags = append(flags, fmt.Sprintf("%s%s=%s", annotationLabelPrefix, key, value))
+	}
+	sort.Strings(flags)
+
+	var args []string
+	for _, flag := range flags {
+		args = append(args, "--label", flag)
+	}
+	return args
+}
+
 func (c *Controller) portsFrom(ports []types.Port) (hostPort int, containerPort int) {
 	for _, port := range ports {
-		if port.IP != "0.0.0.0" {
+		// Podman reports an empty IP for ports bound on all interfaces.
+		if port.IP != "0.0.0.0" && port.IP != "" {
 			continue
 		}
 		if port.PublicPort == 0 {
@@ -177,11 +291,21 @@ func (c *Controller) portsFrom(ports []types.Port) (hostPort int, containerPort
 	return 0, 0
 }
 
-// Compare the desired registry against the existing registry, and reconcile
-// the two to match.
-func (c *Controller) Apply(ctx context.Context, desired *api.Registry) (*api.Registry, error) {
-	FillDefaults(desired)
-	existing, err := c.Get(ctx, desired.Name)
+func (c *Controller) validate(desired *api.Registry) error {
+	err := api.ValidateMetadata(desired.Labels, desired.Annotations)
+	if err != nil {
+		return err
+	}
+	if desired.Engine != "" && docker.Engine(desired.Engine) != c.engine {
+		return fmt.Errorf("registry %s wants container engine %s, but ctlptl is connected to %s",
+			desired.Name, desired.Engine, c.engine)
+	}
+	return nil
+}
+
+// The existing registry, or an empty registry if it doesn't exist.
+func (c *Controller) getExisting(ctx context.Context, name string) (*api.Registry, error) {
+	existing, err := c.Get(ctx, name)
 	if err != nil && !errors.IsNotFound(err) {
 		return nil, err
 	}
@@ -189,17 +313,119 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Registry) (*api.Reg
 	if existing == nil {
 		existing = &api.Registry{}
 	}
+	return existing, nil
+}
+
+// Refuses to recreate an existing registry for changes that aren't worth
+// losing the images that the user pushed to it.
+func checkReplaceable(desired, existing *api.Registry) error {
+	if existing.Name == "" {
+		return nil
+	}
+	if desired.ProxyRemoteURL != "" && existing.ProxyRemoteURL == "" {
+		return fmt.Errorf("registry %s already exists and is not a pull-through cache. "+
+			"Delete it, or choose another name for the cache", desired.Name)
+	}
+
+	// If we're recreating the registry anyway, it gets the new labels.
+	reason := recreateReason(desired, existing)
+	if reason == "" && metadataChanged(desired, existing) {
+		return fmt.Errorf("registry %s: desired labels or annotations do not match current, "+
+			"and Docker can't change the labels on an existing container. "+
+			"Delete the registry and its images to recreate it with the new labels:\n%s",
+			desired.Name, cmp.Diff(metadataConfig(existing), metadataConfig(desired)))
+	}
+	return nil
+}
 
-	needsDelete := false
+// Whether the desired labels or annotations differ from the existing ones.
+//
+// Nil labels or annotations mean that the user doesn't care.
+func metadataChanged(desired, existing *api.Registry) bool {
+	return (desired.Labels != nil && !labels.Equals(desired.Labels, existing.Labels)) ||
+		(desired.Annotations != nil && !labels.Equals(desired.Annotations, existing.Annotations))
+}
+
+// Explains why the existing registry needs to be recreated to match the
+// desired registry.
+//
+// Returns an empty reason if the existing registry is up to date.
+func recreateReason(desired, existing *api.Registry) string {
 	if existing.Port != 0 && desired.Port != 0 && existing.Port != desired.Port {
 		// If the port has changed, let's delete the registry and recreate it.
-		needsDelete = true
+		return fmt.Sprintf("because desired port (%d) does not match current (%d)", desired.Port, existing.Port)
 	}
 	if existing.Status.State != containerStateRunning {
 		// If the registry has died, we need to recreate.
-		needsDelete = true
+		return fmt.Sprintf("because it is not running (state: %s)", existing.Status.State)
+	}
+	if desired.ProxyRemoteURL != "" && existing.ProxyRemoteURL != desired.ProxyRemoteURL {
+		// The remote URL is in the registry's env, so we need to recreate to change it.
+		return fmt.Sprintf("because desired proxy remote URL (%s) does not match current (%s)",
+			desired.ProxyRemoteURL, existing.ProxyRemoteURL)
+	}
+	return ""
+}
+
+// The parts of the registry that are stored as container labels.
+func metadataConfig(registry *api.Registry) map[string]map[string]string {
+	return map[string]map[string]string{
+		"labels":      registry.Labels,
+		"annotations": registry.Annotations,
 	}
-	if needsDelete && existing.Name != "" {
+}
+
+// Compare the desired registry against the existing registry, and describe
+// what Apply would do to reconcile the two, without doing it.
+func (c *Controller) Plan(ctx context.Context, desired *api.Registry) ([]plan.Action, error) {
+	FillDefaults(desired)
+	err := c.validate(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := c.getExisting(ctx, desired.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.Name == "" {
+		return []plan.Action{{Description: "create registry"}}, nil
+	}
+
+	err = checkReplaceable(desired, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := recreateReason(desired, existing)
+	if reason == "" {
+		return nil, nil
+	}
+	return []plan.Action{{Description: "recreate registry " + reason}}, nil
+}
+
+// Compare the desired registry against the existing registry, and reconcile
+// the two to match.
+func (c *Controller) Apply(ctx context.Context, desired *api.Registry) (*api.Registry, error) {
+	FillDefaults(desired)
+	err := c.validate(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := c.getExisting(ctx, desired.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkReplaceable(desired, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := recreateReason(desired, existing)
+	if reason != "" && existing.Name != "" {
 		err = c.Delete(ctx, existing.Name)
 		if err != nil {
 			return nil, err
@@ -223,8 +449,18 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Registry) (*api.Reg
 
 	portSpec := fmt.Sprintf("%d:5000", hostPort)
 
+	args := []string{"run", "-d", "--restart=always", "-p", portSpec, "--name", desired.Name}
+	if desired.ProxyRemoteURL != "" {
+		// https://docs.docker.com/registry/recipes/mirror/
+		args = append(args,
+			"-e", fmt.Sprintf("REGISTRY_PROXY_REMOTEURL=%s", desired.ProxyRemoteURL),
+			"--label", fmt.Sprintf("%s=%s", proxyRemoteURLLabel, desired.ProxyRemoteURL))
+	}
+	args = append(args, labelFlags(desired)...)
+	args = append(args, "registry:2")
+
 	_, _ = fmt.Fprintf(c.iostreams.ErrOut, "Creating registry %q...\n", desired.Name)
-	err = c.runner.Run(ctx, "docker", "run", "-d", "--restart=always", "-p", portSpec, "--name", desired.Name, "registry:2")
+	err = c.runner.Run(ctx, c.engine.CLI(), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -238,7 +474,7 @@ func (c *Controller) Apply(ctx context.Context, desired *api.Registry) (*api.Reg
 }
 
 func (c *Controller) maybeCreateForwarder(ctx context.Context, port int) error {
-	if docker.IsLocalHost(docker.GetHostEnv()) {
+	if c.engine.IsLocalHost() {
 		return nil
 	}
 
@@ -13,6 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tilt-dev/ctlptl/internal/exec"
 	"github.com/tilt-dev/ctlptl/pkg/api"
+	"github.com/tilt-dev/ctlptl/pkg/docker"
+	"github.com/tilt-dev/ctlptl/pkg/plan"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
@@ -59,6 +61,7 @@ func TestListRegistries(t *testing.T) {
 		TypeMeta: typeMeta,
 		Name:     "kind-registry",
 		Port:     5001,
+		Engine:   "docker",
 		Status: api.RegistryStatus{
 			CreationTimestamp: metav1.Time{Time: time.Unix(1603483645, 0)},
 			HostPort:          5001,
@@ -67,6 +70,9 @@ func TestListRegistries(t *testing.T) {
 			Networks:          []string{"bridge", "kind"},
 			ContainerID:       "a815c0ec15f1f7430bd402e3fffe65026dd692a1a99861a52b3e30ad6e253a08",
 			State:             "running",
+			Conditions: []api.Condition{
+				{Type: api.RegistryConditionRunning, Status: metav1.ConditionTrue, Reason: "Running"},
+			},
 		},
 	})
 }
@@ -83,6 +89,7 @@ func TestGetRegistry(t *testing.T) {
 		TypeMeta: typeMeta,
 		Name:     "kind-registry",
 		Port:     5001,
+		Engine:   "docker",
 		Status: api.RegistryStatus{
 			CreationTimestamp: metav1.Time{Time: time.Unix(1603483645, 0)},
 			HostPort:          5001,
@@ -91,6 +98,9 @@ func TestGetRegistry(t *testing.T) {
 			Networks:          []string{"bridge", "kind"},
 			ContainerID:       "a815c0ec15f1f7430bd402e3fffe65026dd692a1a99861a52b3e30ad6e253a08",
 			State:             "running",
+			Conditions: []api.Condition{
+				{Type: api.RegistryConditionRunning, Status: metav1.ConditionTrue, Reason: "Running"},
+			},
 		},
 	})
 }
@@ -121,6 +131,261 @@ func TestApplyDeadRegistry(t *testing.T) {
 	assert.Equal(t, deadRegistry.ID, f.docker.lastRemovedContainer)
 }
 
+func TestApplyPodmanRegistry(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	f.c.engine = docker.EnginePodman
+	podmanRegistry := kindRegistry()
+	podmanRegistry.Ports[0].IP = ""
+
+	f.c.runner = exec.FakeCmdRunner(func(argv []string) {
+		assert.Equal(t, "podman", argv[0])
+		assert.Equal(t, "run", argv[1])
+		f.docker.containers = []types.Container{podmanRegistry}
+	})
+
+	registry, err := f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Port:     5001,
+		Engine:   "podman",
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "running", registry.Status.State)
+		assert.Equal(t, 5001, registry.Status.HostPort)
+	}
+}
+
+func TestApplyRegistryWrongEngine(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	_, err := f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Engine:   "podman",
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "wants container engine podman, but ctlptl is connected to docker")
+	}
+}
+
+func TestApplyProxyRegistry(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	var runArgs []string
+	f.c.runner = exec.FakeCmdRunner(func(argv []string) {
+		runArgs = argv
+		proxyRegistry := kindRegistry()
+		proxyRegistry.Labels = map[string]string{proxyRemoteURLLabel: "https://registry-1.docker.io"}
+		f.docker.containers = []types.Container{proxyRegistry}
+	})
+
+	registry, err := f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta:       typeMeta,
+		Name:           "kind-registry",
+		Port:           5001,
+		ProxyRemoteURL: "https://registry-1.docker.io",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry-1.docker.io", registry.ProxyRemoteURL)
+	assert.Equal(t, []string{
+		"docker", "run", "-d", "--restart=always", "-p", "5001:5000", "--name", "kind-registry",
+		"-e", "REGISTRY_PROXY_REMOTEURL=https://registry-1.docker.io",
+		"--label", "dev.tilt.ctlptl.proxy-remote-url=https://registry-1.docker.io",
+		"registry:2",
+	}, runArgs)
+
+	// Applying the same cache again is a no-op.
+	runArgs = nil
+	f.docker.lastRemovedContainer = ""
+	_, err = f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta:       typeMeta,
+		Name:           "kind-registry",
+		ProxyRemoteURL: "https://registry-1.docker.io",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, runArgs)
+	assert.Equal(t, "", f.docker.lastRemovedContainer)
+}
+
+func TestApplyProxyRegistryOverPlainRegistry(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	// A plain registry may have images that the user pushed, so we don't replace it.
+	f.docker.containers = []types.Container{kindRegistry()}
+	f.c.runner = exec.FakeCmdRunner(func(argv []string) {
+		t.Errorf("unexpected command: %v", argv)
+	})
+
+	desired := &api.Registry{
+		TypeMeta:       typeMeta,
+		Name:           "kind-registry",
+		Port:           5001,
+		ProxyRemoteURL: "https://registry-1.docker.io",
+	}
+	_, err := f.c.Plan(context.Background(), desired)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "registry kind-registry already exists and is not a pull-through cache")
+	}
+
+	_, err = f.c.Apply(context.Background(), desired)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "registry kind-registry already exists and is not a pull-through cache")
+	}
+	assert.Equal(t, "", f.docker.lastRemovedContainer)
+}
+
+func TestApplyRegistryLabels(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	var runArgs []string
+	f.c.runner = exec.FakeCmdRunner(func(argv []string) {
+		runArgs = argv
+		labeledRegistry := kindRegistry()
+		labeledRegistry.Labels = map[string]string{
+			"team":                             "payments",
+			"dev.tilt.ctlptl.label-keys":       "team",
+			"dev.tilt.ctlptl.annotation.owner": "jane",
+		}
+		f.docker.containers = []types.Container{labeledRegistry}
+	})
+
+	registry, err := f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta:    typeMeta,
+		Name:        "kind-registry",
+		Port:        5001,
+		Labels:      map[string]string{"team": "payments"},
+		Annotations: map[string]string{"owner": "jane"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"docker", "run", "-d", "--restart=always", "-p", "5001:5000", "--name", "kind-registry",
+		"--label", "dev.tilt.ctlptl.annotation.owner=jane",
+		"--label", "dev.tilt.ctlptl.label-keys=team",
+		"--label", "team=payments",
+		"registry:2",
+	}, runArgs)
+	assert.Equal(t, map[string]string{"team": "payments"}, registry.Labels)
+	assert.Equal(t, map[string]string{"owner": "jane"}, registry.Annotations)
+
+	list, err := f.c.List(context.Background(), ListOptions{LabelSelector: "team=payments"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(list.Items))
+
+	list, err = f.c.List(context.Background(), ListOptions{LabelSelector: "team!=payments"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(list.Items))
+
+	// Docker can't relabel a container, and recreating the registry
+	// would lose its images, so we refuse.
+	runArgs = nil
+	_, err = f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Labels:   map[string]string{"team": "billing"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "registry kind-registry: desired labels or annotations do not match current")
+		assert.Contains(t, err.Error(), "billing")
+	}
+	assert.Nil(t, runArgs)
+	assert.Equal(t, "", f.docker.lastRemovedContainer)
+}
+
+func TestRegistryLabelsFromOtherTools(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	// A registry started by compose has labels that ctlptl didn't write.
+	composeRegistry := kindRegistry()
+	composeRegistry.Labels = map[string]string{
+		"com.docker.compose.project": "dev",
+		"com.docker.compose.service": "registry",
+	}
+	f.docker.containers = []types.Container{composeRegistry}
+	f.c.runner = exec.FakeCmdRunner(func(argv []string) {
+		t.Errorf("unexpected command: %v", argv)
+	})
+
+	registry, err := f.c.Get(context.Background(), "kind-registry")
+	require.NoError(t, err)
+	assert.Nil(t, registry.Labels)
+
+	// Applying it without labels leaves it alone.
+	_, err = f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Port:     5001,
+		Labels:   map[string]string{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", f.docker.lastRemovedContainer)
+}
+
+func TestApplyRegistryInvalidLabels(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	_, err := f.c.Apply(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Labels:   map[string]string{"-team": "payments"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `labels: invalid key "-team"`)
+	}
+}
+
+func TestPlanRegistry(t *testing.T) {
+	f := newFixture(t)
+	defer f.TearDown()
+
+	actions, err := f.c.Plan(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{{Description: "create registry"}}, actions)
+
+	f.docker.containers = []types.Container{kindRegistry()}
+	actions, err = f.c.Plan(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Port:     5001,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	actions, err = f.c.Plan(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Port:     5002,
+		Labels:   map[string]string{"team": "payments"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []plan.Action{
+		{Description: "recreate registry because desired port (5002) does not match current (5001)"},
+	}, actions)
+
+	_, err = f.c.Plan(context.Background(), &api.Registry{
+		TypeMeta: typeMeta,
+		Name:     "kind-registry",
+		Labels:   map[string]string{"team": "payments"},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "desired labels or annotations do not match current")
+		assert.Contains(t, err.Error(), "payments")
+	}
+
+	// Planning doesn't touch the registry.
+	assert.Equal(t, "", f.docker.lastRemovedContainer)
+}
+
 type fakeDocker struct {
 	containers           []types.Container
 	lastRemovedContainer string